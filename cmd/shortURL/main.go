@@ -2,56 +2,159 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
-	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	grpchandler "github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/handler"
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/interceptor"
 	route "github.com/Totus-Floreo/shortURL/internal/app/delivery/http/handler"
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/http/middleware"
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 	pb "github.com/Totus-Floreo/shortURL/internal/app/domain/proto"
+	"github.com/Totus-Floreo/shortURL/internal/app/repository/cache"
 	"github.com/Totus-Floreo/shortURL/internal/app/repository/inmemory"
+	"github.com/Totus-Floreo/shortURL/internal/app/repository/metrics"
 	"github.com/Totus-Floreo/shortURL/internal/app/repository/postgresql"
+	"github.com/Totus-Floreo/shortURL/internal/app/repository/sqlite"
 	"github.com/Totus-Floreo/shortURL/internal/app/service"
+	"github.com/Totus-Floreo/shortURL/internal/config"
 	"github.com/Totus-Floreo/shortURL/internal/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// managed are the gRPC methods that change a link or read its stats or history
+var managed = []string{
+	"/pb.ShortUrl/GetStats",
+	"/pb.ShortUrl/DeleteUrl",
+	"/pb.ShortUrl/RestoreUrl",
+	"/pb.ShortUrl/DisableUrl",
+	"/pb.ShortUrl/UpdateUrl",
+	"/pb.ShortUrl/GetHistory",
+	"/pb.ShortUrl/RollbackUrl",
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		// the errors are logged after the storages are closed
+		case "export":
+			if err := export(os.Args[2:]); err != nil {
+				log.Fatalf("Export error: %v\n", err)
+			}
+			return
+		case "import":
+			if err := load(os.Args[2:]); err != nil {
+				log.Fatalf("Import error: %v\n", err)
+			}
+			return
+		case "migrate":
+			migrate(os.Args[2:])
+			return
+		}
+	}
+
 	log.Printf("Starting...\n")
 	zerologger := zerolog.New(os.Stderr)
 
-	dbType := flag.String("dbType", "pgx", "Type of database (pgx or inmemory)")
-	flag.Parse()
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+
+	// cancelled by the first SIGINT or SIGTERM, the second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// runs the background workers until the servers are drained,
+	// the storages are closed after every worker returned
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var running sync.WaitGroup
+	run := func(worker func(context.Context)) {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			worker(workers)
+		}()
+	}
+
+	registerer := prometheus.DefaultRegisterer
 
-	var db domain.IUrlStorage
-	switch *dbType {
-	case "inmemory":
-		db = inmemory.NewUrlStorage()
-	case "pgx":
-		postgreUrl := "postgres://" + os.Getenv("pg_url")
-		pgxpool, err := pgxpool.New(context.Background(), postgreUrl)
+	stores := storages(cfg.DB)
+	if len(stores.persisted) > 0 {
+		run(func(ctx context.Context) { compact(ctx, stores.persisted, cfg.DB.InMemory.CompactInterval) })
+	}
+	if stores.pool != nil {
+		registerer.MustRegister(postgresql.NewPoolCollector(stores.pool))
+	}
+	storageMetrics := metrics.NewStorageMetrics(registerer)
+	var db domain.IUrlStorage = metrics.NewUrlStorage(stores.urls, cfg.DB.Type, storageMetrics)
+	if cfg.Cache.Size > 0 {
+		cached := cache.NewUrlStorage(db, cfg.Cache.Size, cfg.Cache.TTL, cfg.Cache.NegativeTTL, cfg.Cache.LoadTimeout, registerer)
+		db = metrics.NewUrlStorage(cached, "cache", storageMetrics)
+	}
+
+	var generator domain.IGenerateLinkService
+	switch cfg.Links.Generator {
+	case "snowflake":
+		snowflake, err := service.NewSnowflakeGenerateService(cfg.Links.NodeID)
 		if err != nil {
-			log.Fatalf("Postgre connection error: %v\n", err)
+			log.Fatalf("Generator error: %v\n", err)
 		}
-		db = postgresql.NewUrlStorage(pgxpool)
-	default:
-		log.Fatalf("Unexpected dbType: %s\n", *dbType)
+		snowflake.Length = cfg.Links.Length
+		generator = snowflake
+	case "random":
+		random := service.NewGenerateLinkService()
+		random.Length = cfg.Links.Length
+		generator = random
 	}
 
-	generator := service.NewGenerateLinkService()
-	service := service.NewUrlService(db, generator)
-	handlers := route.NewUrlHandler(service)
-	grpcHandler := grpchandler.NewShortUrlServer(service)
+	queue := service.NewClickQueueService(stores.clicks, cfg.Clicks.Queue, cfg.Clicks.Batch, cfg.Clicks.Workers, cfg.Clicks.Flush, registerer)
+	queue.Start()
+
+	urlService := service.NewUrlService(service.CountAttempts(db), generator, queue)
+	urlService.Timeout = cfg.Links.GenerateTimeout
+	urls := service.NewMetricsUrlService(urlService, registerer)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0%s", os.Getenv("gRPCport")))
+	reaper := service.NewReaperService(db, cfg.Links.ReapInterval, cfg.Links.RestoreWindow)
+	run(reaper.Run)
+
+	keyService := service.NewKeyService(stores.keys)
+	healthService := service.NewHealthService(db)
+
+	limits, err := service.ParseRateLimits(cfg.RateLimits)
+	if err != nil {
+		log.Fatalf("Unexpected rateLimits: %v\n", err)
+	}
+	limiter := service.NewRateLimitService(limits, time.Minute, registerer)
+	limiter.MaxClients = cfg.RateLimitClients
+	run(limiter.Run)
+
+	handlers := route.NewUrlHandler(urls, cfg.Links.Redirect)
+	keyHandlers := route.NewKeyHandler(keyService)
+	grpcHandler := grpchandler.NewShortUrlServer(urls)
+	healthHandlers := route.NewHealthHandler(healthService)
+
+	grpcListener, err := net.Listen("tcp", cfg.GRPC.Addr)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v\n", err)
+	}
+	httpListener, err := net.Listen("tcp", cfg.HTTP.Addr)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v\n", err)
 	}
@@ -60,26 +163,261 @@ func main() {
 		logging.WithLogOnEvents(logging.StartCall, logging.FinishCall),
 	}
 
+	grpcMetrics := interceptor.NewMetrics(registerer)
+	interceptors := []grpc.UnaryServerInterceptor{
+		interceptor.MetricsUnaryServerInterceptor(grpcMetrics),
+		recovery.UnaryServerInterceptor(),
+		logging.UnaryServerInterceptor(logger.InterceptorLogger(zerologger), opts...),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		interceptor.MetricsStreamServerInterceptor(grpcMetrics),
+		recovery.StreamServerInterceptor(),
+		logging.StreamServerInterceptor(logger.InterceptorLogger(zerologger), opts...),
+	}
+	if cfg.Auth.Enabled {
+		interceptors = append(interceptors, interceptor.AuthUnaryServerInterceptor(keyService, cfg.Auth.AdminToken, "/pb.ShortUrl/GetUrl", "/grpc.health.v1.Health/Check"))
+		streamInterceptors = append(streamInterceptors, interceptor.AuthStreamServerInterceptor(keyService, cfg.Auth.AdminToken, "/pb.ShortUrl/ResolveStream", "/grpc.health.v1.Health/Watch"))
+	} else {
+		// without api keys no link has an owner, so only the admin manages them
+		interceptors = append(interceptors, interceptor.AdminUnaryServerInterceptor(cfg.Auth.AdminToken, managed...))
+	}
+	interceptors = append(interceptors, interceptor.RateLimitUnaryServerInterceptor(limiter))
+	streamInterceptors = append(streamInterceptors, interceptor.RateLimitStreamServerInterceptor(limiter))
+
 	grpcServer := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			recovery.UnaryServerInterceptor(),
-			logging.UnaryServerInterceptor(logger.InterceptorLogger(zerologger), opts...),
-		),
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	)
 	pb.RegisterShortUrlServer(grpcServer, grpcHandler)
+	healthpb.RegisterHealthServer(grpcServer, grpchandler.NewHealthServer(healthService))
+
+	router := gin.Default()
+	// the rate limits take the client IP from X-Forwarded-For of these proxies only
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		log.Fatalf("Unexpected trustedProxies: %v\n", err)
+	}
+	router.Use(middleware.Metrics(registerer))
+	// lets the services see the actor the auth middleware puts into the request context
+	router.ContextWithFallback = true
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// the probes are neither authenticated nor rate limited
+	router.GET("/healthz", healthHandlers.Healthz)
+	router.GET("/readyz", healthHandlers.Readyz)
+
+	public := router.Group("/", middleware.RateLimit(limiter))
+	public.GET("/:link", handlers.GetUrl)
+	public.GET("/api/links/:code", handlers.GetLink)
+	public.POST("/api/resolve", handlers.ResolveUrls)
+
+	owned := router.Group("/")
+	manage := router.Group("/")
+	if cfg.Auth.Enabled {
+		owned.Use(middleware.Auth(keyService, cfg.Auth.AdminToken))
+		manage.Use(middleware.Auth(keyService, cfg.Auth.AdminToken))
+	} else {
+		// without api keys no link has an owner, so only the admin manages them
+		manage.Use(middleware.Admin(cfg.Auth.AdminToken))
+	}
+	// after the auth, so clients with a key are limited by the key
+	owned.Use(middleware.RateLimit(limiter))
+	manage.Use(middleware.RateLimit(limiter))
+	owned.POST("/", handlers.CreateUrl)
+	owned.POST("/api/links/batch", handlers.CreateUrls)
+	manage.GET("/api/links/:code/stats", handlers.GetStats)
+	manage.PATCH("/api/links/:code", handlers.UpdateUrl)
+	manage.GET("/api/links/:code/history", handlers.GetHistory)
+	manage.POST("/api/links/:code/rollback", handlers.RollbackUrl)
+	manage.DELETE("/api/links/:code", handlers.DeleteUrl)
+	manage.POST("/api/links/:code/restore", handlers.RestoreUrl)
+	manage.POST("/api/links/:code/disable", handlers.DisableUrl)
+	manage.POST("/api/links/:code/enable", handlers.EnableUrl)
+
+	if cfg.Auth.AdminToken != "" {
+		admin := router.Group("/api/keys", middleware.Admin(cfg.Auth.AdminToken))
+		admin.POST("", keyHandlers.IssueKey)
+		admin.DELETE("/:id", keyHandlers.RevokeKey)
+		admin.POST("/:id/rotate", keyHandlers.RotateKey)
+	} else {
+		// the auth needs the admin token, so the links are unmanaged too
+		log.Printf("admin_token is not set, api key and link management are off\n")
+	}
+
+	httpServer := &http.Server{Addr: cfg.HTTP.Addr, Handler: router}
+
+	errs := serve(httpServer, httpListener, grpcServer, grpcListener)
+
+	var failed bool
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutting down...\n")
+	case err := <-errs:
+		// a failed server takes the other one down with it
+		log.Printf("Failed to serve %v, shutting down...\n", err)
+		failed = true
+	}
+	stop()
+
+	unready(healthService, cfg.ShutdownDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	drain(shutdownCtx, httpServer, grpcServer)
+	stopWorkers()
+	running.Wait()
+	// the click workers are gone even after the timeout, so the storages can be closed
+	if err := queue.Close(shutdownCtx); err != nil {
+		log.Printf("Click queue flush error: %v\n", err)
+	}
+	stores.close()
 
-	go func() {
-		log.Printf("Serve gRPC server on %s", os.Getenv("gRPCport"))
-		if err := grpcServer.Serve(listener); err != nil {
-			log.Fatalf("Failed to serve grpc: %v", err)
+	if failed {
+		os.Exit(1)
+	}
+	log.Printf("Stopped\n")
+}
+
+// persistent is an inmemory storage kept in the data directory
+type persistent interface {
+	Compact() error
+	Close() error
+}
+
+// stores are the storages of the server with what has to be closed after them
+type stores struct {
+	urls      domain.IUrlStorage
+	clicks    domain.IClickStorage
+	keys      domain.IKeyStorage
+	persisted []persistent
+	pool      *pgxpool.Pool
+	sqlite    *sql.DB
+}
+
+func storages(db config.DBConfig) *stores {
+	switch db.Type {
+	case "inmemory":
+		inMemory := db.InMemory
+		if inMemory.DataDir == "" {
+			s := &stores{urls: inmemory.NewUrlStorage(), clicks: inmemory.NewClickStorage(), keys: inmemory.NewKeyStorage()}
+			if inMemory.Shards > 0 {
+				s.urls = inmemory.NewShardedUrlStorage(inMemory.Shards)
+			}
+			return s
+		}
+		urls, err := openUrls(inMemory.DataDir, inMemory.DataSync, inMemory.Shards)
+		if err != nil {
+			log.Fatalf("Data directory error: %v\n", err)
+		}
+		clicks, err := inmemory.OpenClickStorage(inMemory.DataDir, inMemory.DataSync)
+		if err != nil {
+			log.Fatalf("Data directory error: %v\n", err)
+		}
+		keys, err := inmemory.OpenKeyStorage(inMemory.DataDir, inMemory.DataSync)
+		if err != nil {
+			log.Fatalf("Data directory error: %v\n", err)
+		}
+		return &stores{urls: urls, clicks: clicks, keys: keys, persisted: []persistent{urls, clicks, keys}}
+	case "pgx":
+		pool := connect(db.Postgres)
+		if db.Migrate {
+			migrator, err := postgresql.NewMigrator(pool)
+			if err != nil {
+				log.Fatalf("Migrations error: %v\n", err)
+			}
+			applied, err := migrator.Up(context.Background())
+			if err != nil {
+				log.Fatalf("Migration error: %v\n", err)
+			}
+			for _, migration := range applied {
+				log.Printf("Applied migration %d_%s\n", migration.Version, migration.Name)
+			}
+		}
+		return &stores{
+			urls:   postgresql.NewUrlStorage(pool),
+			clicks: postgresql.NewClickStorage(pool),
+			keys:   postgresql.NewKeyStorage(pool),
+			pool:   pool,
+		}
+	case "sqlite":
+		file, err := sqlite.Open(db.SQLite.Path)
+		if err != nil {
+			log.Fatalf("Sqlite open error: %v\n", err)
+		}
+		if db.Migrate {
+			if err := sqlite.Setup(context.Background(), file); err != nil {
+				log.Fatalf("Sqlite schema error: %v\n", err)
+			}
+		}
+		return &stores{
+			urls:   sqlite.NewUrlStorage(file),
+			clicks: sqlite.NewClickStorage(file),
+			keys:   sqlite.NewKeyStorage(file),
+			sqlite: file,
+		}
+	default:
+		log.Fatalf("Unexpected db type: %s\n", db.Type)
+		return nil
+	}
+}
+
+// close writes the persisted storages out and closes the connections of the pgx and sqlite databases
+func (s *stores) close() {
+	for _, storage := range s.persisted {
+		if err := storage.Close(); err != nil {
+			log.Printf("Data directory close error: %v\n", err)
 		}
-	}()
+	}
+	if s.pool != nil {
+		s.pool.Close()
+	}
+	if s.sqlite != nil {
+		if err := s.sqlite.Close(); err != nil {
+			log.Printf("Sqlite close error: %v\n", err)
+		}
+	}
+}
 
-	router := gin.Default()
-	router.GET("/:link", handlers.GetUrl)
-	router.POST("/", handlers.CreateUrl)
+// persistentUrls is an inmemory link storage kept in a data directory
+type persistentUrls interface {
+	domain.IUrlStorage
+	persistent
+}
 
-	if err := router.Run(os.Getenv("httpport")); err != nil {
-		log.Fatalf("Failed to serve http: %v", err)
+func openUrls(dataDir string, dataSync bool, shards int) (persistentUrls, error) {
+	if shards > 0 {
+		return inmemory.OpenShardedUrlStorage(dataDir, dataSync, shards)
+	}
+	return inmemory.OpenUrlStorage(dataDir, dataSync)
+}
+
+func connect(postgres config.PostgresConfig) *pgxpool.Pool {
+	poolConfig := postgresql.PoolConfig{
+		MaxConns:         int32(postgres.MaxConns),
+		MinConns:         int32(postgres.MinConns),
+		MaxConnLifetime:  postgres.ConnLifetime,
+		MaxConnIdleTime:  postgres.ConnIdle,
+		StatementTimeout: postgres.StatementTimeout,
+	}
+	pool, err := postgresql.NewPool(context.Background(), postgres.ConnString(), poolConfig)
+	if err != nil {
+		log.Fatalf("Postgre connection error: %v\n", err)
+	}
+	return pool
+}
+
+// compact replaces the logs of the persisted storages with snapshots every interval until ctx is done
+func compact(ctx context.Context, persisted []persistent, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, storage := range persisted {
+			if err := storage.Compact(); err != nil {
+				log.Printf("Compaction error: %v\n", err)
+			}
+		}
 	}
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/repository/postgresql"
+	"github.com/Totus-Floreo/shortURL/internal/config"
+)
+
+// migrate changes the schema of the pgx database:
+// shortURL migrate up | shortURL migrate down -steps 1 | shortURL migrate status
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "Number of migrations undone by down")
+
+	if len(args) == 0 {
+		log.Fatalf("Usage: shortURL migrate up|down|status\n")
+	}
+	action := args[0]
+	cfg, err := config.Load(flags, args[1:], os.LookupEnv)
+	if err != nil {
+		log.Fatalf("Config error: %v\n", err)
+	}
+
+	migrator, err := postgresql.NewMigrator(connect(cfg.DB.Postgres))
+	if err != nil {
+		log.Fatalf("Migrations error: %v\n", err)
+	}
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migration error: %v\n", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s\n", migration.Version, migration.Name)
+		}
+		log.Printf("Applied %d migrations\n", len(applied))
+	case "down":
+		if *steps < 1 {
+			log.Fatalf("Unexpected steps: %d\n", *steps)
+		}
+		undone, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			log.Fatalf("Migration error: %v\n", err)
+		}
+		for _, migration := range undone {
+			log.Printf("Undid migration %d_%s\n", migration.Version, migration.Name)
+		}
+		log.Printf("Undid %d migrations\n", len(undone))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Migration status error: %v\n", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != 0 {
+				state = "applied " + time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatalf("Unexpected migrate action: %s\n", action)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/service"
+	"google.golang.org/grpc"
+)
+
+// serve runs both servers in the background, the channel gets the error
+// of every server that stops by itself
+func serve(httpServer *http.Server, httpListener net.Listener, grpcServer *grpc.Server, grpcListener net.Listener) <-chan error {
+	errs := make(chan error, 2)
+
+	go func() {
+		log.Printf("Serve gRPC server on %s", grpcListener.Addr())
+		// returns nil after the server is stopped
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			errs <- fmt.Errorf("grpc: %w", err)
+		}
+	}()
+	go func() {
+		log.Printf("Serve http server on %s", httpListener.Addr())
+		if err := httpServer.Serve(httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("http: %w", err)
+		}
+	}()
+
+	return errs
+}
+
+// unready fails the readiness probes and waits for the delay while the servers still
+// take connections, so the load balancers see the probes and stop sending requests
+// before the servers are drained
+func unready(health *service.HealthService, delay time.Duration) {
+	health.Drain()
+	if delay > 0 {
+		log.Printf("Readiness is off, draining in %v\n", delay)
+		time.Sleep(delay)
+	}
+}
+
+// drain stops both servers from taking new connections and waits for the requests
+// in flight, the ones left when ctx is done are cut off
+func drain(ctx context.Context, httpServer *http.Server, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("Http shutdown error: %v\n", err)
+		httpServer.Close()
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Printf("gRPC shutdown error: %v\n", ctx.Err())
+		grpcServer.Stop()
+		<-stopped
+	}
+}
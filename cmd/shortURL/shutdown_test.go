@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	grpchandler "github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/handler"
+	route "github.com/Totus-Floreo/shortURL/internal/app/delivery/http/handler"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/Totus-Floreo/shortURL/internal/app/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestShutdown_ReadinessBeforeDrain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	db.EXPECT().Ping(gomock.Any()).Return(nil).AnyTimes()
+	health := service.NewHealthService(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", route.NewHealthHandler(health).Readyz)
+	httpServer := &http.Server{Handler: router}
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, grpchandler.NewHealthServer(health))
+
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	errs := serve(httpServer, httpListener, grpcServer, grpcListener)
+
+	readyz := "http://" + httpListener.Addr().String() + "/readyz"
+	conn, err := grpc.Dial(grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	require.Equal(t, http.StatusOK, status(readyz))
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		unready(health, 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		drain(ctx, httpServer, grpcServer)
+	}()
+
+	// both servers still take requests and tell they are not ready
+	require.Eventually(t, func() bool { return status(readyz) == http.StatusServiceUnavailable }, 400*time.Millisecond, 10*time.Millisecond)
+	response, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, response.Status)
+
+	<-stopped
+	_, err = http.Get(readyz)
+	require.Error(t, err)
+	select {
+	case err := <-errs:
+		t.Fatalf("server failed: %v", err)
+	default:
+	}
+}
+
+// status returns the status code of a GET, zero when the request fails
+func status(url string) int {
+	response, err := http.Get(url)
+	if err != nil {
+		return 0
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
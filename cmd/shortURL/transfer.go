@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/service"
+	"github.com/Totus-Floreo/shortURL/internal/config"
+)
+
+// export writes the links of the database to a file or the stdout:
+// shortURL export -dbType pgx -format csv -file links.csv
+func export(args []string) (err error) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", service.FormatJSONL, "Format of the file (jsonl or csv)")
+	file := flags.String("file", "", "File to write, default the stdout")
+	cfg, err := config.Load(flags, args, os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if !service.CheckFormat(*format) {
+		return fmt.Errorf("unexpected format: %s", *format)
+	}
+
+	cfg.DB.Migrate = false
+	stores := storages(cfg.DB)
+	defer stores.close()
+	transfer := service.NewTransferService(stores.urls, 0)
+
+	var out io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("export file: %w", err)
+		}
+		defer func() {
+			// the last writes of the file may fail on close
+			if closeErr := f.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("export file: %w", closeErr)
+			}
+		}()
+		out = f
+	}
+
+	written, err := transfer.Export(context.Background(), out, *format)
+	if err != nil {
+		return fmt.Errorf("after %d links: %w", written, err)
+	}
+	log.Printf("Exported %d links\n", written)
+	return nil
+}
+
+// load reads the links of a file or the stdin into the database and prints the report:
+// shortURL import -dbType pgx -format csv -file links.csv -conflict skip -dryRun
+func load(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", service.FormatJSONL, "Format of the file (jsonl or csv)")
+	file := flags.String("file", "", "File to read, default the stdin")
+	conflict := flags.String("conflict", service.ConflictSkip, "What to do with links whose short link is taken (skip, overwrite or fail)")
+	dryRun := flags.Bool("dryRun", false, "Report what the import would do without writing")
+	batch := flags.Int("batch", 1000, "Number of links written at once")
+	cfg, err := config.Load(flags, args, os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if !service.CheckFormat(*format) {
+		return fmt.Errorf("unexpected format: %s", *format)
+	}
+	if !service.CheckConflict(*conflict) {
+		return fmt.Errorf("unexpected conflict: %s", *conflict)
+	}
+	if *batch < 1 {
+		return fmt.Errorf("unexpected batch: %d", *batch)
+	}
+
+	// an import may be the first use of a new database,
+	// a dry run does not change the schema either
+	cfg.DB.Migrate = !*dryRun
+	stores := storages(cfg.DB)
+	defer stores.close()
+	transfer := service.NewTransferService(stores.urls, *batch)
+
+	var in io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("import file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	report, err := transfer.Import(context.Background(), in, *format, *conflict, *dryRun)
+	if report != nil {
+		json.NewEncoder(os.Stdout).Encode(report)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package grpchandler
+
+import (
+	"context"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// watchInterval is how often a watched status is checked again
+const watchInterval = 5 * time.Second
+
+// HealthHandler is the grpc.health.v1 service, the server as a whole ("")
+// and pb.ShortUrl are serving while the health service is ready
+type HealthHandler struct {
+	healthpb.UnimplementedHealthServer
+
+	service  domain.IHealthService
+	interval time.Duration
+}
+
+func NewHealthServer(service domain.IHealthService) *HealthHandler {
+	return &HealthHandler{
+		service:  service,
+		interval: watchInterval,
+	}
+}
+
+func (h *HealthHandler) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !known(req.GetService()) {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	serving, _ := h.status(ctx)
+	return &healthpb.HealthCheckResponse{Status: serving}, nil
+}
+
+// Watch sends the status and then every change of it. The stream ends
+// with the not serving status once the server shuts down, so it does not hold up the drain
+func (h *HealthHandler) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	if !known(req.GetService()) {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVICE_UNKNOWN}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		serving, draining := h.status(ctx)
+		if serving != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: serving}); err != nil {
+				return err
+			}
+			last = serving
+		}
+		if draining {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// status returns the serving status and whether the server shuts down
+func (h *HealthHandler) status(ctx context.Context) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	switch err := h.service.Ready(ctx); err {
+	case nil:
+		return healthpb.HealthCheckResponse_SERVING, false
+	case domain.ErrorShuttingDown:
+		return healthpb.HealthCheckResponse_NOT_SERVING, true
+	default:
+		return healthpb.HealthCheckResponse_NOT_SERVING, false
+	}
+}
+
+func known(service string) bool {
+	return service == "" || service == "pb.ShortUrl"
+}
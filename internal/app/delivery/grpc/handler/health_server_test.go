@@ -0,0 +1,114 @@
+package grpchandler
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func healthServer(t *testing.T, service domain.IHealthService) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+
+	handler := NewHealthServer(service)
+	handler.interval = 10 * time.Millisecond
+
+	baseServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(baseServer, handler)
+	go baseServer.Serve(lis)
+	t.Cleanup(baseServer.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIHealthService(ctrl)
+	client := healthServer(t, service)
+
+	gomock.InOrder(
+		service.EXPECT().Ready(gomock.Any()).Return(nil),
+		service.EXPECT().Ready(gomock.Any()).Return(domain.ErrorNotReady),
+	)
+
+	response, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+
+	response, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "pb.ShortUrl"})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, response.Status)
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "pb.Other"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestHealthWatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIHealthService(ctrl)
+	client := healthServer(t, service)
+
+	gomock.InOrder(
+		service.EXPECT().Ready(gomock.Any()).Return(nil).Times(2),
+		service.EXPECT().Ready(gomock.Any()).Return(domain.ErrorNotReady),
+		service.EXPECT().Ready(gomock.Any()).Return(nil),
+		service.EXPECT().Ready(gomock.Any()).Return(domain.ErrorShuttingDown),
+	)
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	// only the changes are sent, the stream ends once the server shuts down
+	for _, expected := range []healthpb.HealthCheckResponse_ServingStatus{
+		healthpb.HealthCheckResponse_SERVING,
+		healthpb.HealthCheckResponse_NOT_SERVING,
+		healthpb.HealthCheckResponse_SERVING,
+		healthpb.HealthCheckResponse_NOT_SERVING,
+	} {
+		response, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, expected, response.Status)
+	}
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestHealthWatch_Unknown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIHealthService(ctrl)
+	client := healthServer(t, service)
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "pb.Other"})
+	require.NoError(t, err)
+
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVICE_UNKNOWN, response.Status)
+}
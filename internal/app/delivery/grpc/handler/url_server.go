@@ -2,12 +2,20 @@ package grpchandler
 
 import (
 	"context"
+	"io"
+	"net"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/helpers"
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 	pb "github.com/Totus-Floreo/shortURL/internal/app/domain/proto"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// streamBatch is the number of streamed links created at once
+const streamBatch = 1000
+
 type ShortUrlhandler struct {
 	pb.UnimplementedShortUrlServer
 
@@ -21,9 +29,15 @@ func NewShortUrlServer(service domain.IUrlService) *ShortUrlhandler {
 }
 
 func (s *ShortUrlhandler) CreateUrl(ctx context.Context, long *pb.Long) (*pb.Short, error) {
-	longUrl := long.GetLink()
+	request := domain.URLRequest{
+		LongURL:   long.GetLink(),
+		TTL:       long.GetTtl(),
+		ExpiresAt: long.GetExpiresAt(),
+		Alias:     long.GetAlias(),
+		Redirect:  int(long.GetRedirect()),
+	}
 
-	short, err := s.service.CreateUrl(ctx, longUrl)
+	short, err := s.service.CreateUrl(ctx, request)
 	if err != nil {
 		return nil, helpers.GRPCError(err)
 	}
@@ -31,13 +45,209 @@ func (s *ShortUrlhandler) CreateUrl(ctx context.Context, long *pb.Long) (*pb.Sho
 	return &pb.Short{Link: short}, nil
 }
 
+// CreateUrls creates the streamed links in batches as they arrive and answers
+// with the results of all of them once the client closes the stream
+func (s *ShortUrlhandler) CreateUrls(stream pb.ShortUrl_CreateUrlsServer) error {
+	ctx := stream.Context()
+	out := &pb.Results{}
+	requests := make([]domain.URLRequest, 0, streamBatch)
+
+	flush := func() error {
+		if len(requests) == 0 {
+			return nil
+		}
+
+		results, err := s.service.CreateUrls(ctx, requests)
+		if err != nil {
+			return helpers.GRPCError(err)
+		}
+		for _, result := range results {
+			item := &pb.Result{Link: result.Short}
+			if result.Err != nil {
+				item.Error = result.Err.Error()
+			}
+			out.Results = append(out.Results, item)
+		}
+
+		requests = requests[:0]
+		return nil
+	}
+
+	for {
+		long, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		requests = append(requests, domain.URLRequest{
+			LongURL:   long.GetLink(),
+			TTL:       long.GetTtl(),
+			ExpiresAt: long.GetExpiresAt(),
+			Alias:     long.GetAlias(),
+			Redirect:  int(long.GetRedirect()),
+		})
+		if len(requests) == streamBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(out)
+}
+
 func (s *ShortUrlhandler) GetUrl(ctx context.Context, short *pb.Short) (*pb.Long, error) {
 	shortUrl := short.GetLink()
 
-	long, err := s.service.GetUrl(ctx, shortUrl)
+	long, err := s.service.GetUrl(domain.WithClient(ctx, client(ctx)), shortUrl)
+	if err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return &pb.Long{Link: long.LongURL, ExpiresAt: long.ExpiresAt, Redirect: int32(long.Redirect)}, nil
+}
+
+// ResolveStream answers every request of the stream as soon as it is resolved,
+// the stream ends when the client closes its side
+func (s *ShortUrlhandler) ResolveStream(stream pb.ShortUrl_ResolveStreamServer) error {
+	ctx := stream.Context()
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		results, err := s.service.ResolveUrls(ctx, in.GetLinks())
+		if err != nil {
+			return helpers.GRPCError(err)
+		}
+
+		out := &pb.ResolveResponse{}
+		for _, result := range results {
+			item := &pb.Resolution{Link: result.Short}
+			if result.Err != nil {
+				item.Error = result.Err.Error()
+			} else {
+				item.Target = result.Long.LongURL
+			}
+			out.Results = append(out.Results, item)
+		}
+
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *ShortUrlhandler) GetStats(ctx context.Context, in *pb.StatsRequest) (*pb.Stats, error) {
+	request := domain.StatsRequest{
+		Short:    in.GetLink(),
+		From:     in.GetFrom(),
+		To:       in.GetTo(),
+		Interval: in.GetInterval(),
+	}
+
+	stats, err := s.service.GetStats(ctx, request)
 	if err != nil {
 		return nil, helpers.GRPCError(err)
 	}
 
-	return &pb.Long{Link: long}, nil
+	out := &pb.Stats{Link: stats.Short, Total: stats.Total}
+	for _, bucket := range stats.Buckets {
+		out.Buckets = append(out.Buckets, &pb.Bucket{Start: bucket.Start, Count: bucket.Count})
+	}
+
+	return out, nil
+}
+
+func (s *ShortUrlhandler) DeleteUrl(ctx context.Context, in *pb.Short) (*emptypb.Empty, error) {
+	if err := s.service.DeleteUrl(ctx, in.GetLink()); err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ShortUrlhandler) RestoreUrl(ctx context.Context, in *pb.Short) (*emptypb.Empty, error) {
+	if err := s.service.RestoreUrl(ctx, in.GetLink()); err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ShortUrlhandler) DisableUrl(ctx context.Context, in *pb.DisableRequest) (*emptypb.Empty, error) {
+	if err := s.service.DisableUrl(ctx, in.GetLink(), in.GetDisabled()); err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ShortUrlhandler) UpdateUrl(ctx context.Context, in *pb.UpdateRequest) (*emptypb.Empty, error) {
+	if err := s.service.UpdateUrl(ctx, in.GetLink(), in.GetTarget()); err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ShortUrlhandler) GetHistory(ctx context.Context, in *pb.Short) (*pb.History, error) {
+	history, err := s.service.GetHistory(ctx, in.GetLink())
+	if err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	out := &pb.History{}
+	for _, version := range history {
+		out.Versions = append(out.Versions, &pb.Version{
+			Version:   version.Version,
+			Target:    version.LongURL,
+			ChangedAt: version.ChangedAt,
+			Actor:     version.Actor,
+		})
+	}
+
+	return out, nil
+}
+
+func (s *ShortUrlhandler) RollbackUrl(ctx context.Context, in *pb.RollbackRequest) (*emptypb.Empty, error) {
+	if err := s.service.RollbackUrl(ctx, in.GetLink(), in.GetVersion()); err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func client(ctx context.Context) domain.Client {
+	var client domain.Client
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			client.UserAgent = values[0]
+		}
+		if values := md.Get("referer"); len(values) > 0 {
+			client.Referrer = values[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.IP); err == nil {
+			client.IP = host
+		}
+	}
+
+	return client
 }
@@ -12,8 +12,12 @@ import (
 	pb "github.com/Totus-Floreo/shortURL/internal/app/domain/proto"
 	"github.com/golang/mock/gomock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
 )
 
 func server(ctx context.Context, service domain.IUrlService) (pb.ShortUrlClient, func()) {
@@ -80,6 +84,19 @@ func TestCreateUrl(t *testing.T) {
 				serviceError: nil,
 			},
 		},
+		"Success with TTL": {
+			in: &pb.Long{
+				Link: "google.com",
+				Ttl:  3600,
+			},
+			expected: expectation{
+				out: &pb.Short{
+					Link: "bE2bqvWHr9",
+				},
+				err:          nil,
+				serviceError: nil,
+			},
+		},
 		"Error": {
 			in: &pb.Long{
 				Link: "google.com",
@@ -97,7 +114,12 @@ func TestCreateUrl(t *testing.T) {
 	for title, test := range tests {
 		t.Run(title, func(t *testing.T) {
 
-			service.EXPECT().CreateUrl(gomock.Any(), test.in.Link).Return(test.expected.out.Link, test.expected.serviceError)
+			request := domain.URLRequest{
+				LongURL:   test.in.Link,
+				TTL:       test.in.Ttl,
+				ExpiresAt: test.in.ExpiresAt,
+			}
+			service.EXPECT().CreateUrl(gomock.Any(), request).Return(test.expected.out.Link, test.expected.serviceError)
 
 			out, err := client.CreateUrl(ctx, test.in)
 			if err != nil {
@@ -113,6 +135,120 @@ func TestCreateUrl(t *testing.T) {
 	}
 }
 
+func TestCreateUrls(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	// the stream is created in batches of streamBatch links
+	gomock.InOrder(
+		service.EXPECT().CreateUrls(gomock.Any(), gomock.Len(streamBatch)).DoAndReturn(func(ctx context.Context, requests []domain.URLRequest) ([]domain.URLResult, error) {
+			return make([]domain.URLResult, len(requests)), nil
+		}),
+		service.EXPECT().CreateUrls(gomock.Any(), []domain.URLRequest{{LongURL: "not a link"}}).Return([]domain.URLResult{{Err: domain.ErrorInvalidLink}}, nil),
+	)
+
+	stream, err := client.CreateUrls(ctx)
+	if err != nil {
+		t.Fatalf("CreateUrls: %v", err)
+	}
+	for i := 0; i < streamBatch; i++ {
+		if err := stream.Send(&pb.Long{Link: "google.com"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if err := stream.Send(&pb.Long{Link: "not a link"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	out, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv: %v", err)
+	}
+	if len(out.Results) != streamBatch+1 {
+		t.Fatalf("Results -> \nWant: %d\nGot: %d\n", streamBatch+1, len(out.Results))
+	}
+	if last := out.Results[streamBatch]; last.Link != "" || last.Error != domain.ErrorInvalidLink.Error() {
+		t.Errorf("Last -> \nWant: %q\nGot: %v\n", domain.ErrorInvalidLink, last)
+	}
+}
+
+func TestCreateUrls_Error(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	service.EXPECT().CreateUrls(gomock.Any(), gomock.Any()).Return(nil, domain.ErrorInvalidBatch)
+
+	stream, err := client.CreateUrls(ctx)
+	if err != nil {
+		t.Fatalf("CreateUrls: %v", err)
+	}
+	if err := stream.Send(&pb.Long{Link: "google.com"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	_, err = stream.CloseAndRecv()
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Code -> \nWant: %v\nGot: %v\n", codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestResolveStream(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	gomock.InOrder(
+		service.EXPECT().ResolveUrls(gomock.Any(), []string{"GoodLink12", "Missing123"}).Return([]domain.ResolveResult{
+			{Short: "GoodLink12", Long: &domain.URLLong{LongURL: "google.com"}},
+			{Short: "Missing123", Err: domain.ErrorLinkNotFound},
+		}, nil),
+		service.EXPECT().ResolveUrls(gomock.Any(), gomock.Len(0)).Return(nil, domain.ErrorInvalidBatch),
+	)
+
+	stream, err := client.ResolveStream(ctx)
+	if err != nil {
+		t.Fatalf("ResolveStream: %v", err)
+	}
+
+	// every request is answered before the next one is sent
+	if err := stream.Send(&pb.ResolveRequest{Links: []string{"GoodLink12", "Missing123"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	out, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	want := &pb.ResolveResponse{Results: []*pb.Resolution{
+		{Link: "GoodLink12", Target: "google.com"},
+		{Link: "Missing123", Error: domain.ErrorLinkNotFound.Error()},
+	}}
+	if !proto.Equal(want, out) {
+		t.Errorf("Out -> \nWant: %v\nGot: %v\n", want, out)
+	}
+
+	if err := stream.Send(&pb.ResolveRequest{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	_, err = stream.Recv()
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Code -> \nWant: %v\nGot: %v\n", codes.InvalidArgument, status.Code(err))
+	}
+}
+
 func TestGetUrl(t *testing.T) {
 	ctx := context.Background()
 
@@ -161,7 +297,7 @@ func TestGetUrl(t *testing.T) {
 	for title, test := range tests {
 		t.Run(title, func(t *testing.T) {
 
-			service.EXPECT().GetUrl(gomock.Any(), test.in.Link).Return(test.expected.out.Link, test.expected.serviceError)
+			service.EXPECT().GetUrl(gomock.Any(), test.in.Link).Return(&domain.URLLong{LongURL: test.expected.out.Link}, test.expected.serviceError)
 
 			out, err := client.GetUrl(ctx, test.in)
 			if err != nil {
@@ -176,3 +312,168 @@ func TestGetUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUrl_Client(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "referer", "https://ya.ru/")
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	service.EXPECT().GetUrl(gomock.Any(), "bE2bqvWHr9").DoAndReturn(func(ctx context.Context, short string) (*domain.URLLong, error) {
+		client := domain.ClientFromContext(ctx)
+		if client.Referrer != "https://ya.ru/" || client.UserAgent == "" {
+			t.Errorf("Client -> \nGot: %+v", client)
+		}
+		return &domain.URLLong{LongURL: "google.com"}, nil
+	})
+
+	if _, err := client.GetUrl(ctx, &pb.Short{Link: "bE2bqvWHr9"}); err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %q\n", err)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	type expectation struct {
+		out          *pb.Stats
+		err          error
+		serviceError error
+	}
+
+	tests := map[string]struct {
+		in       *pb.StatsRequest
+		expected expectation
+	}{
+		"Success": {
+			in: &pb.StatsRequest{
+				Link:     "bE2bqvWHr9",
+				From:     1000,
+				To:       1300,
+				Interval: 100,
+			},
+			expected: expectation{
+				out: &pb.Stats{
+					Link:    "bE2bqvWHr9",
+					Total:   3,
+					Buckets: []*pb.Bucket{{Start: 1000, Count: 3}},
+				},
+				err:          nil,
+				serviceError: nil,
+			},
+		},
+		"Error": {
+			in: &pb.StatsRequest{
+				Link: "bE2bqvWHr9",
+				From: 1300,
+				To:   1000,
+			},
+			expected: expectation{
+				out:          &pb.Stats{},
+				err:          helpers.GRPCError(domain.ErrorInvalidStats),
+				serviceError: domain.ErrorInvalidStats,
+			},
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			request := domain.StatsRequest{
+				Short:    test.in.Link,
+				From:     test.in.From,
+				To:       test.in.To,
+				Interval: test.in.Interval,
+			}
+			stats := &domain.Stats{Short: test.expected.out.Link, Total: test.expected.out.Total}
+			for _, bucket := range test.expected.out.Buckets {
+				stats.Buckets = append(stats.Buckets, domain.Bucket{Start: bucket.Start, Count: bucket.Count})
+			}
+
+			service.EXPECT().GetStats(gomock.Any(), request).Return(stats, test.expected.serviceError)
+
+			out, err := client.GetStats(ctx, test.in)
+			if err != nil {
+				if test.expected.err == nil || test.expected.err.Error() != err.Error() {
+					t.Errorf("Err -> \nWant: %q\nGot: %q\n", test.expected.err, err)
+				}
+			} else {
+				if !proto.Equal(test.expected.out, out) {
+					t.Errorf("Out -> \nWant: %q\nGot : %q", test.expected.out, out)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkState(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	service.EXPECT().DeleteUrl(gomock.Any(), "bE2bqvWHr9").Return(nil)
+	service.EXPECT().RestoreUrl(gomock.Any(), "bE2bqvWHr9").Return(domain.ErrorLinkNotFound)
+	service.EXPECT().DisableUrl(gomock.Any(), "bE2bqvWHr9", true).Return(nil)
+	service.EXPECT().GetUrl(gomock.Any(), "bE2bqvWHr9").Return(&domain.URLLong{}, domain.ErrorLinkGone)
+
+	if _, err := client.DeleteUrl(ctx, &pb.Short{Link: "bE2bqvWHr9"}); err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %q\n", err)
+	}
+
+	_, err := client.RestoreUrl(ctx, &pb.Short{Link: "bE2bqvWHr9"})
+	if want := helpers.GRPCError(domain.ErrorLinkNotFound); err == nil || want.Error() != err.Error() {
+		t.Errorf("Err -> \nWant: %q\nGot: %q\n", want, err)
+	}
+
+	if _, err := client.DisableUrl(ctx, &pb.DisableRequest{Link: "bE2bqvWHr9", Disabled: true}); err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %q\n", err)
+	}
+
+	_, err = client.GetUrl(ctx, &pb.Short{Link: "bE2bqvWHr9"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Code -> \nWant: %v\nGot: %v\n", codes.FailedPrecondition, status.Code(err))
+	}
+}
+
+func TestUpdateUrl(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockIUrlService(ctrl)
+
+	client, closer := server(ctx, service)
+	defer closer()
+
+	service.EXPECT().UpdateUrl(gomock.Any(), "bE2bqvWHr9", "yandex.ru").Return(nil)
+	service.EXPECT().GetHistory(gomock.Any(), "bE2bqvWHr9").Return([]domain.URLVersion{{Version: 1, LongURL: "google.com", ChangedAt: 1686557090}}, nil)
+	service.EXPECT().RollbackUrl(gomock.Any(), "bE2bqvWHr9", int64(1)).Return(nil)
+
+	if _, err := client.UpdateUrl(ctx, &pb.UpdateRequest{Link: "bE2bqvWHr9", Target: "yandex.ru"}); err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %q\n", err)
+	}
+
+	history, err := client.GetHistory(ctx, &pb.Short{Link: "bE2bqvWHr9"})
+	if err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %q\n", err)
+	}
+	want := &pb.History{Versions: []*pb.Version{{Version: 1, Target: "google.com", ChangedAt: 1686557090}}}
+	if !proto.Equal(want, history) {
+		t.Errorf("Out -> \nWant: %q\nGot : %q", want, history)
+	}
+
+	if _, err := client.RollbackUrl(ctx, &pb.RollbackRequest{Link: "bE2bqvWHr9", Version: 1}); err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %q\n", err)
+	}
+}
@@ -10,12 +10,36 @@ func GRPCError(err error) error {
 	switch err {
 	case domain.ErrorGenerateTimeout:
 		return status.Errorf(codes.Canceled, "Error: %s", err.Error())
+	case domain.ErrorVersionNotFound:
+		return status.Errorf(codes.NotFound, "Error: %s", err.Error())
 	case domain.ErrorLinkNotFound:
 		return status.Errorf(codes.NotFound, "Error: %s", err.Error())
+	case domain.ErrorLinkGone:
+		return status.Errorf(codes.FailedPrecondition, "Error: %s", err.Error())
 	case domain.ErrorInvalidShort:
 		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
 	case domain.ErrorInvalidLink:
 		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
+	case domain.ErrorInvalidExpiry:
+		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
+	case domain.ErrorInvalidAlias:
+		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
+	case domain.ErrorInvalidRedirect:
+		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
+	case domain.ErrorInvalidStats:
+		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
+	case domain.ErrorInvalidBatch:
+		return status.Errorf(codes.InvalidArgument, "Error: %s", err.Error())
+	case domain.ErrorAliasTaken:
+		return status.Errorf(codes.AlreadyExists, "Error: %s", err.Error())
+	case domain.ErrorUnauthorized:
+		return status.Errorf(codes.Unauthenticated, "Error: %s", err.Error())
+	case domain.ErrorForbidden:
+		return status.Errorf(codes.PermissionDenied, "Error: %s", err.Error())
+	case domain.ErrorKeyNotFound:
+		return status.Errorf(codes.NotFound, "Error: %s", err.Error())
+	case domain.ErrorRateLimited:
+		return status.Errorf(codes.ResourceExhausted, "Error: %s", err.Error())
 	default:
 		return status.Errorf(codes.Internal, "Error: %s", err.Error())
 	}
@@ -0,0 +1,123 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/helpers"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const bearerPrefix = "bearer "
+
+// AuthUnaryServerInterceptor requires an active api key in the authorization
+// metadata for every method but the public ones, public methods are full
+// method names such as /pb.ShortUrl/GetUrl. The key id becomes the actor of the call.
+// The admin token is let in too, an empty one matches nothing
+func AuthUnaryServerInterceptor(keys domain.IKeyService, admin string, public ...string) grpc.UnaryServerInterceptor {
+	open := methods(public)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if open[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, keys, admin)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamServerInterceptor is AuthUnaryServerInterceptor for streaming methods
+func AuthStreamServerInterceptor(keys domain.IKeyService, admin string, public ...string) grpc.StreamServerInterceptor {
+	open := methods(public)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if open[info.FullMethod] {
+			return handler(srv, stream)
+		}
+
+		ctx, err := authenticate(stream.Context(), keys, admin)
+		if err != nil {
+			return err
+		}
+
+		wrapped := grpcmiddleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// AdminUnaryServerInterceptor requires the admin token for the guarded methods,
+// it guards the links while the auth is off. An empty token lets nobody in
+func AdminUnaryServerInterceptor(token string, guarded ...string) grpc.UnaryServerInterceptor {
+	closed := methods(guarded)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !closed[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		got, ok := bearer(ctx)
+		if !ok || !isAdmin(got, token) {
+			return nil, helpers.GRPCError(domain.ErrorUnauthorized)
+		}
+
+		return handler(domain.WithAdmin(ctx), req)
+	}
+}
+
+func authenticate(ctx context.Context, keys domain.IKeyService, admin string) (context.Context, error) {
+	token, ok := bearer(ctx)
+	if !ok {
+		return nil, helpers.GRPCError(domain.ErrorUnauthorized)
+	}
+
+	if isAdmin(token, admin) {
+		return domain.WithAdmin(ctx), nil
+	}
+
+	key, err := keys.Authenticate(ctx, token)
+	if err != nil {
+		return nil, helpers.GRPCError(err)
+	}
+
+	return domain.WithActor(ctx, key.ID), nil
+}
+
+func isAdmin(token, admin string) bool {
+	return admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1
+}
+
+func methods(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+func bearer(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+
+	header := values[0]
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return header[len(bearerPrefix):], true
+}
@@ -0,0 +1,59 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics are the call counts and latencies shared by the unary and the stream interceptor
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shorturl_grpc_requests_total",
+			Help: "gRPC calls by full method and status code.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shorturl_grpc_request_duration_seconds",
+			Help:    "Latency of the gRPC calls by full method, streams last until they are closed.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	registerer.MustRegister(m.requests, m.duration)
+	return m
+}
+
+func (m *Metrics) observe(method string, start time.Time, err error) {
+	m.requests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// MetricsUnaryServerInterceptor counts the calls per method and status code and
+// observes their latency, it goes first so the calls the other interceptors answer are counted too
+func MetricsUnaryServerInterceptor(metrics *Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.observe(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// MetricsStreamServerInterceptor is MetricsUnaryServerInterceptor for streaming methods
+func MetricsStreamServerInterceptor(metrics *Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		metrics.observe(info.FullMethod, start, err)
+		return err
+	}
+}
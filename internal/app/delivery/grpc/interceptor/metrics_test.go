@@ -0,0 +1,42 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/helpers"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestMetricsServerInterceptors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics(registry)
+	unary := MetricsUnaryServerInterceptor(metrics)
+	stream := MetricsStreamServerInterceptor(metrics)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.ShortUrl/GetUrl"}
+	unary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	unary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, helpers.GRPCError(domain.ErrorLinkNotFound)
+	})
+	stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/pb.ShortUrl/ResolveStream"}, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+
+	expected := `
+		# HELP shorturl_grpc_requests_total gRPC calls by full method and status code.
+		# TYPE shorturl_grpc_requests_total counter
+		shorturl_grpc_requests_total{code="NotFound",method="/pb.ShortUrl/GetUrl"} 1
+		shorturl_grpc_requests_total{code="OK",method="/pb.ShortUrl/GetUrl"} 1
+		shorturl_grpc_requests_total{code="OK",method="/pb.ShortUrl/ResolveStream"} 1
+	`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "shorturl_grpc_requests_total"))
+	require.Equal(t, 2, testutil.CollectAndCount(registry, "shorturl_grpc_request_duration_seconds"))
+}
@@ -0,0 +1,64 @@
+package interceptor
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/grpc/helpers"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RateLimitUnaryServerInterceptor limits the calls of every client per method,
+// the scope is the full method name. Clients are told apart by the api key,
+// so it has to run after the auth interceptor, calls without a key by their IP.
+// Limited calls get the wait in seconds in the retry-after header
+func RateLimitUnaryServerInterceptor(limiter domain.IRateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if wait, ok := limiter.Allow(info.FullMethod, client(ctx)); !ok {
+			// fails only outside of a real call
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter(wait)))
+			return nil, helpers.GRPCError(domain.ErrorRateLimited)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamServerInterceptor is RateLimitUnaryServerInterceptor for
+// streaming methods, the stream takes a single token however many messages it carries
+func RateLimitStreamServerInterceptor(limiter domain.IRateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if wait, ok := limiter.Allow(info.FullMethod, client(stream.Context())); !ok {
+			_ = stream.SetHeader(metadata.Pairs("retry-after", retryAfter(wait)))
+			return helpers.GRPCError(domain.ErrorRateLimited)
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+func client(ctx context.Context) string {
+	if actor := domain.ActorFromContext(ctx); actor != "" {
+		return "key:" + actor
+	}
+
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return "ip:" + ip
+}
+
+// retryAfter rounds the wait up to whole seconds
+func retryAfter(wait time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10)
+}
@@ -0,0 +1,87 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitUnaryServerInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	limiter := mocks.NewMockIRateLimiter(ctrl)
+	limit := RateLimitUnaryServerInterceptor(limiter)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}
+	byIP := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	byKey := domain.WithActor(byIP, "0123456789abcdef")
+
+	limiter.EXPECT().Allow("/pb.ShortUrl/CreateUrl", "ip:192.0.2.1").Return(time.Duration(0), true)
+	limiter.EXPECT().Allow("/pb.ShortUrl/CreateUrl", "key:0123456789abcdef").Return(time.Second, false)
+
+	tests := map[string]struct {
+		ctx  context.Context
+		code codes.Code
+	}{
+		"Allowed": {
+			ctx:  byIP,
+			code: codes.OK,
+		},
+		"Limited": {
+			ctx:  byKey,
+			code: codes.ResourceExhausted,
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			_, err := limit(test.ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pb.ShortUrl/CreateUrl"}, handler)
+
+			if status.Code(err) != test.code {
+				t.Errorf("Code -> \nWant: %v\nGot: %v\n", test.code, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestRateLimitStreamServerInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	limiter := mocks.NewMockIRateLimiter(ctrl)
+	limit := RateLimitStreamServerInterceptor(limiter)
+	info := &grpc.StreamServerInfo{FullMethod: "/pb.ShortUrl/CreateUrls"}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	}
+
+	ctx := domain.WithActor(context.Background(), "0123456789abcdef")
+	gomock.InOrder(
+		limiter.EXPECT().Allow("/pb.ShortUrl/CreateUrls", "key:0123456789abcdef").Return(time.Duration(0), true),
+		limiter.EXPECT().Allow("/pb.ShortUrl/CreateUrls", "key:0123456789abcdef").Return(time.Second, false),
+	)
+
+	if err := limit(nil, &stream{ctx: ctx}, info, handler); err != nil {
+		t.Errorf("Err -> \nWant: nil\nGot: %v\n", err)
+	}
+
+	err := limit(nil, &stream{ctx: ctx}, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Code -> \nWant: %v\nGot: %v\n", codes.ResourceExhausted, status.Code(err))
+	}
+}
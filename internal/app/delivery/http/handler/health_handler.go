@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/http/helpers"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	Service domain.IHealthService
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+func NewHealthHandler(service domain.IHealthService) *HealthHandler {
+	return &HealthHandler{
+		Service: service,
+	}
+}
+
+// Healthz answers while the process can serve http, it does not touch the storage
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Readyz answers 503 while the storage does not answer or the server shuts down
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	if err := h.Service.Ready(c); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIHealthService(ctrl)
+	handler := NewHealthHandler(service)
+
+	router := gin.New()
+	router.GET("/healthz", handler.Healthz)
+	router.GET("/readyz", handler.Readyz)
+
+	gomock.InOrder(
+		service.EXPECT().Ready(gomock.Any()).Return(nil),
+		service.EXPECT().Ready(gomock.Any()).Return(domain.ErrorNotReady),
+		service.EXPECT().Ready(gomock.Any()).Return(domain.ErrorShuttingDown),
+	)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.JSONEq(t, `{"error":"storage is not reachable"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.JSONEq(t, `{"error":"server is shutting down"}`, w.Body.String())
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/http/helpers"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type KeyHandler struct {
+	Service domain.IKeyService
+}
+
+type KeyRequest struct {
+	Name string `json:"name"`
+}
+
+// KeyResponse carries the token, it is the only time the token is shown
+type KeyResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Token string `json:"key"`
+}
+
+func NewKeyHandler(service domain.IKeyService) *KeyHandler {
+	return &KeyHandler{
+		Service: service,
+	}
+}
+
+func (h *KeyHandler) IssueKey(c *gin.Context) {
+	var request KeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		helpers.HTTPError(c, domain.ErrorInvalidDecode)
+		return
+	}
+
+	token, key, err := h.Service.IssueKey(c, request.Name)
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, KeyResponse{ID: key.ID, Name: key.Name, Token: token})
+}
+
+func (h *KeyHandler) RevokeKey(c *gin.Context) {
+	if err := h.Service.RevokeKey(c, c.Param("id")); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *KeyHandler) RotateKey(c *gin.Context) {
+	id := c.Param("id")
+
+	token, err := h.Service.RotateKey(c, id)
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, KeyResponse{ID: id, Token: token})
+}
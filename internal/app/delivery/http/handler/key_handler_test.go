@@ -0,0 +1,66 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIKeyService(ctrl)
+	handler := NewKeyHandler(service)
+
+	router := gin.New()
+	router.POST("/api/keys", handler.IssueKey)
+	router.DELETE("/api/keys/:id", handler.RevokeKey)
+	router.POST("/api/keys/:id/rotate", handler.RotateKey)
+
+	service.EXPECT().IssueKey(gomock.Any(), "ci").Return("0123456789abcdef.secret", &domain.APIKey{ID: "0123456789abcdef", Name: "ci"}, nil)
+	service.EXPECT().RotateKey(gomock.Any(), "0123456789abcdef").Return("0123456789abcdef.rotated", nil)
+	service.EXPECT().RevokeKey(gomock.Any(), "0123456789abcdef").Return(nil)
+	service.EXPECT().RevokeKey(gomock.Any(), "fedcba9876543210").Return(domain.ErrorKeyNotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/keys", strings.NewReader(`{"name":"ci"}`))
+	router.ServeHTTP(w, req)
+
+	var response KeyResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Equal(t, KeyResponse{ID: "0123456789abcdef", Name: "ci", Token: "0123456789abcdef.secret"}, response)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/keys/0123456789abcdef/rotate", nil)
+	router.ServeHTTP(w, req)
+
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "0123456789abcdef.rotated", response.Token)
+
+	for id, status := range map[string]int{"0123456789abcdef": http.StatusNoContent, "fedcba9876543210": http.StatusNotFound} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", "/api/keys/"+id, nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, status, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/keys", strings.NewReader(`{"name":`))
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/delivery/http/helpers"
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
@@ -9,27 +11,56 @@ import (
 )
 
 type UrlHandler struct {
-	Service domain.IUrlService
+	Service  domain.IUrlService
+	Redirect int // default redirect status, zero answers with json
 }
 
 type Response struct {
 	Link string `json:"link"`
 }
 
-func NewUrlHandler(service domain.IUrlService) *UrlHandler {
+// BatchResponse is an item of the CreateUrls response, it has either the link or the error
+type BatchResponse struct {
+	Link  string `json:"link,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ResolveResponse is an item of the ResolveUrls response, it has either the link or the error
+type ResolveResponse struct {
+	Short string `json:"short"`
+	Link  string `json:"link,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type UpdateRequest struct {
+	Link string `json:"link"`
+}
+
+type RollbackRequest struct {
+	Version int64 `json:"version"`
+}
+
+type StatsQuery struct {
+	From     int64 `form:"from"`
+	To       int64 `form:"to"`
+	Interval int64 `form:"interval"`
+}
+
+func NewUrlHandler(service domain.IUrlService, redirect int) *UrlHandler {
 	return &UrlHandler{
-		Service: service,
+		Service:  service,
+		Redirect: redirect,
 	}
 }
 
 func (h *UrlHandler) CreateUrl(c *gin.Context) {
-	var long domain.URLLong
-	if err := c.BindJSON(&long); err != nil {
+	var request domain.URLRequest
+	if err := c.BindJSON(&request); err != nil {
 		helpers.HTTPError(c, domain.ErrorInvalidDecode)
 		return
 	}
 
-	short, err := h.Service.CreateUrl(c, long.LongURL)
+	short, err := h.Service.CreateUrl(c, request)
 	if err != nil {
 		helpers.HTTPError(c, err)
 		return
@@ -38,14 +69,224 @@ func (h *UrlHandler) CreateUrl(c *gin.Context) {
 	c.JSON(http.StatusCreated, Response{Link: short})
 }
 
+// CreateUrls answers with the results in the order of the requests,
+// links that failed do not fail the whole batch
+func (h *UrlHandler) CreateUrls(c *gin.Context) {
+	var requests []domain.URLRequest
+	if err := c.BindJSON(&requests); err != nil {
+		helpers.HTTPError(c, domain.ErrorInvalidDecode)
+		return
+	}
+
+	results, err := h.Service.CreateUrls(c, requests)
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	response := make([]BatchResponse, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			response[i].Error = result.Err.Error()
+			continue
+		}
+		response[i].Link = result.Short
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResolveUrls takes a list of short links and answers with their
+// original links in the same order, lookups are not counted as clicks
+func (h *UrlHandler) ResolveUrls(c *gin.Context) {
+	var shorts []string
+	if err := c.BindJSON(&shorts); err != nil {
+		helpers.HTTPError(c, domain.ErrorInvalidDecode)
+		return
+	}
+
+	results, err := h.Service.ResolveUrls(c, shorts)
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	response := make([]ResolveResponse, len(results))
+	for i, result := range results {
+		response[i].Short = result.Short
+		if result.Err != nil {
+			response[i].Error = result.Err.Error()
+			continue
+		}
+		response[i].Link = result.Long.LongURL
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUrl redirects to the long link, clients that accept json
+// rather than html get the json response as well as all clients
+// if neither the link nor the server has a redirect status
 func (h *UrlHandler) GetUrl(c *gin.Context) {
 	short := c.Param("link")
 
-	long, err := h.Service.GetUrl(c, short)
+	long, err := h.Service.GetUrl(domain.WithClient(c, client(c)), short)
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	status := long.Redirect
+	if status == 0 {
+		status = h.Redirect
+	}
+
+	// only browsers are redirected, clients without an Accept header or with */* get json
+	if status == 0 || !acceptsHTML(c) || c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) != gin.MIMEHTML {
+		c.JSON(http.StatusOK, Response{Link: long.LongURL})
+		return
+	}
+
+	c.Redirect(status, long.LongURL)
+}
+
+// GetLink always answers with json, it is the api counterpart of GetUrl
+func (h *UrlHandler) GetLink(c *gin.Context) {
+	short := c.Param("code")
+
+	long, err := h.Service.GetUrl(domain.WithClient(c, client(c)), short)
 	if err != nil {
 		helpers.HTTPError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, Response{Link: long})
+	c.JSON(http.StatusOK, Response{Link: long.LongURL})
+}
+
+func (h *UrlHandler) GetStats(c *gin.Context) {
+	var query StatsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		helpers.HTTPError(c, domain.ErrorInvalidStats)
+		return
+	}
+
+	request := domain.StatsRequest{
+		Short:    c.Param("code"),
+		From:     query.From,
+		To:       query.To,
+		Interval: query.Interval,
+	}
+
+	stats, err := h.Service.GetStats(c, request)
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *UrlHandler) DeleteUrl(c *gin.Context) {
+	if err := h.Service.DeleteUrl(c, c.Param("code")); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UrlHandler) RestoreUrl(c *gin.Context) {
+	if err := h.Service.RestoreUrl(c, c.Param("code")); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UrlHandler) DisableUrl(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+func (h *UrlHandler) EnableUrl(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *UrlHandler) setDisabled(c *gin.Context, disabled bool) {
+	if err := h.Service.DisableUrl(c, c.Param("code"), disabled); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UrlHandler) UpdateUrl(c *gin.Context) {
+	var request UpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		helpers.HTTPError(c, domain.ErrorInvalidDecode)
+		return
+	}
+
+	if err := h.Service.UpdateUrl(c, c.Param("code"), request.Link); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UrlHandler) GetHistory(c *gin.Context) {
+	history, err := h.Service.GetHistory(c, c.Param("code"))
+	if err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *UrlHandler) RollbackUrl(c *gin.Context) {
+	var request RollbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		helpers.HTTPError(c, domain.ErrorInvalidDecode)
+		return
+	}
+
+	if err := h.Service.RollbackUrl(c, c.Param("code"), request.Version); err != nil {
+		helpers.HTTPError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func client(c *gin.Context) domain.Client {
+	return domain.Client{
+		Referrer:  c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// acceptsHTML reports whether the Accept header names text/html, wildcards do not count
+func acceptsHTML(c *gin.Context) bool {
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		params := strings.Split(accepted, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), gin.MIMEHTML) {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err != nil || q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	return false
 }
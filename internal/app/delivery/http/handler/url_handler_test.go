@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -79,12 +80,12 @@ func TestCreateUrl_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	service := mocks.NewMockIUrlService(ctrl)
-	handler := NewUrlHandler(service)
+	handler := NewUrlHandler(service, 0)
 
 	w := httptest.NewRecorder()
 	_, router := gin.CreateTestContext(w)
 
-	service.EXPECT().CreateUrl(gomock.Any(), Tests[0].Long).Return(Tests[0].Short, Tests[0].ServiceError)
+	service.EXPECT().CreateUrl(gomock.Any(), domain.URLRequest{LongURL: Tests[0].Long}).Return(Tests[0].Short, Tests[0].ServiceError)
 
 	router.POST("/", handler.CreateUrl)
 
@@ -100,12 +101,56 @@ func TestCreateUrl_Success(t *testing.T) {
 	require.Equal(t, Tests[0].Short, response["link"])
 }
 
+func TestCreateUrl_TTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, 0)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().CreateUrl(gomock.Any(), domain.URLRequest{LongURL: Tests[0].Long, TTL: 3600}).Return(Tests[0].Short, nil)
+
+	router.POST("/", handler.CreateUrl)
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(fmt.Sprintf(`{"link": "%s", "ttl": 3600}`, Tests[0].Long)))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, Tests[0].StatusCode, w.Code)
+}
+
+func TestCreateUrl_AliasTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, 0)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().CreateUrl(gomock.Any(), domain.URLRequest{LongURL: Tests[0].Long, Alias: "summer_sale"}).Return("", domain.ErrorAliasTaken)
+
+	router.POST("/", handler.CreateUrl)
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(fmt.Sprintf(`{"link": "%s", "alias": "summer_sale"}`, Tests[0].Long)))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusConflict, w.Code)
+}
+
 func TestCreateUrl_BadJson(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	service := mocks.NewMockIUrlService(ctrl)
-	handler := NewUrlHandler(service)
+	handler := NewUrlHandler(service, 0)
 
 	w := httptest.NewRecorder()
 	_, router := gin.CreateTestContext(w)
@@ -125,12 +170,12 @@ func TestCreateUrl_ServiceError(t *testing.T) {
 	defer ctrl.Finish()
 
 	service := mocks.NewMockIUrlService(ctrl)
-	handler := NewUrlHandler(service)
+	handler := NewUrlHandler(service, 0)
 
 	w := httptest.NewRecorder()
 	_, router := gin.CreateTestContext(w)
 
-	service.EXPECT().CreateUrl(gomock.Any(), Tests[2].Long).Return(Tests[2].Short, Tests[2].ServiceError)
+	service.EXPECT().CreateUrl(gomock.Any(), domain.URLRequest{LongURL: Tests[2].Long}).Return(Tests[2].Short, Tests[2].ServiceError)
 
 	router.POST("/", handler.CreateUrl)
 
@@ -146,6 +191,98 @@ func TestCreateUrl_ServiceError(t *testing.T) {
 	require.Equal(t, Tests[2].ServiceError.Error(), response["error"])
 }
 
+func TestCreateUrls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, 0)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	requests := []domain.URLRequest{{LongURL: "google.com"}, {LongURL: "not a link"}}
+	service.EXPECT().CreateUrls(gomock.Any(), requests).Return([]domain.URLResult{{Short: "GoodLink12"}, {Err: domain.ErrorInvalidLink}}, nil)
+
+	router.POST("/api/links/batch", handler.CreateUrls)
+
+	req, _ := http.NewRequest("POST", "/api/links/batch", strings.NewReader(`[{"link": "google.com"}, {"link": "not a link"}]`))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"link": "GoodLink12"}, {"error": "invalid link"}]`, w.Body.String())
+}
+
+func TestCreateUrls_InvalidBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, 0)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().CreateUrls(gomock.Any(), []domain.URLRequest{}).Return(nil, domain.ErrorInvalidBatch)
+
+	router.POST("/api/links/batch", handler.CreateUrls)
+
+	req, _ := http.NewRequest("POST", "/api/links/batch", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestResolveUrls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, 0)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().ResolveUrls(gomock.Any(), []string{"GoodLink12", "Missing123"}).Return([]domain.ResolveResult{
+		{Short: "GoodLink12", Long: &domain.URLLong{LongURL: "google.com"}},
+		{Short: "Missing123", Err: domain.ErrorLinkNotFound},
+	}, nil)
+
+	router.POST("/api/resolve", handler.ResolveUrls)
+
+	req, _ := http.NewRequest("POST", "/api/resolve", strings.NewReader(`["GoodLink12", "Missing123"]`))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"short": "GoodLink12", "link": "google.com"}, {"short": "Missing123", "error": "link not found"}]`, w.Body.String())
+}
+
+func TestResolveUrls_BadJson(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, 0)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	router.POST("/api/resolve", handler.ResolveUrls)
+
+	req, _ := http.NewRequest("POST", "/api/resolve", strings.NewReader(`{"links": "GoodLink12"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // Get
 
 func TestGetUrl_Success(t *testing.T) {
@@ -153,12 +290,12 @@ func TestGetUrl_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	service := mocks.NewMockIUrlService(ctrl)
-	handler := NewUrlHandler(service)
+	handler := NewUrlHandler(service, 0)
 
 	w := httptest.NewRecorder()
 	_, router := gin.CreateTestContext(w)
 
-	service.EXPECT().GetUrl(gomock.Any(), Tests[3].Short).Return(Tests[3].Long, Tests[3].ServiceError)
+	service.EXPECT().GetUrl(gomock.Any(), Tests[3].Short).Return(&domain.URLLong{LongURL: Tests[3].Long}, Tests[3].ServiceError)
 
 	router.GET("/:link", handler.GetUrl)
 
@@ -178,12 +315,12 @@ func TestGetUrl_ServiceError(t *testing.T) {
 	defer ctrl.Finish()
 
 	service := mocks.NewMockIUrlService(ctrl)
-	handler := NewUrlHandler(service)
+	handler := NewUrlHandler(service, 0)
 
 	w := httptest.NewRecorder()
 	_, router := gin.CreateTestContext(w)
 
-	service.EXPECT().GetUrl(gomock.Any(), Tests[4].Short).Return(Tests[4].Long, Tests[4].ServiceError)
+	service.EXPECT().GetUrl(gomock.Any(), Tests[4].Short).Return(&domain.URLLong{LongURL: Tests[4].Long}, Tests[4].ServiceError)
 
 	router.GET("/:link", handler.GetUrl)
 
@@ -197,3 +334,357 @@ func TestGetUrl_ServiceError(t *testing.T) {
 	require.Equal(t, Tests[4].StatusCode, w.Code)
 	require.Equal(t, Tests[4].ServiceError.Error(), response["error"])
 }
+
+func TestGetUrl_Redirect(t *testing.T) {
+	tests := map[string]struct {
+		ServerRedirect int
+		LinkRedirect   int
+		Accept         string
+		StatusCode     int
+	}{
+		"Browser":           {http.StatusFound, 0, "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusFound},
+		"Link override":     {http.StatusFound, http.StatusMovedPermanently, "text/html", http.StatusMovedPermanently},
+		"Link w/out server": {0, http.StatusTemporaryRedirect, "text/html", http.StatusTemporaryRedirect},
+		"No accept":         {http.StatusFound, 0, "", http.StatusOK},
+		"Any type":          {http.StatusFound, http.StatusMovedPermanently, "*/*", http.StatusOK},
+		"Json client":       {http.StatusFound, http.StatusMovedPermanently, "application/json", http.StatusOK},
+		"Json first":        {http.StatusFound, 0, "application/json, text/html;q=0.9", http.StatusOK},
+		"Html refused":      {http.StatusFound, 0, "text/html;q=0, */*", http.StatusOK},
+		"Redirect disabled": {0, 0, "text/html", http.StatusOK},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service := mocks.NewMockIUrlService(ctrl)
+			handler := NewUrlHandler(service, test.ServerRedirect)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+
+			service.EXPECT().GetUrl(gomock.Any(), Tests[3].Short).Return(&domain.URLLong{LongURL: "https://google.com", Redirect: test.LinkRedirect}, nil)
+
+			router.GET("/:link", handler.GetUrl)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/%s", Tests[3].Short), nil)
+			if test.Accept != "" {
+				req.Header.Set("Accept", test.Accept)
+			}
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, test.StatusCode, w.Code)
+			if test.StatusCode == http.StatusOK {
+				response := make(map[string]string)
+				json.Unmarshal(w.Body.Bytes(), &response)
+				require.Equal(t, "https://google.com", response["link"])
+			} else {
+				require.Equal(t, "https://google.com", w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestGetLink_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, http.StatusFound)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().GetUrl(gomock.Any(), Tests[3].Short).Return(&domain.URLLong{LongURL: Tests[3].Long, Redirect: http.StatusMovedPermanently}, nil)
+
+	router.GET("/:link", handler.GetUrl)
+	router.GET("/api/links/:code", handler.GetLink)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/links/%s", Tests[3].Short), nil)
+
+	router.ServeHTTP(w, req)
+
+	response := make(map[string]string)
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, Tests[3].Long, response["link"])
+}
+
+func TestGetLink_Client(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, http.StatusFound)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().GetUrl(gomock.Any(), Tests[3].Short).DoAndReturn(func(ctx context.Context, short string) (*domain.URLLong, error) {
+		client := domain.ClientFromContext(ctx)
+		require.Equal(t, "https://ya.ru/", client.Referrer)
+		require.Equal(t, "curl/8.0", client.UserAgent)
+		require.Equal(t, "192.0.2.1", client.IP)
+		return &domain.URLLong{LongURL: Tests[3].Long}, nil
+	})
+
+	router.GET("/api/links/:code", handler.GetLink)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/links/%s", Tests[3].Short), nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Referer", "https://ya.ru/")
+	req.Header.Set("User-Agent", "curl/8.0")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetStats(t *testing.T) {
+	var tests = map[string]struct {
+		Query        string
+		Request      domain.StatsRequest
+		ServiceError error
+		StatusCode   int
+	}{
+		"Success": {
+			Query:      "?from=1000&to=1300&interval=100",
+			Request:    domain.StatsRequest{Short: Tests[3].Short, From: 1000, To: 1300, Interval: 100},
+			StatusCode: http.StatusOK,
+		},
+		"Defaults": {
+			Query:      "",
+			Request:    domain.StatsRequest{Short: Tests[3].Short},
+			StatusCode: http.StatusOK,
+		},
+		"Invalid Stats": {
+			Query:        "?from=1300&to=1000",
+			Request:      domain.StatsRequest{Short: Tests[3].Short, From: 1300, To: 1000},
+			ServiceError: domain.ErrorInvalidStats,
+			StatusCode:   http.StatusBadRequest,
+		},
+		"Not Found": {
+			Query:        "",
+			Request:      domain.StatsRequest{Short: Tests[3].Short},
+			ServiceError: domain.ErrorLinkNotFound,
+			StatusCode:   http.StatusNotFound,
+		},
+		"Bad Query": {
+			Query:      "?from=yesterday",
+			StatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service := mocks.NewMockIUrlService(ctrl)
+			handler := NewUrlHandler(service, http.StatusFound)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+
+			if test.Request.Short != "" {
+				stats := &domain.Stats{Short: Tests[3].Short, Total: 3, Buckets: []domain.Bucket{{Start: 1000, Count: 3}}}
+				service.EXPECT().GetStats(gomock.Any(), test.Request).Return(stats, test.ServiceError)
+			}
+
+			router.GET("/api/links/:code/stats", handler.GetStats)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/links/%s/stats%s", Tests[3].Short, test.Query), nil)
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, test.StatusCode, w.Code)
+			if test.StatusCode == http.StatusOK {
+				var response domain.Stats
+				json.Unmarshal(w.Body.Bytes(), &response)
+				require.Equal(t, int64(3), response.Total)
+				require.Len(t, response.Buckets, 1)
+			}
+		})
+	}
+}
+
+func TestGetUrl_Gone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, http.StatusFound)
+
+	w := httptest.NewRecorder()
+	_, router := gin.CreateTestContext(w)
+
+	service.EXPECT().GetUrl(gomock.Any(), Tests[3].Short).Return(&domain.URLLong{}, domain.ErrorLinkGone)
+
+	router.GET("/:link", handler.GetUrl)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/%s", Tests[3].Short), nil)
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusGone, w.Code)
+}
+
+func TestLinkState(t *testing.T) {
+	var tests = map[string]struct {
+		Method       string
+		Path         string
+		Expect       func(service *mocks.MockIUrlService) *gomock.Call
+		ServiceError error
+		StatusCode   int
+	}{
+		"Delete": {
+			Method: "DELETE",
+			Path:   "/api/links/%s",
+			Expect: func(service *mocks.MockIUrlService) *gomock.Call {
+				return service.EXPECT().DeleteUrl(gomock.Any(), Tests[3].Short)
+			},
+			StatusCode: http.StatusNoContent,
+		},
+		"Delete Not Found": {
+			Method: "DELETE",
+			Path:   "/api/links/%s",
+			Expect: func(service *mocks.MockIUrlService) *gomock.Call {
+				return service.EXPECT().DeleteUrl(gomock.Any(), Tests[3].Short)
+			},
+			ServiceError: domain.ErrorLinkNotFound,
+			StatusCode:   http.StatusNotFound,
+		},
+		"Restore": {
+			Method: "POST",
+			Path:   "/api/links/%s/restore",
+			Expect: func(service *mocks.MockIUrlService) *gomock.Call {
+				return service.EXPECT().RestoreUrl(gomock.Any(), Tests[3].Short)
+			},
+			StatusCode: http.StatusNoContent,
+		},
+		"Disable": {
+			Method: "POST",
+			Path:   "/api/links/%s/disable",
+			Expect: func(service *mocks.MockIUrlService) *gomock.Call {
+				return service.EXPECT().DisableUrl(gomock.Any(), Tests[3].Short, true)
+			},
+			StatusCode: http.StatusNoContent,
+		},
+		"Enable": {
+			Method: "POST",
+			Path:   "/api/links/%s/enable",
+			Expect: func(service *mocks.MockIUrlService) *gomock.Call {
+				return service.EXPECT().DisableUrl(gomock.Any(), Tests[3].Short, false)
+			},
+			StatusCode: http.StatusNoContent,
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service := mocks.NewMockIUrlService(ctrl)
+			handler := NewUrlHandler(service, http.StatusFound)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+
+			test.Expect(service).Return(test.ServiceError)
+
+			router.DELETE("/api/links/:code", handler.DeleteUrl)
+			router.POST("/api/links/:code/restore", handler.RestoreUrl)
+			router.POST("/api/links/:code/disable", handler.DisableUrl)
+			router.POST("/api/links/:code/enable", handler.EnableUrl)
+
+			req, _ := http.NewRequest(test.Method, fmt.Sprintf(test.Path, Tests[3].Short), nil)
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, test.StatusCode, w.Code)
+		})
+	}
+}
+
+func TestUpdateUrl(t *testing.T) {
+	var tests = map[string]struct {
+		Body         string
+		ServiceError error
+		StatusCode   int
+	}{
+		"Success": {
+			Body:       `{"link":"yandex.ru"}`,
+			StatusCode: http.StatusNoContent,
+		},
+		"Invalid Link": {
+			Body:         `{"link":"yandex"}`,
+			ServiceError: domain.ErrorInvalidLink,
+			StatusCode:   http.StatusBadRequest,
+		},
+		"Bad Json": {
+			Body:       `{"link":`,
+			StatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service := mocks.NewMockIUrlService(ctrl)
+			handler := NewUrlHandler(service, http.StatusFound)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+
+			if test.Body != `{"link":` {
+				service.EXPECT().UpdateUrl(gomock.Any(), Tests[3].Short, gomock.Any()).Return(test.ServiceError)
+			}
+
+			router.PATCH("/api/links/:code", handler.UpdateUrl)
+
+			req, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/links/%s", Tests[3].Short), strings.NewReader(test.Body))
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, test.StatusCode, w.Code)
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockIUrlService(ctrl)
+	handler := NewUrlHandler(service, http.StatusFound)
+
+	router := gin.New()
+	router.GET("/api/links/:code/history", handler.GetHistory)
+	router.POST("/api/links/:code/rollback", handler.RollbackUrl)
+
+	history := []domain.URLVersion{{Version: 1, LongURL: Tests[3].Long, ChangedAt: 1686557090, Actor: "192.0.2.1"}}
+	service.EXPECT().GetHistory(gomock.Any(), Tests[3].Short).Return(history, nil)
+	service.EXPECT().RollbackUrl(gomock.Any(), Tests[3].Short, int64(2)).Return(domain.ErrorVersionNotFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/links/%s/history", Tests[3].Short), nil)
+	router.ServeHTTP(w, req)
+
+	var response []domain.URLVersion
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, history, response)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/links/%s/rollback", Tests[3].Short), strings.NewReader(`{"version":2}`))
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -14,14 +14,42 @@ func HTTPError(c *gin.Context, err error) {
 	switch err {
 	case domain.ErrorGenerateTimeout:
 		c.AbortWithStatusJSON(http.StatusInternalServerError, ginError.JSON())
+	case domain.ErrorVersionNotFound:
+		c.AbortWithStatusJSON(http.StatusNotFound, ginError.JSON())
 	case domain.ErrorLinkNotFound:
 		c.AbortWithStatusJSON(http.StatusNotFound, ginError.JSON())
+	case domain.ErrorLinkGone:
+		c.AbortWithStatusJSON(http.StatusGone, ginError.JSON())
 	case domain.ErrorInvalidDecode:
 		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
 	case domain.ErrorInvalidShort:
 		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
 	case domain.ErrorInvalidLink:
 		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
+	case domain.ErrorInvalidExpiry:
+		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
+	case domain.ErrorInvalidAlias:
+		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
+	case domain.ErrorInvalidRedirect:
+		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
+	case domain.ErrorInvalidStats:
+		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
+	case domain.ErrorInvalidBatch:
+		c.AbortWithStatusJSON(http.StatusBadRequest, ginError.JSON())
+	case domain.ErrorAliasTaken:
+		c.AbortWithStatusJSON(http.StatusConflict, ginError.JSON())
+	case domain.ErrorUnauthorized:
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ginError.JSON())
+	case domain.ErrorForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, ginError.JSON())
+	case domain.ErrorKeyNotFound:
+		c.AbortWithStatusJSON(http.StatusNotFound, ginError.JSON())
+	case domain.ErrorRateLimited:
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, ginError.JSON())
+	case domain.ErrorShuttingDown:
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, ginError.JSON())
+	case domain.ErrorNotReady:
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, ginError.JSON())
 	default:
 		c.AbortWithStatusJSON(http.StatusInternalServerError, ginError.JSON())
 	}
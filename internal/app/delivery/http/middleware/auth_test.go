@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	tests := map[string]struct {
+		Header     string
+		Error      error
+		StatusCode int
+		Body       string
+	}{
+		"Success": {
+			Header:     "Bearer 0123456789abcdef.secret",
+			StatusCode: http.StatusOK,
+			Body:       "0123456789abcdef",
+		},
+		"Lower Case Scheme": {
+			Header:     "bearer 0123456789abcdef.secret",
+			StatusCode: http.StatusOK,
+			Body:       "0123456789abcdef",
+		},
+		"Admin": {
+			Header:     "Bearer admin-token",
+			StatusCode: http.StatusOK,
+			Body:       "admin",
+		},
+		"Invalid Key": {
+			Header:     "Bearer 0123456789abcdef.secret",
+			Error:      domain.ErrorUnauthorized,
+			StatusCode: http.StatusUnauthorized,
+		},
+		"Missing Header": {
+			StatusCode: http.StatusUnauthorized,
+		},
+		"Basic Scheme": {
+			Header:     "Basic dXNlcjpwYXNz",
+			StatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			keys := mocks.NewMockIKeyService(ctrl)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+			router.ContextWithFallback = true
+
+			if test.Error != nil || test.Body == "0123456789abcdef" {
+				keys.EXPECT().Authenticate(gomock.Any(), "0123456789abcdef.secret").Return(&domain.APIKey{ID: "0123456789abcdef"}, test.Error)
+			}
+
+			router.GET("/", Auth(keys, "admin-token"), func(c *gin.Context) {
+				// the service sees the gin context, not the request one
+				var ctx context.Context = c
+				if domain.IsAdmin(ctx) {
+					c.String(http.StatusOK, "admin")
+					return
+				}
+				c.String(http.StatusOK, domain.ActorFromContext(ctx))
+			})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			if test.Header != "" {
+				req.Header.Set("Authorization", test.Header)
+			}
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, test.StatusCode, w.Code)
+			if test.StatusCode == http.StatusOK {
+				require.Equal(t, test.Body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	router := gin.New()
+	router.GET("/", Admin("admin-token"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for header, status := range map[string]int{
+		"Bearer admin-token": http.StatusNoContent,
+		"Bearer admin":       http.StatusUnauthorized,
+		"":                   http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", header)
+
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, status, w.Code, header)
+	}
+}
+
+func TestAdmin_NoToken(t *testing.T) {
+	router := gin.New()
+	router.GET("/", Admin(""), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer ")
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
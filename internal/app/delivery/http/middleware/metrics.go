@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute is the route of the requests no route matched,
+// so scans of random paths do not grow the number of series
+const unmatchedRoute = "unmatched"
+
+// Metrics counts the requests per route pattern and status and observes their
+// latency, it goes first so the requests the other middlewares answer are counted too
+func Metrics(registerer prometheus.Registerer) gin.HandlerFunc {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shorturl_http_requests_total",
+		Help: "Http requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shorturl_http_request_duration_seconds",
+		Help:    "Latency of the http requests by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	registerer.MustRegister(requests, duration)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		duration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	router := gin.New()
+	router.Use(Metrics(registry))
+	router.GET("/:link", func(c *gin.Context) {
+		if c.Param("link") == "missing" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusFound)
+	})
+
+	for _, path := range []string{"/S0mE__Lin4", "/bE2bqvWHr9", "/missing", "/a/b/c"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	expected := `
+		# HELP shorturl_http_requests_total Http requests by method, route pattern and status.
+		# TYPE shorturl_http_requests_total counter
+		shorturl_http_requests_total{method="GET",route="/:link",status="302"} 2
+		shorturl_http_requests_total{method="GET",route="/:link",status="404"} 1
+		shorturl_http_requests_total{method="GET",route="unmatched",status="404"} 1
+	`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "shorturl_http_requests_total"))
+	require.Equal(t, 2, testutil.CollectAndCount(registry, "shorturl_http_request_duration_seconds"))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/delivery/http/helpers"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits the requests of every client per route, the scope is the
+// method with the route pattern, such as "POST /". Clients are told apart by
+// the api key, so it has to run after Auth, requests without a key by their IP
+func RateLimit(limiter domain.IRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := "ip:" + c.ClientIP()
+		if actor := domain.ActorFromContext(c.Request.Context()); actor != "" {
+			client = "key:" + actor
+		}
+
+		if wait, ok := limiter.Allow(c.Request.Method+" "+c.FullPath(), client); !ok {
+			c.Header("Retry-After", retryAfter(wait))
+			helpers.HTTPError(c, domain.ErrorRateLimited)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// retryAfter rounds the wait up to whole seconds
+func retryAfter(wait time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10)
+}
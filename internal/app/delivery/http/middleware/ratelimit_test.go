@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := map[string]struct {
+		Actor      string
+		Client     string
+		Wait       time.Duration
+		Allowed    bool
+		StatusCode int
+		RetryAfter string
+	}{
+		"Allowed By IP": {
+			Client:     "ip:192.0.2.1",
+			Allowed:    true,
+			StatusCode: http.StatusNoContent,
+		},
+		"Allowed By Key": {
+			Actor:      "0123456789abcdef",
+			Client:     "key:0123456789abcdef",
+			Allowed:    true,
+			StatusCode: http.StatusNoContent,
+		},
+		"Limited": {
+			Client:     "ip:192.0.2.1",
+			Wait:       1500 * time.Millisecond,
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: "2",
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			limiter := mocks.NewMockIRateLimiter(ctrl)
+			limiter.EXPECT().Allow("POST /", test.Client).Return(test.Wait, test.Allowed)
+
+			router := gin.New()
+			router.POST("/", func(c *gin.Context) {
+				if test.Actor != "" {
+					c.Request = c.Request.WithContext(domain.WithActor(c.Request.Context(), test.Actor))
+				}
+			}, RateLimit(limiter), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, test.StatusCode, w.Code)
+			require.Equal(t, test.RetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor attaches who changes a link to the context of the request
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the attached actor or an empty string
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+type adminKey struct{}
+
+// WithAdmin marks the request as made with the admin token, the admin manages every link
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether the request is made with the admin token
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
@@ -0,0 +1,15 @@
+package domain
+
+// APIKey identifies a client that manages links, links are owned by the key id
+// so rotating the secret keeps the links with the key
+type APIKey struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Hash      string `json:"-"` // hex sha256 of the secret, the secret itself is never stored
+	CreatedAt int64  `json:"created"`
+	RevokedAt int64  `json:"revoked,omitempty"` // unix time, zero means the key is active
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != 0
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "context"
+
+// Client describes who resolves a short link
+type Client struct {
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// Click is a single successful resolve of a short link
+type Click struct {
+	Short  string `json:"short"`
+	At     int64  `json:"at"` // unix time
+	Client        // who made the click
+}
+
+type clientKey struct{}
+
+// WithClient attaches the client to the context of the resolve request
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the attached client or an empty one
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
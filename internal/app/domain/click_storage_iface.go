@@ -0,0 +1,11 @@
+package domain
+
+import "context"
+
+type IClickStorage interface {
+	AddClick(context.Context, Click) error
+	// AddClicks writes the batch at once, the slice is not retained
+	AddClicks(context.Context, []Click) error
+	// GetStats counts clicks of the link, buckets without clicks are omitted
+	GetStats(context.Context, StatsRequest) (*Stats, error)
+}
@@ -3,9 +3,31 @@ package domain
 import "errors"
 
 var (
-	ErrorInvalidShort    = errors.New("invalid short link")
-	ErrorInvalidLink     = errors.New("invalid link")
-	ErrorInvalidDecode   = errors.New("link cant decode")
-	ErrorLinkNotFound    = errors.New("link not found")
-	ErrorGenerateTimeout = errors.New("generate short link timeout")
+	ErrorInvalidShort     = errors.New("invalid short link")
+	ErrorInvalidLink      = errors.New("invalid link")
+	ErrorInvalidDecode    = errors.New("link cant decode")
+	ErrorLinkNotFound     = errors.New("link not found")
+	ErrorLinkGone         = errors.New("link was deleted or disabled")
+	ErrorGenerateTimeout  = errors.New("generate short link timeout")
+	ErrorLongExists       = errors.New("long link already shortened")
+	ErrorShortExists      = errors.New("short link already exists")
+	ErrorInvalidExpiry    = errors.New("invalid link expiry")
+	ErrorInvalidAlias     = errors.New("invalid alias")
+	ErrorInvalidRedirect  = errors.New("invalid redirect status")
+	ErrorInvalidStats     = errors.New("invalid stats range")
+	ErrorAliasTaken       = errors.New("alias already taken")
+	ErrorInvalidNodeID    = errors.New("invalid generator node id")
+	ErrorClockOverflow    = errors.New("generator clock is past the id layout")
+	ErrorVersionNotFound  = errors.New("link version not found")
+	ErrorUnauthorized     = errors.New("missing or invalid api key")
+	ErrorForbidden        = errors.New("link is owned by another api key")
+	ErrorKeyNotFound      = errors.New("api key not found")
+	ErrorRateLimited      = errors.New("rate limit exceeded")
+	ErrorInvalidLimit     = errors.New("invalid rate limit")
+	ErrorInvalidBatch     = errors.New("batch is empty or too large")
+	ErrorImportConflict   = errors.New("imported link already exists")
+	ErrorInvalidRecord    = errors.New("invalid link record")
+	ErrorUnknownMigration = errors.New("applied migration is unknown to this binary")
+	ErrorShuttingDown     = errors.New("server is shutting down")
+	ErrorNotReady         = errors.New("storage is not reachable")
 )
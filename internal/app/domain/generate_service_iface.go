@@ -1,5 +1,6 @@
 package domain
 
 type IGenerateLinkService interface {
-	GenerateShortLink() (string, int64)
+	// GenerateShortLink returns a new short link and the unix time it was made at
+	GenerateShortLink() (string, int64, error)
 }
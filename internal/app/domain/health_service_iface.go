@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+type IHealthService interface {
+	// Ready fails with ErrorShuttingDown once the server drains its requests
+	// and with ErrorNotReady while the storage does not answer
+	Ready(context.Context) error
+}
@@ -0,0 +1,10 @@
+package domain
+
+// ImportReport counts what an import did, or would do on a dry run
+type ImportReport struct {
+	Read        int64 `json:"read"`
+	Created     int64 `json:"created"`
+	Overwritten int64 `json:"overwritten"`
+	Skipped     int64 `json:"skipped"`
+	DryRun      bool  `json:"dry_run,omitempty"`
+}
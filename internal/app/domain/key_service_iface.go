@@ -0,0 +1,13 @@
+package domain
+
+import "context"
+
+type IKeyService interface {
+	// IssueKey returns the new key with its token, the token is shown only once
+	IssueKey(context.Context, string) (string, *APIKey, error)
+	// Authenticate resolves the token to an active key or fails with ErrorUnauthorized
+	Authenticate(context.Context, string) (*APIKey, error)
+	RevokeKey(context.Context, string) error
+	// RotateKey issues a new token for the key, the old token stops working
+	RotateKey(context.Context, string) (string, error)
+}
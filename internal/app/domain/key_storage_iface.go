@@ -0,0 +1,13 @@
+package domain
+
+import "context"
+
+type IKeyStorage interface {
+	AddKey(context.Context, APIKey) error
+	// GetKey returns the key even if it is revoked, ErrorKeyNotFound if it does not exist
+	GetKey(context.Context, string) (*APIKey, error)
+	// RevokeKey revokes an active key at the given unix time
+	RevokeKey(context.Context, string, int64) error
+	// RotateKey replaces the secret hash of an active key
+	RotateKey(context.Context, string, string) error
+}
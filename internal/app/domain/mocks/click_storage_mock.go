@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: click_storage_iface.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/Totus-Floreo/shortURL/internal/app/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIClickStorage is a mock of IClickStorage interface.
+type MockIClickStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockIClickStorageMockRecorder
+}
+
+// MockIClickStorageMockRecorder is the mock recorder for MockIClickStorage.
+type MockIClickStorageMockRecorder struct {
+	mock *MockIClickStorage
+}
+
+// NewMockIClickStorage creates a new mock instance.
+func NewMockIClickStorage(ctrl *gomock.Controller) *MockIClickStorage {
+	mock := &MockIClickStorage{ctrl: ctrl}
+	mock.recorder = &MockIClickStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIClickStorage) EXPECT() *MockIClickStorageMockRecorder {
+	return m.recorder
+}
+
+// AddClick mocks base method.
+func (m *MockIClickStorage) AddClick(arg0 context.Context, arg1 domain.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddClick", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddClick indicates an expected call of AddClick.
+func (mr *MockIClickStorageMockRecorder) AddClick(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClick", reflect.TypeOf((*MockIClickStorage)(nil).AddClick), arg0, arg1)
+}
+
+// AddClicks mocks base method.
+func (m *MockIClickStorage) AddClicks(arg0 context.Context, arg1 []domain.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddClicks", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddClicks indicates an expected call of AddClicks.
+func (mr *MockIClickStorageMockRecorder) AddClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClicks", reflect.TypeOf((*MockIClickStorage)(nil).AddClicks), arg0, arg1)
+}
+
+// GetStats mocks base method.
+func (m *MockIClickStorage) GetStats(arg0 context.Context, arg1 domain.StatsRequest) (*domain.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockIClickStorageMockRecorder) GetStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockIClickStorage)(nil).GetStats), arg0, arg1)
+}
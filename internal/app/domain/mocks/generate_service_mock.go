@@ -34,12 +34,13 @@ func (m *MockIGenerateLinkService) EXPECT() *MockIGenerateLinkServiceMockRecorde
 }
 
 // GenerateShortLink mocks base method.
-func (m *MockIGenerateLinkService) GenerateShortLink() (string, int64) {
+func (m *MockIGenerateLinkService) GenerateShortLink() (string, int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GenerateShortLink")
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(int64)
-	return ret0, ret1
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GenerateShortLink indicates an expected call of GenerateShortLink.
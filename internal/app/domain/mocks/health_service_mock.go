@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: health_service_iface.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIHealthService is a mock of IHealthService interface.
+type MockIHealthService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIHealthServiceMockRecorder
+}
+
+// MockIHealthServiceMockRecorder is the mock recorder for MockIHealthService.
+type MockIHealthServiceMockRecorder struct {
+	mock *MockIHealthService
+}
+
+// NewMockIHealthService creates a new mock instance.
+func NewMockIHealthService(ctrl *gomock.Controller) *MockIHealthService {
+	mock := &MockIHealthService{ctrl: ctrl}
+	mock.recorder = &MockIHealthServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIHealthService) EXPECT() *MockIHealthServiceMockRecorder {
+	return m.recorder
+}
+
+// Ready mocks base method.
+func (m *MockIHealthService) Ready(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ready", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ready indicates an expected call of Ready.
+func (mr *MockIHealthServiceMockRecorder) Ready(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ready", reflect.TypeOf((*MockIHealthService)(nil).Ready), arg0)
+}
@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: key_service_iface.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/Totus-Floreo/shortURL/internal/app/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIKeyService is a mock of IKeyService interface.
+type MockIKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIKeyServiceMockRecorder
+}
+
+// MockIKeyServiceMockRecorder is the mock recorder for MockIKeyService.
+type MockIKeyServiceMockRecorder struct {
+	mock *MockIKeyService
+}
+
+// NewMockIKeyService creates a new mock instance.
+func NewMockIKeyService(ctrl *gomock.Controller) *MockIKeyService {
+	mock := &MockIKeyService{ctrl: ctrl}
+	mock.recorder = &MockIKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIKeyService) EXPECT() *MockIKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockIKeyService) Authenticate(arg0 context.Context, arg1 string) (*domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", arg0, arg1)
+	ret0, _ := ret[0].(*domain.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockIKeyServiceMockRecorder) Authenticate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockIKeyService)(nil).Authenticate), arg0, arg1)
+}
+
+// IssueKey mocks base method.
+func (m *MockIKeyService) IssueKey(arg0 context.Context, arg1 string) (string, *domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueKey", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*domain.APIKey)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IssueKey indicates an expected call of IssueKey.
+func (mr *MockIKeyServiceMockRecorder) IssueKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueKey", reflect.TypeOf((*MockIKeyService)(nil).IssueKey), arg0, arg1)
+}
+
+// RevokeKey mocks base method.
+func (m *MockIKeyService) RevokeKey(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeKey indicates an expected call of RevokeKey.
+func (mr *MockIKeyServiceMockRecorder) RevokeKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeKey", reflect.TypeOf((*MockIKeyService)(nil).RevokeKey), arg0, arg1)
+}
+
+// RotateKey mocks base method.
+func (m *MockIKeyService) RotateKey(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateKey", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateKey indicates an expected call of RotateKey.
+func (mr *MockIKeyServiceMockRecorder) RotateKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateKey", reflect.TypeOf((*MockIKeyService)(nil).RotateKey), arg0, arg1)
+}
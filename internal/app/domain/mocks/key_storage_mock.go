@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: key_storage_iface.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/Totus-Floreo/shortURL/internal/app/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIKeyStorage is a mock of IKeyStorage interface.
+type MockIKeyStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockIKeyStorageMockRecorder
+}
+
+// MockIKeyStorageMockRecorder is the mock recorder for MockIKeyStorage.
+type MockIKeyStorageMockRecorder struct {
+	mock *MockIKeyStorage
+}
+
+// NewMockIKeyStorage creates a new mock instance.
+func NewMockIKeyStorage(ctrl *gomock.Controller) *MockIKeyStorage {
+	mock := &MockIKeyStorage{ctrl: ctrl}
+	mock.recorder = &MockIKeyStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIKeyStorage) EXPECT() *MockIKeyStorageMockRecorder {
+	return m.recorder
+}
+
+// AddKey mocks base method.
+func (m *MockIKeyStorage) AddKey(arg0 context.Context, arg1 domain.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddKey indicates an expected call of AddKey.
+func (mr *MockIKeyStorageMockRecorder) AddKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddKey", reflect.TypeOf((*MockIKeyStorage)(nil).AddKey), arg0, arg1)
+}
+
+// GetKey mocks base method.
+func (m *MockIKeyStorage) GetKey(arg0 context.Context, arg1 string) (*domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKey", arg0, arg1)
+	ret0, _ := ret[0].(*domain.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKey indicates an expected call of GetKey.
+func (mr *MockIKeyStorageMockRecorder) GetKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKey", reflect.TypeOf((*MockIKeyStorage)(nil).GetKey), arg0, arg1)
+}
+
+// RevokeKey mocks base method.
+func (m *MockIKeyStorage) RevokeKey(arg0 context.Context, arg1 string, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeKey indicates an expected call of RevokeKey.
+func (mr *MockIKeyStorageMockRecorder) RevokeKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeKey", reflect.TypeOf((*MockIKeyStorage)(nil).RevokeKey), arg0, arg1, arg2)
+}
+
+// RotateKey mocks base method.
+func (m *MockIKeyStorage) RotateKey(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateKey indicates an expected call of RotateKey.
+func (mr *MockIKeyStorageMockRecorder) RotateKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateKey", reflect.TypeOf((*MockIKeyStorage)(nil).RotateKey), arg0, arg1, arg2)
+}
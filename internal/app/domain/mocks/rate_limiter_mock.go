@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rate_limiter_iface.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIRateLimiter is a mock of IRateLimiter interface.
+type MockIRateLimiter struct {
+	ctrl     *gomock.Controller
+	recorder *MockIRateLimiterMockRecorder
+}
+
+// MockIRateLimiterMockRecorder is the mock recorder for MockIRateLimiter.
+type MockIRateLimiterMockRecorder struct {
+	mock *MockIRateLimiter
+}
+
+// NewMockIRateLimiter creates a new mock instance.
+func NewMockIRateLimiter(ctrl *gomock.Controller) *MockIRateLimiter {
+	mock := &MockIRateLimiter{ctrl: ctrl}
+	mock.recorder = &MockIRateLimiterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIRateLimiter) EXPECT() *MockIRateLimiterMockRecorder {
+	return m.recorder
+}
+
+// Allow mocks base method.
+func (m *MockIRateLimiter) Allow(scope, client string) (time.Duration, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", scope, client)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockIRateLimiterMockRecorder) Allow(scope, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockIRateLimiter)(nil).Allow), scope, client)
+}
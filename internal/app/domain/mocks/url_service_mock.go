@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	domain "github.com/Totus-Floreo/shortURL/internal/app/domain"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -35,7 +36,7 @@ func (m *MockIUrlService) EXPECT() *MockIUrlServiceMockRecorder {
 }
 
 // CreateUrl mocks base method.
-func (m *MockIUrlService) CreateUrl(arg0 context.Context, arg1 string) (string, error) {
+func (m *MockIUrlService) CreateUrl(arg0 context.Context, arg1 domain.URLRequest) (string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateUrl", arg0, arg1)
 	ret0, _ := ret[0].(string)
@@ -49,11 +50,84 @@ func (mr *MockIUrlServiceMockRecorder) CreateUrl(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUrl", reflect.TypeOf((*MockIUrlService)(nil).CreateUrl), arg0, arg1)
 }
 
+// CreateUrls mocks base method.
+func (m *MockIUrlService) CreateUrls(arg0 context.Context, arg1 []domain.URLRequest) ([]domain.URLResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUrls", arg0, arg1)
+	ret0, _ := ret[0].([]domain.URLResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUrls indicates an expected call of CreateUrls.
+func (mr *MockIUrlServiceMockRecorder) CreateUrls(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUrls", reflect.TypeOf((*MockIUrlService)(nil).CreateUrls), arg0, arg1)
+}
+
+// DeleteUrl mocks base method.
+func (m *MockIUrlService) DeleteUrl(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUrl", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUrl indicates an expected call of DeleteUrl.
+func (mr *MockIUrlServiceMockRecorder) DeleteUrl(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUrl", reflect.TypeOf((*MockIUrlService)(nil).DeleteUrl), arg0, arg1)
+}
+
+// DisableUrl mocks base method.
+func (m *MockIUrlService) DisableUrl(arg0 context.Context, arg1 string, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUrl", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableUrl indicates an expected call of DisableUrl.
+func (mr *MockIUrlServiceMockRecorder) DisableUrl(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUrl", reflect.TypeOf((*MockIUrlService)(nil).DisableUrl), arg0, arg1, arg2)
+}
+
+// GetHistory mocks base method.
+func (m *MockIUrlService) GetHistory(arg0 context.Context, arg1 string) ([]domain.URLVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].([]domain.URLVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockIUrlServiceMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockIUrlService)(nil).GetHistory), arg0, arg1)
+}
+
+// GetStats mocks base method.
+func (m *MockIUrlService) GetStats(arg0 context.Context, arg1 domain.StatsRequest) (*domain.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockIUrlServiceMockRecorder) GetStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockIUrlService)(nil).GetStats), arg0, arg1)
+}
+
 // GetUrl mocks base method.
-func (m *MockIUrlService) GetUrl(arg0 context.Context, arg1 string) (string, error) {
+func (m *MockIUrlService) GetUrl(arg0 context.Context, arg1 string) (*domain.URLLong, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetUrl", arg0, arg1)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(*domain.URLLong)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -63,3 +137,60 @@ func (mr *MockIUrlServiceMockRecorder) GetUrl(arg0, arg1 interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUrl", reflect.TypeOf((*MockIUrlService)(nil).GetUrl), arg0, arg1)
 }
+
+// ResolveUrls mocks base method.
+func (m *MockIUrlService) ResolveUrls(arg0 context.Context, arg1 []string) ([]domain.ResolveResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveUrls", arg0, arg1)
+	ret0, _ := ret[0].([]domain.ResolveResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveUrls indicates an expected call of ResolveUrls.
+func (mr *MockIUrlServiceMockRecorder) ResolveUrls(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveUrls", reflect.TypeOf((*MockIUrlService)(nil).ResolveUrls), arg0, arg1)
+}
+
+// RestoreUrl mocks base method.
+func (m *MockIUrlService) RestoreUrl(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUrl", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreUrl indicates an expected call of RestoreUrl.
+func (mr *MockIUrlServiceMockRecorder) RestoreUrl(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUrl", reflect.TypeOf((*MockIUrlService)(nil).RestoreUrl), arg0, arg1)
+}
+
+// RollbackUrl mocks base method.
+func (m *MockIUrlService) RollbackUrl(arg0 context.Context, arg1 string, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackUrl", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollbackUrl indicates an expected call of RollbackUrl.
+func (mr *MockIUrlServiceMockRecorder) RollbackUrl(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackUrl", reflect.TypeOf((*MockIUrlService)(nil).RollbackUrl), arg0, arg1, arg2)
+}
+
+// UpdateUrl mocks base method.
+func (m *MockIUrlService) UpdateUrl(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUrl", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUrl indicates an expected call of UpdateUrl.
+func (mr *MockIUrlServiceMockRecorder) UpdateUrl(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUrl", reflect.TypeOf((*MockIUrlService)(nil).UpdateUrl), arg0, arg1, arg2)
+}
@@ -49,6 +49,108 @@ func (mr *MockIUrlStorageMockRecorder) AddUrl(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUrl", reflect.TypeOf((*MockIUrlStorage)(nil).AddUrl), arg0, arg1)
 }
 
+// AddUrls mocks base method.
+func (m *MockIUrlStorage) AddUrls(arg0 context.Context, arg1 []domain.URLData) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUrls", arg0, arg1)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddUrls indicates an expected call of AddUrls.
+func (mr *MockIUrlStorageMockRecorder) AddUrls(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUrls", reflect.TypeOf((*MockIUrlStorage)(nil).AddUrls), arg0, arg1)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockIUrlStorage) DeleteExpired(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockIUrlStorageMockRecorder) DeleteExpired(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockIUrlStorage)(nil).DeleteExpired), arg0, arg1)
+}
+
+// DeleteUrl mocks base method.
+func (m *MockIUrlStorage) DeleteUrl(arg0 context.Context, arg1 string, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUrl", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUrl indicates an expected call of DeleteUrl.
+func (mr *MockIUrlStorageMockRecorder) DeleteUrl(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUrl", reflect.TypeOf((*MockIUrlStorage)(nil).DeleteUrl), arg0, arg1, arg2)
+}
+
+// DisableUrl mocks base method.
+func (m *MockIUrlStorage) DisableUrl(arg0 context.Context, arg1 string, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUrl", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableUrl indicates an expected call of DisableUrl.
+func (mr *MockIUrlStorageMockRecorder) DisableUrl(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUrl", reflect.TypeOf((*MockIUrlStorage)(nil).DisableUrl), arg0, arg1, arg2)
+}
+
+// ExportUrls mocks base method.
+func (m *MockIUrlStorage) ExportUrls(arg0 context.Context, arg1 func(domain.URLData) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportUrls", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportUrls indicates an expected call of ExportUrls.
+func (mr *MockIUrlStorageMockRecorder) ExportUrls(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportUrls", reflect.TypeOf((*MockIUrlStorage)(nil).ExportUrls), arg0, arg1)
+}
+
+// GetHistory mocks base method.
+func (m *MockIUrlStorage) GetHistory(arg0 context.Context, arg1 string) ([]domain.URLVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].([]domain.URLVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockIUrlStorageMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockIUrlStorage)(nil).GetHistory), arg0, arg1)
+}
+
+// GetShort mocks base method.
+func (m *MockIUrlStorage) GetShort(arg0 context.Context, arg1, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetShort", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetShort indicates an expected call of GetShort.
+func (mr *MockIUrlStorageMockRecorder) GetShort(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShort", reflect.TypeOf((*MockIUrlStorage)(nil).GetShort), arg0, arg1, arg2)
+}
+
 // GetUrl mocks base method.
 func (m *MockIUrlStorage) GetUrl(arg0 context.Context, arg1 string) (*domain.URLLong, error) {
 	m.ctrl.T.Helper()
@@ -63,3 +165,90 @@ func (mr *MockIUrlStorageMockRecorder) GetUrl(arg0, arg1 interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUrl", reflect.TypeOf((*MockIUrlStorage)(nil).GetUrl), arg0, arg1)
 }
+
+// GetUrls mocks base method.
+func (m *MockIUrlStorage) GetUrls(arg0 context.Context, arg1 []string) (map[string]domain.URLLong, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUrls", arg0, arg1)
+	ret0, _ := ret[0].(map[string]domain.URLLong)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUrls indicates an expected call of GetUrls.
+func (mr *MockIUrlStorageMockRecorder) GetUrls(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUrls", reflect.TypeOf((*MockIUrlStorage)(nil).GetUrls), arg0, arg1)
+}
+
+// ImportUrls mocks base method.
+func (m *MockIUrlStorage) ImportUrls(arg0 context.Context, arg1 []domain.URLData, arg2 bool) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportUrls", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportUrls indicates an expected call of ImportUrls.
+func (mr *MockIUrlStorageMockRecorder) ImportUrls(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportUrls", reflect.TypeOf((*MockIUrlStorage)(nil).ImportUrls), arg0, arg1, arg2)
+}
+
+// Ping mocks base method.
+func (m *MockIUrlStorage) Ping(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockIUrlStorageMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIUrlStorage)(nil).Ping), arg0)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockIUrlStorage) PurgeDeleted(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockIUrlStorageMockRecorder) PurgeDeleted(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockIUrlStorage)(nil).PurgeDeleted), arg0, arg1)
+}
+
+// RestoreUrl mocks base method.
+func (m *MockIUrlStorage) RestoreUrl(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUrl", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreUrl indicates an expected call of RestoreUrl.
+func (mr *MockIUrlStorageMockRecorder) RestoreUrl(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUrl", reflect.TypeOf((*MockIUrlStorage)(nil).RestoreUrl), arg0, arg1)
+}
+
+// UpdateUrl mocks base method.
+func (m *MockIUrlStorage) UpdateUrl(arg0 context.Context, arg1 string, arg2 domain.URLUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUrl", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUrl indicates an expected call of UpdateUrl.
+func (mr *MockIUrlStorageMockRecorder) UpdateUrl(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUrl", reflect.TypeOf((*MockIUrlStorage)(nil).UpdateUrl), arg0, arg1, arg2)
+}
@@ -1,4 +1,8 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.14.0
+// source: short_url.proto
 
 package pb
 
@@ -7,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,7 +24,16 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ShortUrlClient interface {
 	CreateUrl(ctx context.Context, in *Long, opts ...grpc.CallOption) (*Short, error)
+	CreateUrls(ctx context.Context, opts ...grpc.CallOption) (ShortUrl_CreateUrlsClient, error)
 	GetUrl(ctx context.Context, in *Short, opts ...grpc.CallOption) (*Long, error)
+	ResolveStream(ctx context.Context, opts ...grpc.CallOption) (ShortUrl_ResolveStreamClient, error)
+	GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*Stats, error)
+	DeleteUrl(ctx context.Context, in *Short, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RestoreUrl(ctx context.Context, in *Short, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DisableUrl(ctx context.Context, in *DisableRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpdateUrl(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetHistory(ctx context.Context, in *Short, opts ...grpc.CallOption) (*History, error)
+	RollbackUrl(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type shortUrlClient struct {
@@ -39,6 +53,40 @@ func (c *shortUrlClient) CreateUrl(ctx context.Context, in *Long, opts ...grpc.C
 	return out, nil
 }
 
+func (c *shortUrlClient) CreateUrls(ctx context.Context, opts ...grpc.CallOption) (ShortUrl_CreateUrlsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ShortUrl_ServiceDesc.Streams[0], "/pb.ShortUrl/CreateUrls", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortUrlCreateUrlsClient{stream}
+	return x, nil
+}
+
+type ShortUrl_CreateUrlsClient interface {
+	Send(*Long) error
+	CloseAndRecv() (*Results, error)
+	grpc.ClientStream
+}
+
+type shortUrlCreateUrlsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortUrlCreateUrlsClient) Send(m *Long) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shortUrlCreateUrlsClient) CloseAndRecv() (*Results, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Results)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *shortUrlClient) GetUrl(ctx context.Context, in *Short, opts ...grpc.CallOption) (*Long, error) {
 	out := new(Long)
 	err := c.cc.Invoke(ctx, "/pb.ShortUrl/GetUrl", in, out, opts...)
@@ -48,12 +96,115 @@ func (c *shortUrlClient) GetUrl(ctx context.Context, in *Short, opts ...grpc.Cal
 	return out, nil
 }
 
+func (c *shortUrlClient) ResolveStream(ctx context.Context, opts ...grpc.CallOption) (ShortUrl_ResolveStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ShortUrl_ServiceDesc.Streams[1], "/pb.ShortUrl/ResolveStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortUrlResolveStreamClient{stream}
+	return x, nil
+}
+
+type ShortUrl_ResolveStreamClient interface {
+	Send(*ResolveRequest) error
+	Recv() (*ResolveResponse, error)
+	grpc.ClientStream
+}
+
+type shortUrlResolveStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortUrlResolveStreamClient) Send(m *ResolveRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shortUrlResolveStreamClient) Recv() (*ResolveResponse, error) {
+	m := new(ResolveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortUrlClient) GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) DeleteUrl(ctx context.Context, in *Short, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/DeleteUrl", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) RestoreUrl(ctx context.Context, in *Short, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/RestoreUrl", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) DisableUrl(ctx context.Context, in *DisableRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/DisableUrl", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) UpdateUrl(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/UpdateUrl", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) GetHistory(ctx context.Context, in *Short, opts ...grpc.CallOption) (*History, error) {
+	out := new(History)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/GetHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortUrlClient) RollbackUrl(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/pb.ShortUrl/RollbackUrl", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortUrlServer is the server API for ShortUrl service.
 // All implementations must embed UnimplementedShortUrlServer
 // for forward compatibility
 type ShortUrlServer interface {
 	CreateUrl(context.Context, *Long) (*Short, error)
+	CreateUrls(ShortUrl_CreateUrlsServer) error
 	GetUrl(context.Context, *Short) (*Long, error)
+	ResolveStream(ShortUrl_ResolveStreamServer) error
+	GetStats(context.Context, *StatsRequest) (*Stats, error)
+	DeleteUrl(context.Context, *Short) (*emptypb.Empty, error)
+	RestoreUrl(context.Context, *Short) (*emptypb.Empty, error)
+	DisableUrl(context.Context, *DisableRequest) (*emptypb.Empty, error)
+	UpdateUrl(context.Context, *UpdateRequest) (*emptypb.Empty, error)
+	GetHistory(context.Context, *Short) (*History, error)
+	RollbackUrl(context.Context, *RollbackRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedShortUrlServer()
 }
 
@@ -64,9 +215,36 @@ type UnimplementedShortUrlServer struct {
 func (UnimplementedShortUrlServer) CreateUrl(context.Context, *Long) (*Short, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateUrl not implemented")
 }
+func (UnimplementedShortUrlServer) CreateUrls(ShortUrl_CreateUrlsServer) error {
+	return status.Errorf(codes.Unimplemented, "method CreateUrls not implemented")
+}
 func (UnimplementedShortUrlServer) GetUrl(context.Context, *Short) (*Long, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUrl not implemented")
 }
+func (UnimplementedShortUrlServer) ResolveStream(ShortUrl_ResolveStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ResolveStream not implemented")
+}
+func (UnimplementedShortUrlServer) GetStats(context.Context, *StatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedShortUrlServer) DeleteUrl(context.Context, *Short) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUrl not implemented")
+}
+func (UnimplementedShortUrlServer) RestoreUrl(context.Context, *Short) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreUrl not implemented")
+}
+func (UnimplementedShortUrlServer) DisableUrl(context.Context, *DisableRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableUrl not implemented")
+}
+func (UnimplementedShortUrlServer) UpdateUrl(context.Context, *UpdateRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUrl not implemented")
+}
+func (UnimplementedShortUrlServer) GetHistory(context.Context, *Short) (*History, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedShortUrlServer) RollbackUrl(context.Context, *RollbackRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackUrl not implemented")
+}
 func (UnimplementedShortUrlServer) mustEmbedUnimplementedShortUrlServer() {}
 
 // UnsafeShortUrlServer may be embedded to opt out of forward compatibility for this service.
@@ -98,6 +276,32 @@ func _ShortUrl_CreateUrl_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortUrl_CreateUrls_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortUrlServer).CreateUrls(&shortUrlCreateUrlsServer{stream})
+}
+
+type ShortUrl_CreateUrlsServer interface {
+	SendAndClose(*Results) error
+	Recv() (*Long, error)
+	grpc.ServerStream
+}
+
+type shortUrlCreateUrlsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortUrlCreateUrlsServer) SendAndClose(m *Results) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shortUrlCreateUrlsServer) Recv() (*Long, error) {
+	m := new(Long)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _ShortUrl_GetUrl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Short)
 	if err := dec(in); err != nil {
@@ -116,6 +320,158 @@ func _ShortUrl_GetUrl_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortUrl_ResolveStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortUrlServer).ResolveStream(&shortUrlResolveStreamServer{stream})
+}
+
+type ShortUrl_ResolveStreamServer interface {
+	Send(*ResolveResponse) error
+	Recv() (*ResolveRequest, error)
+	grpc.ServerStream
+}
+
+type shortUrlResolveStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortUrlResolveStreamServer) Send(m *ResolveResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shortUrlResolveStreamServer) Recv() (*ResolveRequest, error) {
+	m := new(ResolveRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ShortUrl_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).GetStats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_DeleteUrl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Short)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).DeleteUrl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/DeleteUrl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).DeleteUrl(ctx, req.(*Short))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_RestoreUrl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Short)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).RestoreUrl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/RestoreUrl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).RestoreUrl(ctx, req.(*Short))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_DisableUrl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).DisableUrl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/DisableUrl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).DisableUrl(ctx, req.(*DisableRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_UpdateUrl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).UpdateUrl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/UpdateUrl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).UpdateUrl(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Short)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/GetHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).GetHistory(ctx, req.(*Short))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortUrl_RollbackUrl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortUrlServer).RollbackUrl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ShortUrl/RollbackUrl",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortUrlServer).RollbackUrl(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ShortUrl_ServiceDesc is the grpc.ServiceDesc for ShortUrl service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -131,7 +487,47 @@ var ShortUrl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetUrl",
 			Handler:    _ShortUrl_GetUrl_Handler,
 		},
+		{
+			MethodName: "GetStats",
+			Handler:    _ShortUrl_GetStats_Handler,
+		},
+		{
+			MethodName: "DeleteUrl",
+			Handler:    _ShortUrl_DeleteUrl_Handler,
+		},
+		{
+			MethodName: "RestoreUrl",
+			Handler:    _ShortUrl_RestoreUrl_Handler,
+		},
+		{
+			MethodName: "DisableUrl",
+			Handler:    _ShortUrl_DisableUrl_Handler,
+		},
+		{
+			MethodName: "UpdateUrl",
+			Handler:    _ShortUrl_UpdateUrl_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _ShortUrl_GetHistory_Handler,
+		},
+		{
+			MethodName: "RollbackUrl",
+			Handler:    _ShortUrl_RollbackUrl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CreateUrls",
+			Handler:       _ShortUrl_CreateUrls_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ResolveStream",
+			Handler:       _ShortUrl_ResolveStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "short_url.proto",
 }
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+type IRateLimiter interface {
+	// Allow takes a token of the client from the bucket of the scope, a route
+	// such as "POST /" or a full rpc method. It returns how long the client
+	// has to wait when the bucket is empty, scopes without a limit always pass
+	Allow(scope string, client string) (time.Duration, bool)
+}
@@ -0,0 +1,19 @@
+package domain
+
+type StatsRequest struct {
+	Short    string
+	From     int64 // unix time, inclusive
+	To       int64 // unix time, exclusive
+	Interval int64 // bucket size in seconds
+}
+
+type Stats struct {
+	Short   string   `json:"short"`
+	Total   int64    `json:"total"` // all time clicks
+	Buckets []Bucket `json:"buckets"`
+}
+
+type Bucket struct {
+	Start int64 `json:"start"` // unix time the bucket begins at
+	Count int64 `json:"count"`
+}
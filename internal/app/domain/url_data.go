@@ -3,7 +3,7 @@ package domain
 type URLData struct {
 	URLShort string `json:"short"`
 	URLLong         // original link struct
-	// ExpiresAt time.Time // unrealized, cuz its not specified in test task
+	Alias    bool   `json:"alias,omitempty"` // short link was chosen by the client
 }
 
 func NewURLData(short string, long string, addedAt int64) *URLData {
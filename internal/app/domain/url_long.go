@@ -1,6 +1,25 @@
 package domain
 
 type URLLong struct {
-	LongURL string `json:"link"`
-	AddedAt int64  `json:"added"` // addition to justify the construction
+	LongURL   string `json:"link"`
+	AddedAt   int64  `json:"added"`              // addition to justify the construction
+	ExpiresAt int64  `json:"expires,omitempty"`  // unix time, zero means the link never expires
+	Redirect  int    `json:"redirect,omitempty"` // http redirect status, zero means the server default
+	DeletedAt int64  `json:"deleted,omitempty"`  // unix time of the soft delete, zero means not deleted
+	Disabled  bool   `json:"disabled,omitempty"`
+	Owner     string `json:"owner,omitempty"` // api key id, empty for links made without authentication
+}
+
+func (l URLLong) Expired(now int64) bool {
+	return l.ExpiresAt != 0 && l.ExpiresAt <= now
+}
+
+// Gone reports a link taken down by its owner, it still holds its short link
+func (l URLLong) Gone() bool {
+	return l.DeletedAt != 0 || l.Disabled
+}
+
+// Active reports a link that resolves and can be reused for its long link
+func (l URLLong) Active(now int64) bool {
+	return !l.Expired(now) && !l.Gone()
 }
@@ -0,0 +1,10 @@
+package domain
+
+// URLRequest is the input of IUrlService.CreateUrl
+type URLRequest struct {
+	LongURL   string `json:"link"`
+	TTL       int64  `json:"ttl,omitempty"`      // lifetime in seconds, ignored if ExpiresAt is set
+	ExpiresAt int64  `json:"expires,omitempty"`  // absolute unix expiry time
+	Alias     string `json:"alias,omitempty"`    // custom short link instead of a generated one
+	Redirect  int    `json:"redirect,omitempty"` // http redirect status of the link
+}
@@ -0,0 +1,16 @@
+package domain
+
+// URLResult is an item of the IUrlService.CreateUrls result,
+// it has either the short link or the error of the request at the same index
+type URLResult struct {
+	Short string
+	Err   error
+}
+
+// ResolveResult is an item of the IUrlService.ResolveUrls result,
+// it has either the original link or the error of the short link
+type ResolveResult struct {
+	Short string
+	Long  *URLLong
+	Err   error
+}
@@ -3,6 +3,17 @@ package domain
 import "context"
 
 type IUrlService interface {
-	CreateUrl(context.Context, string) (string, error)
-	GetUrl(context.Context, string) (string, error)
+	CreateUrl(context.Context, URLRequest) (string, error)
+	// CreateUrls creates the links of the batch, results are in the order of the requests
+	CreateUrls(context.Context, []URLRequest) ([]URLResult, error)
+	GetUrl(context.Context, string) (*URLLong, error)
+	// ResolveUrls looks the links up without recording clicks, results are in the order of the short links
+	ResolveUrls(context.Context, []string) ([]ResolveResult, error)
+	GetStats(context.Context, StatsRequest) (*Stats, error)
+	DeleteUrl(context.Context, string) error
+	RestoreUrl(context.Context, string) error
+	DisableUrl(context.Context, string, bool) error
+	UpdateUrl(context.Context, string, string) error
+	GetHistory(context.Context, string) ([]URLVersion, error)
+	RollbackUrl(context.Context, string, int64) error
 }
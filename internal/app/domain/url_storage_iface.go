@@ -3,6 +3,42 @@ package domain
 import "context"
 
 type IUrlStorage interface {
+	// AddUrl atomically stores the link if its short link is free or expired,
+	// otherwise it fails with ErrorShortExists. Generated (non alias) links
+	// also fail with ErrorLongExists if the long link already has an active one
+	// of the same owner
 	AddUrl(context.Context, URLData) error
+	// AddUrls stores the batch at once, every link fails or is stored like with
+	// AddUrl, the errors are returned at the indexes of their links. Links of the
+	// batch also collide with the links before them. Only the second error fails
+	// the whole batch, none of its links is stored then
+	AddUrls(context.Context, []URLData) ([]error, error)
 	GetUrl(context.Context, string) (*URLLong, error)
+	// GetUrls returns the stored links of the short links, missing ones are left out
+	GetUrls(context.Context, []string) (map[string]URLLong, error)
+	// GetShort returns the active generated short link of the long link and owner
+	GetShort(context.Context, string, string) (string, error)
+	DeleteExpired(context.Context, int64) (int64, error)
+	// DeleteUrl soft deletes the link at the given unix time
+	DeleteUrl(context.Context, string, int64) error
+	// RestoreUrl undoes the soft delete, ErrorLinkNotFound if the link is not deleted
+	RestoreUrl(context.Context, string) error
+	// DisableUrl switches a not deleted link off or back on
+	DisableUrl(context.Context, string, bool) error
+	// UpdateUrl retargets a not deleted link that has not expired at the time
+	// of the change, and keeps its previous long link as the next version in
+	// the history of the link
+	UpdateUrl(context.Context, string, URLUpdate) error
+	// GetHistory returns the previous versions of the link, oldest first
+	GetHistory(context.Context, string) ([]URLVersion, error)
+	// ExportUrls calls the function with every stored link, it stops at the first error
+	ExportUrls(context.Context, func(URLData) error) error
+	// ImportUrls stores the links as they are, without the checks of AddUrl.
+	// Links whose short link is taken fail with ErrorShortExists at their
+	// indexes, unless overwrite replaces the stored links and their history
+	ImportUrls(context.Context, []URLData, bool) ([]error, error)
+	// PurgeDeleted removes links soft deleted at or before the given unix time
+	PurgeDeleted(context.Context, int64) (int64, error)
+	// Ping fails when the storage can not serve requests
+	Ping(context.Context) error
 }
@@ -0,0 +1,16 @@
+package domain
+
+// URLVersion is a previous long link of a retargeted short link
+type URLVersion struct {
+	Version   int64  `json:"version"` // grows with every retarget of the short link
+	LongURL   string `json:"link"`
+	ChangedAt int64  `json:"changed"` // unix time the link was retargeted away from it
+	Actor     string `json:"actor,omitempty"`
+}
+
+// URLUpdate retargets a short link to a new long link
+type URLUpdate struct {
+	LongURL   string
+	ChangedAt int64 // unix time
+	Actor     string
+}
@@ -0,0 +1,376 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// UrlStorage is a read-through cache of the links of another storage. It keeps
+// up to Size links for TTL and remembers missing short links for NegativeTTL.
+// Changes made through it drop the cached links at once, changes made through
+// other instances are seen when the cached links run out of time
+type UrlStorage struct {
+	DB          domain.IUrlStorage
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration // zero does not cache missing short links
+	LoadTimeout time.Duration // the longest a shared load runs, its callers do not cancel it
+
+	mux     sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List          // most recently used first
+	running map[string]*running // short links being loaded
+	loads   singleflight.Group
+	now     func() time.Time
+
+	requests  *prometheus.CounterVec
+	evictions prometheus.Counter
+	size      prometheus.Gauge
+}
+
+// running counts the loads of a short link and the changes made to it while they run,
+// a load that sees a change is not cached
+type running struct {
+	loads   int
+	changes uint64
+}
+
+type entry struct {
+	short   string
+	long    *domain.URLLong // nil for a missing short link
+	expires time.Time
+}
+
+func NewUrlStorage(db domain.IUrlStorage, size int, ttl time.Duration, negativeTTL time.Duration, loadTimeout time.Duration, registerer prometheus.Registerer) *UrlStorage {
+	s := &UrlStorage{
+		DB:          db,
+		Size:        size,
+		TTL:         ttl,
+		NegativeTTL: negativeTTL,
+		LoadTimeout: loadTimeout,
+		entries:     make(map[string]*list.Element, size),
+		running:     make(map[string]*running),
+		order:       list.New(),
+		now:         time.Now,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shorturl_cache_requests_total",
+			Help: "Short links looked up in the link cache by result (hit or miss).",
+		}, []string{"result"}),
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shorturl_cache_evictions_total",
+			Help: "Links dropped from the full link cache.",
+		}),
+		size: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "shorturl_cache_entries",
+			Help: "Links in the link cache, missing short links included.",
+		}),
+	}
+
+	registerer.MustRegister(s.requests, s.evictions, s.size)
+	return s
+}
+
+func (s *UrlStorage) AddUrl(ctx context.Context, urlData domain.URLData) error {
+	defer s.invalidate(urlData.URLShort)
+	return s.DB.AddUrl(ctx, urlData)
+}
+
+func (s *UrlStorage) AddUrls(ctx context.Context, batch []domain.URLData) ([]error, error) {
+	defer s.invalidate(shorts(batch)...)
+	return s.DB.AddUrls(ctx, batch)
+}
+
+// GetUrl answers from the cache, concurrent misses of a short link share a single load.
+// The load outlives a caller that goes away, so it does not fail the others,
+// but not LoadTimeout, so a hung storage does not hold the short link forever
+func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
+	if long, ok := s.get(shortUrl); ok {
+		s.requests.WithLabelValues("hit").Inc()
+		if long == nil {
+			return &domain.URLLong{}, domain.ErrorLinkNotFound
+		}
+		return long, nil
+	}
+	s.requests.WithLabelValues("miss").Inc()
+
+	loads := s.loads.DoChan(shortUrl, func() (interface{}, error) {
+		load, cancel := context.WithTimeout(detached{ctx}, s.LoadTimeout)
+		defer cancel()
+
+		changes := s.begin(shortUrl)
+		long, err := s.DB.GetUrl(load, shortUrl)
+		switch err {
+		case nil:
+			s.end(shortUrl, changes, long, true)
+		case domain.ErrorLinkNotFound:
+			s.end(shortUrl, changes, nil, true)
+		default:
+			s.end(shortUrl, changes, nil, false)
+		}
+		return long, err
+	})
+
+	var result singleflight.Result
+	select {
+	case result = <-loads:
+	case <-ctx.Done():
+		return &domain.URLLong{}, ctx.Err()
+	}
+
+	// every caller gets its own copy
+	loaded, _ := result.Val.(*domain.URLLong)
+	if loaded == nil {
+		return &domain.URLLong{}, result.Err
+	}
+	long := *loaded
+	return &long, result.Err
+}
+
+func (s *UrlStorage) GetUrls(ctx context.Context, shortUrls []string) (map[string]domain.URLLong, error) {
+	found := make(map[string]domain.URLLong, len(shortUrls))
+	var misses []string
+	for _, short := range shortUrls {
+		long, ok := s.get(short)
+		if !ok {
+			misses = append(misses, short)
+			continue
+		}
+		if long != nil {
+			found[short] = *long
+		}
+	}
+	s.requests.WithLabelValues("hit").Add(float64(len(shortUrls) - len(misses)))
+	if len(misses) == 0 {
+		return found, nil
+	}
+	s.requests.WithLabelValues("miss").Add(float64(len(misses)))
+
+	changes := make([]uint64, len(misses))
+	for i, short := range misses {
+		changes[i] = s.begin(short)
+	}
+	loaded, err := s.DB.GetUrls(ctx, misses)
+	if err != nil {
+		for i, short := range misses {
+			s.end(short, changes[i], nil, false)
+		}
+		return nil, err
+	}
+	for i, short := range misses {
+		long, ok := loaded[short]
+		if !ok {
+			s.end(short, changes[i], nil, true)
+			continue
+		}
+		s.end(short, changes[i], &long, true)
+		found[short] = long
+	}
+
+	return found, nil
+}
+
+func (s *UrlStorage) GetShort(ctx context.Context, longUrl string, owner string) (string, error) {
+	return s.DB.GetShort(ctx, longUrl, owner)
+}
+
+func (s *UrlStorage) DeleteExpired(ctx context.Context, now int64) (int64, error) {
+	deleted, err := s.DB.DeleteExpired(ctx, now)
+	if deleted > 0 {
+		s.evict(func(long domain.URLLong) bool { return long.Expired(now) })
+	}
+	return deleted, err
+}
+
+func (s *UrlStorage) DeleteUrl(ctx context.Context, shortUrl string, deletedAt int64) error {
+	defer s.invalidate(shortUrl)
+	return s.DB.DeleteUrl(ctx, shortUrl, deletedAt)
+}
+
+func (s *UrlStorage) RestoreUrl(ctx context.Context, shortUrl string) error {
+	defer s.invalidate(shortUrl)
+	return s.DB.RestoreUrl(ctx, shortUrl)
+}
+
+func (s *UrlStorage) DisableUrl(ctx context.Context, shortUrl string, disabled bool) error {
+	defer s.invalidate(shortUrl)
+	return s.DB.DisableUrl(ctx, shortUrl, disabled)
+}
+
+func (s *UrlStorage) UpdateUrl(ctx context.Context, shortUrl string, update domain.URLUpdate) error {
+	defer s.invalidate(shortUrl)
+	return s.DB.UpdateUrl(ctx, shortUrl, update)
+}
+
+func (s *UrlStorage) GetHistory(ctx context.Context, shortUrl string) ([]domain.URLVersion, error) {
+	return s.DB.GetHistory(ctx, shortUrl)
+}
+
+func (s *UrlStorage) ExportUrls(ctx context.Context, fn func(domain.URLData) error) error {
+	return s.DB.ExportUrls(ctx, fn)
+}
+
+func (s *UrlStorage) ImportUrls(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+	defer s.invalidate(shorts(batch)...)
+	return s.DB.ImportUrls(ctx, batch, overwrite)
+}
+
+func (s *UrlStorage) PurgeDeleted(ctx context.Context, before int64) (int64, error) {
+	purged, err := s.DB.PurgeDeleted(ctx, before)
+	if purged > 0 {
+		s.evict(func(long domain.URLLong) bool { return long.DeletedAt != 0 && long.DeletedAt <= before })
+	}
+	return purged, err
+}
+
+func (s *UrlStorage) Ping(ctx context.Context) error {
+	return s.DB.Ping(ctx)
+}
+
+// get returns a copy of the cached link, nil if the short link is cached as missing
+func (s *UrlStorage) get(short string) (*domain.URLLong, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	element, ok := s.entries[short]
+	if !ok {
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if !s.now().Before(e.expires) {
+		s.remove(element)
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+
+	if e.long == nil {
+		return nil, true
+	}
+	long := *e.long
+	return &long, true
+}
+
+// begin registers a load of the short link and returns the changes made to it so far
+func (s *UrlStorage) begin(short string) uint64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	r, ok := s.running[short]
+	if !ok {
+		r = &running{}
+		s.running[short] = r
+	}
+	r.loads++
+	return r.changes
+}
+
+// end finishes a load of the short link begun with begin, the loaded link
+// is cached if ok and the short link did not change since the load began
+func (s *UrlStorage) end(short string, changes uint64, long *domain.URLLong, ok bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	r := s.running[short]
+	r.loads--
+	if r.loads == 0 {
+		delete(s.running, short)
+	}
+	if ok && r.changes == changes {
+		s.put(short, long)
+	}
+}
+
+// put caches the link, the caller must hold the lock
+func (s *UrlStorage) put(short string, long *domain.URLLong) {
+	ttl := s.TTL
+	if long == nil {
+		ttl = s.NegativeTTL
+	} else {
+		copied := *long
+		long = &copied
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	e := &entry{short: short, long: long, expires: s.now().Add(ttl)}
+	if element, ok := s.entries[short]; ok {
+		element.Value = e
+		s.order.MoveToFront(element)
+		return
+	}
+
+	s.entries[short] = s.order.PushFront(e)
+	for s.order.Len() > s.Size {
+		s.remove(s.order.Back())
+		s.evictions.Inc()
+	}
+	s.size.Set(float64(s.order.Len()))
+}
+
+// remove drops the entry, the caller must hold the lock
+func (s *UrlStorage) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*entry).short)
+	s.size.Set(float64(s.order.Len()))
+}
+
+// invalidate drops the cached short links and keeps their running loads from caching
+func (s *UrlStorage) invalidate(shorts ...string) {
+	s.mux.Lock()
+	for _, short := range shorts {
+		if r, ok := s.running[short]; ok {
+			r.changes++
+		}
+		if element, ok := s.entries[short]; ok {
+			s.remove(element)
+		}
+	}
+	s.mux.Unlock()
+
+	// later misses do not join a load that may have read the old link
+	for _, short := range shorts {
+		s.loads.Forget(short)
+	}
+}
+
+// evict drops the cached links removed from the storage by a bulk delete. The running
+// loads may have read a removed link before the delete, none of them is cached
+func (s *UrlStorage) evict(removed func(domain.URLLong) bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, r := range s.running {
+		r.changes++
+	}
+	for element := s.order.Front(); element != nil; {
+		next := element.Next()
+		if e := element.Value.(*entry); e.long != nil && removed(*e.long) {
+			s.remove(element)
+		}
+		element = next
+	}
+}
+
+// detached keeps the values of a context but is never cancelled
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+func shorts(batch []domain.URLData) []string {
+	shorts := make([]string, len(batch))
+	for i, urlData := range batch {
+		shorts[i] = urlData.URLShort
+	}
+	return shorts
+}
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCache(t *testing.T, size int) (*UrlStorage, *mocks.MockIUrlStorage, *time.Time) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	cache := NewUrlStorage(db, size, time.Minute, 10*time.Second, time.Second, prometheus.NewRegistry())
+
+	now := time.Unix(1686557090, 0)
+	cache.now = func() time.Time { return now }
+
+	return cache, db, &now
+}
+
+func TestGetUrl_Hit(t *testing.T) {
+	ctx := context.Background()
+	cache, db, now := newCache(t, 10)
+
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil).Times(2)
+
+	for i := 0; i < 3; i++ {
+		long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+		require.NoError(t, err)
+		require.Equal(t, "example.com", long.LongURL)
+
+		// the cached link is not shared with the callers
+		long.LongURL = "changed.com"
+	}
+
+	*now = now.Add(time.Minute)
+	_, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(cache.requests.WithLabelValues("hit")))
+	require.Equal(t, 2.0, testutil.ToFloat64(cache.requests.WithLabelValues("miss")))
+}
+
+func TestGetUrl_NotFound(t *testing.T) {
+	ctx := context.Background()
+	cache, db, now := newCache(t, 10)
+
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{}, domain.ErrorLinkNotFound)
+	db.EXPECT().GetUrl(gomock.Any(), "0tHeR_Lin4").Return(&domain.URLLong{}, context.DeadlineExceeded).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.GetUrl(ctx, "S0mE__Lin4")
+		require.Equal(t, domain.ErrorLinkNotFound, err)
+
+		// other errors are not cached
+		_, err = cache.GetUrl(ctx, "0tHeR_Lin4")
+		require.Equal(t, context.DeadlineExceeded, err)
+	}
+
+	// the missing short link is taken
+	db.EXPECT().AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)).Return(nil)
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil)
+
+	require.NoError(t, cache.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)))
+	long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+
+	// missing short links are cached for a shorter time
+	db.EXPECT().GetUrl(gomock.Any(), "Missing123").Return(&domain.URLLong{}, domain.ErrorLinkNotFound).Times(2)
+	_, err = cache.GetUrl(ctx, "Missing123")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	*now = now.Add(10 * time.Second)
+	_, err = cache.GetUrl(ctx, "Missing123")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestGetUrl_Evict(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 2)
+
+	for _, short := range []string{"F1rst_Lin4", "Sec0nd_Lin", "Th1rd_Lin4"} {
+		db.EXPECT().GetUrl(gomock.Any(), short).Return(&domain.URLLong{LongURL: short + ".com"}, nil)
+	}
+	db.EXPECT().GetUrl(gomock.Any(), "Sec0nd_Lin").Return(&domain.URLLong{LongURL: "Sec0nd_Lin.com"}, nil)
+
+	for _, short := range []string{"F1rst_Lin4", "Sec0nd_Lin", "F1rst_Lin4", "Th1rd_Lin4", "F1rst_Lin4", "Sec0nd_Lin"} {
+		_, err := cache.GetUrl(ctx, short)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(cache.evictions))
+	require.Equal(t, 2.0, testutil.ToFloat64(cache.size))
+}
+
+func TestGetUrl_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+
+	update := domain.URLUpdate{LongURL: "example.org"}
+	gomock.InOrder(
+		db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil),
+		db.EXPECT().UpdateUrl(ctx, "S0mE__Lin4", update).Return(nil),
+		db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.org"}, nil),
+	)
+
+	_, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+
+	require.NoError(t, cache.UpdateUrl(ctx, "S0mE__Lin4", update))
+	long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.org", long.LongURL)
+}
+
+func TestGetUrl_BulkDelete(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+
+	links := map[string]domain.URLLong{
+		"Expired123": {LongURL: "example.com", ExpiresAt: 1686557000},
+		"Deleted123": {LongURL: "example.org", DeletedAt: 1686557000},
+		"Active1234": {LongURL: "example.net", ExpiresAt: 1686560000},
+	}
+	for short, long := range links {
+		long := long
+		db.EXPECT().GetUrl(gomock.Any(), short).Return(&long, nil)
+		_, err := cache.GetUrl(ctx, short)
+		require.NoError(t, err)
+	}
+
+	// only the removed links are dropped
+	db.EXPECT().DeleteExpired(ctx, int64(1686557090)).Return(int64(1), nil)
+	db.EXPECT().GetUrl(gomock.Any(), "Expired123").Return(&domain.URLLong{}, domain.ErrorLinkNotFound)
+	_, err := cache.DeleteExpired(ctx, 1686557090)
+	require.NoError(t, err)
+
+	db.EXPECT().PurgeDeleted(ctx, int64(1686557090)).Return(int64(1), nil)
+	db.EXPECT().GetUrl(gomock.Any(), "Deleted123").Return(&domain.URLLong{}, domain.ErrorLinkNotFound)
+	_, err = cache.PurgeDeleted(ctx, 1686557090)
+	require.NoError(t, err)
+
+	for _, short := range []string{"Expired123", "Deleted123"} {
+		_, err := cache.GetUrl(ctx, short)
+		require.Equal(t, domain.ErrorLinkNotFound, err)
+	}
+	long, err := cache.GetUrl(ctx, "Active1234")
+	require.NoError(t, err)
+	require.Equal(t, "example.net", long.LongURL)
+	require.Equal(t, 1.0, testutil.ToFloat64(cache.requests.WithLabelValues("hit")))
+}
+
+func TestGetUrl_StaleLoad(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+
+	// the link changes while it is loaded
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").DoAndReturn(func(ctx context.Context, short string) (*domain.URLLong, error) {
+		cache.invalidate(short)
+		return &domain.URLLong{LongURL: "example.com"}, nil
+	})
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.org"}, nil)
+
+	long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+
+	long, err = cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.org", long.LongURL)
+}
+
+func TestGetUrl_OtherChange(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+
+	// another link is added while the link is loaded
+	db.EXPECT().AddUrl(ctx, *domain.NewURLData("0tHeR_Lin4", "example.org", 1686557090)).Return(nil)
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").DoAndReturn(func(_ context.Context, short string) (*domain.URLLong, error) {
+		assert.NoError(t, cache.AddUrl(ctx, *domain.NewURLData("0tHeR_Lin4", "example.org", 1686557090)))
+		return &domain.URLLong{LongURL: "example.com"}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+		require.NoError(t, err)
+		require.Equal(t, "example.com", long.LongURL)
+	}
+	require.Empty(t, cache.running)
+}
+
+func TestGetUrl_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+
+	release := make(chan struct{})
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").DoAndReturn(func(ctx context.Context, short string) (*domain.URLLong, error) {
+		<-release
+		return &domain.URLLong{LongURL: "example.com"}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+			assert.NoError(t, err)
+			assert.Equal(t, "example.com", long.LongURL)
+		}()
+	}
+
+	// every caller misses before the load ends
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(cache.requests.WithLabelValues("miss")) == 10
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestGetUrl_Cancel(t *testing.T) {
+	cache, db, _ := newCache(t, 10)
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").DoAndReturn(func(ctx context.Context, short string) (*domain.URLLong, error) {
+		close(loading)
+		<-release
+		// the caller that started the load went away
+		assert.NoError(t, ctx.Err())
+		return &domain.URLLong{LongURL: "example.com"}, nil
+	})
+
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := cache.GetUrl(first, "S0mE__Lin4")
+		errs <- err
+	}()
+	<-loading
+
+	loaded := make(chan *domain.URLLong)
+	go func() {
+		long, err := cache.GetUrl(context.Background(), "S0mE__Lin4")
+		assert.NoError(t, err)
+		loaded <- long
+	}()
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(cache.requests.WithLabelValues("miss")) == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.Equal(t, context.Canceled, <-errs)
+
+	close(release)
+	require.Equal(t, "example.com", (<-loaded).LongURL)
+
+	// the load is cached
+	long, err := cache.GetUrl(context.Background(), "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+}
+
+func TestGetUrls(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+
+	db.EXPECT().GetUrl(gomock.Any(), "F1rst_Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil)
+	db.EXPECT().GetUrls(ctx, []string{"Sec0nd_Lin", "Missing123"}).Return(map[string]domain.URLLong{"Sec0nd_Lin": {LongURL: "example.org"}}, nil)
+
+	_, err := cache.GetUrl(ctx, "F1rst_Lin4")
+	require.NoError(t, err)
+
+	want := map[string]domain.URLLong{"F1rst_Lin4": {LongURL: "example.com"}, "Sec0nd_Lin": {LongURL: "example.org"}}
+	for i := 0; i < 2; i++ {
+		found, err := cache.GetUrls(ctx, []string{"F1rst_Lin4", "Sec0nd_Lin", "Missing123"})
+		require.NoError(t, err)
+		require.Equal(t, want, found)
+	}
+}
+
+func TestGetUrl_LoadTimeout(t *testing.T) {
+	ctx := context.Background()
+	cache, db, _ := newCache(t, 10)
+	cache.LoadTimeout = 10 * time.Millisecond
+
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").DoAndReturn(func(ctx context.Context, short string) (*domain.URLLong, error) {
+		// a hung storage
+		<-ctx.Done()
+		return &domain.URLLong{}, ctx.Err()
+	})
+
+	_, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// the failed load is not cached, the next miss loads again
+	db.EXPECT().GetUrl(gomock.Any(), "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil)
+
+	long, err := cache.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+}
@@ -0,0 +1,143 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+type ClickStorage struct {
+	Mux     *sync.RWMutex
+	Clicks  map[string][]domain.Click // short link -> clicks
+	Journal *Journal                  // persists the clicks, nil keeps them in memory only
+}
+
+func NewClickStorage() *ClickStorage {
+	return &ClickStorage{
+		Mux:    new(sync.RWMutex),
+		Clicks: map[string][]domain.Click{},
+	}
+}
+
+// OpenClickStorage replays the clicks persisted in the directory
+// and persists every later click there
+func OpenClickStorage(dir string, sync bool) (*ClickStorage, error) {
+	s := NewClickStorage()
+
+	journal, err := OpenJournal(dir, "clicks", sync, s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.Journal = journal
+
+	return s, nil
+}
+
+func (s *ClickStorage) AddClick(ctx context.Context, click domain.Click) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	if err := s.log(click); err != nil {
+		return err
+	}
+	s.Clicks[click.Short] = append(s.Clicks[click.Short], click)
+	return nil
+}
+
+func (s *ClickStorage) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	if err := s.log(clicks...); err != nil {
+		return err
+	}
+	for _, click := range clicks {
+		s.Clicks[click.Short] = append(s.Clicks[click.Short], click)
+	}
+	return nil
+}
+
+func (s *ClickStorage) GetStats(ctx context.Context, request domain.StatsRequest) (*domain.Stats, error) {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	clicks := s.Clicks[request.Short]
+	counts := map[int64]int64{}
+	for _, click := range clicks {
+		if click.At < request.From || click.At >= request.To {
+			continue
+		}
+		counts[request.From+(click.At-request.From)/request.Interval*request.Interval]++
+	}
+
+	stats := &domain.Stats{
+		Short: request.Short,
+		Total: int64(len(clicks)),
+	}
+	for start := request.From; start < request.To; start += request.Interval {
+		if count, ok := counts[start]; ok {
+			stats.Buckets = append(stats.Buckets, domain.Bucket{Start: start, Count: count})
+		}
+	}
+
+	return stats, nil
+}
+
+// Compact replaces the logs of the journal with a snapshot of the clicks
+func (s *ClickStorage) Compact() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	// the appends happen under the write lock
+	s.Mux.RLock()
+	generation, err := s.Journal.Rotate()
+	if err != nil || generation == 0 {
+		s.Mux.RUnlock()
+		return err
+	}
+	var entries []interface{}
+	for _, clicks := range s.Clicks {
+		for _, click := range clicks {
+			entries = append(entries, click)
+		}
+	}
+	s.Mux.RUnlock()
+
+	return s.Journal.Snapshot(generation, entries)
+}
+
+func (s *ClickStorage) Close() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	return s.Journal.Close()
+}
+
+// log appends the clicks to the journal, the caller must hold the write lock
+func (s *ClickStorage) log(clicks ...domain.Click) error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	entries := make([]interface{}, len(clicks))
+	for i, click := range clicks {
+		entries[i] = click
+	}
+
+	return s.Journal.Append(entries...)
+}
+
+// apply replays a click of the journal
+func (s *ClickStorage) apply(line json.RawMessage) error {
+	var click domain.Click
+	if err := json.Unmarshal(line, &click); err != nil {
+		return err
+	}
+
+	s.Clicks[click.Short] = append(s.Clicks[click.Short], click)
+	return nil
+}
@@ -0,0 +1,92 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStorage_GetStats(t *testing.T) {
+	ctx := context.Background()
+
+	clickStorage := NewClickStorage()
+
+	for _, at := range []int64{900, 1000, 1050, 1250, 1299, 1300} {
+		err := clickStorage.AddClick(ctx, domain.Click{Short: "S0mE__Lin4", At: at})
+		require.NoError(t, err)
+	}
+	err := clickStorage.AddClick(ctx, domain.Click{Short: "0tHeR_Lin4", At: 1000})
+	require.NoError(t, err)
+
+	stats, err := clickStorage.GetStats(ctx, domain.StatsRequest{Short: "S0mE__Lin4", From: 1000, To: 1300, Interval: 100})
+
+	require.NoError(t, err)
+	require.Equal(t, "S0mE__Lin4", stats.Short)
+	require.Equal(t, int64(6), stats.Total)
+	require.Equal(t, []domain.Bucket{{Start: 1000, Count: 2}, {Start: 1200, Count: 2}}, stats.Buckets)
+}
+
+func TestClickStorage_GetStats_Empty(t *testing.T) {
+	ctx := context.Background()
+
+	clickStorage := NewClickStorage()
+
+	stats, err := clickStorage.GetStats(ctx, domain.StatsRequest{Short: "S0mE__Lin4", From: 1000, To: 1300, Interval: 100})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(0), stats.Total)
+	require.Empty(t, stats.Buckets)
+}
+
+func TestClickStorage_AddClicks(t *testing.T) {
+	ctx := context.Background()
+
+	clickStorage := NewClickStorage()
+
+	err := clickStorage.AddClicks(ctx, []domain.Click{
+		{Short: "S0mE__Lin4", At: 1000},
+		{Short: "0tHeR_Lin4", At: 1000},
+		{Short: "S0mE__Lin4", At: 1100},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, clickStorage.Clicks["S0mE__Lin4"], 2)
+	require.Len(t, clickStorage.Clicks["0tHeR_Lin4"], 1)
+}
+
+func TestOpenClickStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	clickStorage, err := OpenClickStorage(dir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, clickStorage.AddClick(ctx, domain.Click{Short: "S0mE__Lin4", At: 1000, Client: domain.Client{IP: "127.0.0.1"}}))
+	require.NoError(t, clickStorage.Compact())
+	require.NoError(t, clickStorage.AddClicks(ctx, []domain.Click{{Short: "S0mE__Lin4", At: 1001}, {Short: "0tHeR_Lin4", At: 1002}}))
+	require.NoError(t, clickStorage.Close())
+
+	reopened, err := OpenClickStorage(dir, false)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	require.Equal(t, clickStorage.Clicks, reopened.Clicks)
+}
+
+func TestClickStorage_JournalError(t *testing.T) {
+	ctx := context.Background()
+
+	clickStorage, err := OpenClickStorage(t.TempDir(), false)
+	require.NoError(t, err)
+
+	// every click fails to be journaled and is not counted
+	require.NoError(t, clickStorage.Journal.file.Close())
+	defer clickStorage.Journal.lock.Close()
+
+	require.Error(t, clickStorage.AddClick(ctx, domain.Click{Short: "GoodLink12", At: 1010}))
+	require.Error(t, clickStorage.AddClicks(ctx, []domain.Click{{Short: "GoodLink12", At: 1020}}))
+
+	require.Empty(t, clickStorage.Clicks)
+}
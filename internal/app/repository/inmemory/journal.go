@@ -0,0 +1,321 @@
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrLocked means that another process has the journal open
+var ErrLocked = errors.New("journal is used by another process")
+
+// Journal keeps the changes of a storage in a directory as numbered
+// append-only logs of JSON lines and a snapshot of the whole storage.
+// The snapshot numbered N replaces every log before N, so a storage is
+// replayed from its newest snapshot and the logs from N on
+type Journal struct {
+	Dir  string
+	Name string
+	Sync bool // fsync every append, otherwise the logs are synced when they are rotated
+
+	lock       *os.File
+	mux        sync.Mutex // guards the current log
+	file       *os.File
+	generation int64
+	size       int64 // the end of the last whole entry of the current log
+	stale      bool  // replayed logs are not compacted yet
+	broken     error // a failed append could not be cut off the current log
+
+	snapshot sync.Mutex // a single snapshot is written at a time
+}
+
+// OpenJournal replays the snapshot and the logs of the storage through apply
+// and starts a new log. A line cut off by a crash at the end of a log is dropped
+func OpenJournal(dir string, name string, sync bool, apply func(json.RawMessage) error) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	j := &Journal{Dir: dir, Name: name, Sync: sync}
+
+	locked, err := lock(filepath.Join(dir, name+".lock"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	j.lock = locked
+
+	if err := j.load(apply); err != nil {
+		locked.Close()
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (j *Journal) load(apply func(json.RawMessage) error) error {
+	snapshots, err := j.generations(".snapshot")
+	if err != nil {
+		return err
+	}
+	logs, err := j.generations(".log")
+	if err != nil {
+		return err
+	}
+
+	var from int64
+	if len(snapshots) > 0 {
+		from = snapshots[len(snapshots)-1]
+		if err := j.replay(j.path(from, ".snapshot"), apply); err != nil {
+			return err
+		}
+		j.generation = from
+	}
+	for _, generation := range logs {
+		if generation < from {
+			continue
+		}
+		if err := j.replay(j.path(generation, ".log"), apply); err != nil {
+			return err
+		}
+		j.generation = generation
+		j.stale = true
+	}
+
+	// leftovers of a compaction cut off by a crash
+	if err := j.remove(from); err != nil {
+		return err
+	}
+
+	// a new log, so a cut off line never ends up in the middle of one
+	return j.open(j.generation + 1)
+}
+
+// Append writes the entries to the current log at once. A failed append
+// is cut off the log, so the entries are either all written or not at all
+func (j *Journal) Append(entries ...interface{}) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.broken != nil {
+		return j.broken
+	}
+
+	written, err := j.file.Write(buffer.Bytes())
+	if err == nil && j.Sync {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		// a cut off entry in the middle of the log would stop the replay
+		if truncateErr := j.file.Truncate(j.size); truncateErr != nil {
+			j.broken = fmt.Errorf("journal %s is broken: %w", j.Name, truncateErr)
+		}
+		return err
+	}
+	j.size += int64(written)
+
+	return nil
+}
+
+// Rotate starts a new log and returns its number, the snapshot of the storage
+// taken together with the rotation gets this number. Zero means that nothing
+// was written since the last snapshot. The caller must stop the appends
+func (j *Journal) Rotate() (int64, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.size == 0 && !j.stale {
+		return 0, nil
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return 0, err
+	}
+	if err := j.file.Close(); err != nil {
+		return 0, err
+	}
+	if err := j.open(j.generation + 1); err != nil {
+		return 0, err
+	}
+	j.stale = false
+
+	return j.generation, nil
+}
+
+// Snapshot writes the entries as the snapshot numbered generation and removes
+// the snapshots and the logs it replaces
+func (j *Journal) Snapshot(generation int64, entries []interface{}) error {
+	j.snapshot.Lock()
+	defer j.snapshot.Unlock()
+
+	path := j.path(generation, ".snapshot")
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	// the snapshot appears whole or not at all
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+	if err := j.syncDir(); err != nil {
+		return err
+	}
+
+	return j.remove(generation)
+}
+
+func (j *Journal) Close() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	defer j.lock.Close()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+func (j *Journal) open(generation int64) error {
+	file, err := os.OpenFile(j.path(generation, ".log"), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	j.file = file
+	j.generation = generation
+	j.size = 0
+	j.broken = nil
+	return j.syncDir()
+}
+
+func (j *Journal) replay(path string, apply func(json.RawMessage) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// every entry ends with a new line, anything after the last one was cut off
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := apply(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+	}
+}
+
+// remove deletes the snapshots and the logs before generation
+// and the temporary files of the snapshots
+func (j *Journal) remove(generation int64) error {
+	temporary, err := filepath.Glob(filepath.Join(j.Dir, j.Name+"-*.snapshot.tmp"))
+	if err != nil {
+		return err
+	}
+	for _, path := range temporary {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	for _, suffix := range []string{".snapshot", ".log"} {
+		generations, err := j.generations(suffix)
+		if err != nil {
+			return err
+		}
+		for _, old := range generations {
+			if old >= generation {
+				break
+			}
+			if err := os.Remove(j.path(old, suffix)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// generations returns the numbers of the files with the suffix in ascending order
+func (j *Journal) generations(suffix string) ([]int64, error) {
+	paths, err := filepath.Glob(filepath.Join(j.Dir, j.Name+"-*"+suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	generations := make([]int64, 0, len(paths))
+	for _, path := range paths {
+		number := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), j.Name+"-"), suffix)
+		generation, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			continue
+		}
+		generations = append(generations, generation)
+	}
+	sort.Slice(generations, func(a, b int) bool { return generations[a] < generations[b] })
+
+	return generations, nil
+}
+
+func (j *Journal) path(generation int64, suffix string) string {
+	return filepath.Join(j.Dir, fmt.Sprintf("%s-%08d%s", j.Name, generation, suffix))
+}
+
+// syncDir makes the created and renamed files of the directory durable
+func (j *Journal) syncDir() error {
+	dir, err := os.Open(j.Dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package inmemory
+
+import "os"
+
+// lock only opens the lock file, the journal is not guarded on this platform
+func lock(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+}
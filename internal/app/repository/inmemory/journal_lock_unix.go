@@ -0,0 +1,24 @@
+//go:build unix
+
+package inmemory
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock keeps other processes away from the files of the journal until the
+// returned file is closed, the lock is released with the process as well
+func lock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return nil, ErrLocked
+	}
+
+	return file, nil
+}
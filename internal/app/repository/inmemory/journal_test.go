@@ -0,0 +1,146 @@
+package inmemory
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	Value int `json:"value"`
+}
+
+// collect returns an apply that gathers the replayed values
+func collect(values *[]int) func(json.RawMessage) error {
+	return func(line json.RawMessage) error {
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		*values = append(*values, e.Value)
+		return nil
+	}
+}
+
+func files(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestJournal_Replay(t *testing.T) {
+	dir := t.TempDir()
+
+	var values []int
+	journal, err := OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+	require.Empty(t, values)
+
+	require.NoError(t, journal.Append(entry{1}, entry{2}))
+	require.NoError(t, journal.Append(entry{3}))
+	require.NoError(t, journal.Close())
+
+	// a crash in the middle of an append
+	file, err := os.OpenFile(filepath.Join(dir, "test-00000001.log"), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"val`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	journal, err = OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, values)
+
+	require.NoError(t, journal.Append(entry{4}))
+	require.NoError(t, journal.Close())
+
+	values = nil
+	_, err = OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4}, values)
+	require.Equal(t, []string{"test-00000001.log", "test-00000002.log", "test-00000003.log", "test.lock"}, files(t, dir))
+}
+
+func TestJournal_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	var values []int
+	journal, err := OpenJournal(dir, "test", true, collect(&values))
+	require.NoError(t, err)
+
+	generation, err := journal.Rotate()
+	require.NoError(t, err)
+	require.Zero(t, generation, "nothing to compact")
+
+	require.NoError(t, journal.Append(entry{1}, entry{2}))
+
+	generation, err = journal.Rotate()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), generation)
+
+	// written after the rotation, so the snapshot does not have it
+	require.NoError(t, journal.Append(entry{3}))
+
+	require.NoError(t, journal.Snapshot(generation, []interface{}{entry{12}}))
+	require.Equal(t, []string{"test-00000002.log", "test-00000002.snapshot", "test.lock"}, files(t, dir))
+	require.NoError(t, journal.Close())
+
+	_, err = OpenJournal(dir, "test", true, collect(&values))
+	require.NoError(t, err)
+	require.Equal(t, []int{12, 3}, values)
+}
+
+func TestJournal_Corrupted(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test-00000001.log"), []byte("{\"value\":1}\nnot json\n"), 0o644))
+
+	var values []int
+	_, err := OpenJournal(dir, "test", false, collect(&values))
+	require.Error(t, err)
+}
+
+func TestJournal_Locked(t *testing.T) {
+	dir := t.TempDir()
+
+	var values []int
+	journal, err := OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+
+	_, err = OpenJournal(dir, "test", false, collect(&values))
+	require.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+}
+
+func TestJournal_AppendError(t *testing.T) {
+	dir := t.TempDir()
+
+	var values []int
+	journal, err := OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+	require.NoError(t, journal.Append(entry{1}))
+
+	// the log can neither be written nor cut off
+	require.NoError(t, journal.file.Close())
+	require.Error(t, journal.Append(entry{2}))
+	require.ErrorContains(t, journal.Append(entry{3}), "journal test is broken")
+	require.Equal(t, int64(len("{\"value\":1}\n")), journal.size)
+	journal.lock.Close()
+
+	_, err = OpenJournal(dir, "test", false, collect(&values))
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, values)
+}
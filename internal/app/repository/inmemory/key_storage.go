@@ -0,0 +1,150 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+type KeyStorage struct {
+	Mux     *sync.RWMutex
+	Keys    map[string]domain.APIKey
+	Journal *Journal // persists the keys, nil keeps them in memory only
+}
+
+// keyRecord is the whole state of a key in the journal, with its hash
+type keyRecord struct {
+	domain.APIKey
+	Hash string `json:"hash"`
+}
+
+func NewKeyStorage() *KeyStorage {
+	return &KeyStorage{
+		Mux:  new(sync.RWMutex),
+		Keys: map[string]domain.APIKey{},
+	}
+}
+
+// OpenKeyStorage replays the keys persisted in the directory
+// and persists every later change there
+func OpenKeyStorage(dir string, sync bool) (*KeyStorage, error) {
+	s := NewKeyStorage()
+
+	journal, err := OpenJournal(dir, "keys", sync, s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.Journal = journal
+
+	return s, nil
+}
+
+func (s *KeyStorage) AddKey(ctx context.Context, key domain.APIKey) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	if err := s.log(key); err != nil {
+		return err
+	}
+	s.Keys[key.ID] = key
+	return nil
+}
+
+func (s *KeyStorage) GetKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	key, ok := s.Keys[id]
+	if !ok {
+		return &domain.APIKey{}, domain.ErrorKeyNotFound
+	}
+
+	return &key, nil
+}
+
+func (s *KeyStorage) RevokeKey(ctx context.Context, id string, revokedAt int64) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	key, ok := s.Keys[id]
+	if !ok || key.Revoked() {
+		return domain.ErrorKeyNotFound
+	}
+
+	key.RevokedAt = revokedAt
+	if err := s.log(key); err != nil {
+		return err
+	}
+	s.Keys[id] = key
+	return nil
+}
+
+func (s *KeyStorage) RotateKey(ctx context.Context, id string, hash string) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	key, ok := s.Keys[id]
+	if !ok || key.Revoked() {
+		return domain.ErrorKeyNotFound
+	}
+
+	key.Hash = hash
+	if err := s.log(key); err != nil {
+		return err
+	}
+	s.Keys[id] = key
+	return nil
+}
+
+// Compact replaces the logs of the journal with a snapshot of the keys
+func (s *KeyStorage) Compact() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	// the appends happen under the write lock
+	s.Mux.RLock()
+	generation, err := s.Journal.Rotate()
+	if err != nil || generation == 0 {
+		s.Mux.RUnlock()
+		return err
+	}
+	entries := make([]interface{}, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		entries = append(entries, keyRecord{APIKey: key, Hash: key.Hash})
+	}
+	s.Mux.RUnlock()
+
+	return s.Journal.Snapshot(generation, entries)
+}
+
+func (s *KeyStorage) Close() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	return s.Journal.Close()
+}
+
+// log appends the new state of the key to the journal, the caller must hold the write lock
+func (s *KeyStorage) log(key domain.APIKey) error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	return s.Journal.Append(keyRecord{APIKey: key, Hash: key.Hash})
+}
+
+// apply replays a change of the journal
+func (s *KeyStorage) apply(line json.RawMessage) error {
+	var record keyRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return err
+	}
+
+	record.APIKey.Hash = record.Hash
+	s.Keys[record.ID] = record.APIKey
+	return nil
+}
@@ -0,0 +1,75 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStorage(t *testing.T) {
+	ctx := context.Background()
+
+	keyStorage := NewKeyStorage()
+
+	require.NoError(t, keyStorage.AddKey(ctx, domain.APIKey{ID: "0123456789abcdef", Hash: "old", CreatedAt: 1686557090}))
+
+	require.NoError(t, keyStorage.RotateKey(ctx, "0123456789abcdef", "new"))
+
+	key, err := keyStorage.GetKey(ctx, "0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, "new", key.Hash)
+
+	require.NoError(t, keyStorage.RevokeKey(ctx, "0123456789abcdef", 1686557091))
+
+	key, err = keyStorage.GetKey(ctx, "0123456789abcdef")
+	require.NoError(t, err)
+	require.True(t, key.Revoked())
+
+	require.Equal(t, domain.ErrorKeyNotFound, keyStorage.RevokeKey(ctx, "0123456789abcdef", 1686557092))
+	require.Equal(t, domain.ErrorKeyNotFound, keyStorage.RotateKey(ctx, "0123456789abcdef", "newer"))
+
+	_, err = keyStorage.GetKey(ctx, "fedcba9876543210")
+	require.Equal(t, domain.ErrorKeyNotFound, err)
+}
+
+func TestOpenKeyStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	keyStorage, err := OpenKeyStorage(dir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, keyStorage.AddKey(ctx, domain.APIKey{ID: "0123456789abcdef", Hash: "old", CreatedAt: 1686557090}))
+	require.NoError(t, keyStorage.Compact())
+	require.NoError(t, keyStorage.RotateKey(ctx, "0123456789abcdef", "new"))
+	require.NoError(t, keyStorage.Close())
+
+	reopened, err := OpenKeyStorage(dir, false)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	key, err := reopened.GetKey(ctx, "0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, &domain.APIKey{ID: "0123456789abcdef", Hash: "new", CreatedAt: 1686557090}, key)
+}
+
+func TestKeyStorage_JournalError(t *testing.T) {
+	ctx := context.Background()
+
+	keyStorage, err := OpenKeyStorage(t.TempDir(), false)
+	require.NoError(t, err)
+	stored := domain.APIKey{ID: "0123456789abcdef", Hash: "old", CreatedAt: 1686557090}
+	require.NoError(t, keyStorage.AddKey(ctx, stored))
+
+	// every later change fails to be journaled and is not made
+	require.NoError(t, keyStorage.Journal.file.Close())
+	defer keyStorage.Journal.lock.Close()
+
+	require.Error(t, keyStorage.AddKey(ctx, domain.APIKey{ID: "fedcba9876543210", Hash: "hash"}))
+	require.Error(t, keyStorage.RotateKey(ctx, "0123456789abcdef", "new"))
+	require.Error(t, keyStorage.RevokeKey(ctx, "0123456789abcdef", 1686557091))
+
+	require.Equal(t, map[string]domain.APIKey{"0123456789abcdef": stored}, keyStorage.Keys)
+}
@@ -0,0 +1,585 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+// ShardedUrlStorage spreads the links over independently locked shards by the
+// hash of the short link, and the reverse index over its own shards by the hash
+// of the owner and the long link, so a write stalls only the readers of its shard.
+// A batch is not atomic, other requests see its links as they are stored and
+// a link that fails to be stored fails alone.
+//
+// A reverse entry is only a hint, it counts while the link it points to is
+// flagged as the reused link of that long link. Locks are taken in a fixed order,
+// reverse shards before link shards and shards of the same kind by their index.
+// A request holds at most one reverse shard and one link shard at a time, only
+// Compact read locks every shard at once
+type ShardedUrlStorage struct {
+	Shards  []*urlShard
+	Reverse []*reverseShard
+	Journal *Journal // persists the links, nil keeps them in memory only
+}
+
+type urlShard struct {
+	mux   sync.RWMutex
+	links map[string]*shardedLink
+}
+
+type reverseShard struct {
+	mux    sync.RWMutex
+	shorts map[string]string // owner and long link -> generated short link, see reverseKey
+}
+
+type shardedLink struct {
+	domain.URLLong
+	Alias   bool
+	Reverse bool // the link is reused for its long link
+	History []domain.URLVersion
+}
+
+// indexes reports whether the reverse entry of the owner and the long link may point to the link
+func (l *shardedLink) indexes(owner string, long string) bool {
+	return l.Reverse && l.Owner == owner && l.LongURL == long
+}
+
+// NewShardedUrlStorage returns a storage of n shards, n below one gives a single shard
+func NewShardedUrlStorage(n int) *ShardedUrlStorage {
+	if n < 1 {
+		n = 1
+	}
+
+	s := &ShardedUrlStorage{
+		Shards:  make([]*urlShard, n),
+		Reverse: make([]*reverseShard, n),
+	}
+	for i := 0; i < n; i++ {
+		s.Shards[i] = &urlShard{links: map[string]*shardedLink{}}
+		s.Reverse[i] = &reverseShard{shorts: map[string]string{}}
+	}
+
+	return s
+}
+
+// OpenShardedUrlStorage replays the links persisted in the directory and persists
+// every later change there, the files are the same as the ones of UrlStorage
+func OpenShardedUrlStorage(dir string, sync bool, n int) (*ShardedUrlStorage, error) {
+	s := NewShardedUrlStorage(n)
+
+	journal, err := OpenJournal(dir, "links", sync, s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.Journal = journal
+
+	return s, nil
+}
+
+// conflict is what add does with a link that holds the short link or the long link
+type conflict int
+
+const (
+	keepActive conflict = iota // only expired links give their short links away
+	keepAll                    // every stored short link is kept
+	replace                    // the new link replaces the stored ones
+)
+
+func (s *ShardedUrlStorage) AddUrl(ctx context.Context, urlData domain.URLData) error {
+	return s.add(urlData, time.Now().Unix(), keepActive)
+}
+
+func (s *ShardedUrlStorage) AddUrls(ctx context.Context, batch []domain.URLData) ([]error, error) {
+	now := time.Now().Unix()
+	errs := make([]error, len(batch))
+	for i, urlData := range batch {
+		// the links before are stored already, so even a failed journal fails only its link
+		errs[i] = s.add(urlData, now, keepActive)
+	}
+
+	return errs, nil
+}
+
+// add stores the link and drops the reverse entries it made stale
+func (s *ShardedUrlStorage) add(urlData domain.URLData, now int64, mode conflict) error {
+	stale, err := s.addLocked(urlData, now, mode)
+	s.unindex(stale...)
+	return err
+}
+
+// addLocked stores the link, a generated link holds the lock of its reverse entry
+// the whole time, so concurrent links of the same long link are stored one after another
+func (s *ShardedUrlStorage) addLocked(urlData domain.URLData, now int64, mode conflict) ([]domain.URLData, error) {
+	if urlData.Alias {
+		return s.put(urlData, false, now, mode)
+	}
+
+	key := reverseKey(urlData.Owner, urlData.LongURL)
+	reverse := s.reverse(key)
+	reverse.mux.Lock()
+	defer reverse.mux.Unlock()
+
+	previous, ok := reverse.shorts[key]
+	if ok && previous != urlData.URLShort {
+		held, active := s.holds(previous, urlData.Owner, urlData.LongURL, now)
+		if mode == keepActive && active {
+			return nil, domain.ErrorLongExists
+		}
+		// an imported link leaves the long link to the link that holds it, as the other storages do
+		if mode != keepActive && held {
+			return s.put(urlData, false, now, mode)
+		}
+	}
+
+	stale, err := s.put(urlData, true, now, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && previous != urlData.URLShort {
+		s.takeOver(previous, urlData.Owner, urlData.LongURL)
+	}
+	reverse.shorts[key] = urlData.URLShort
+
+	return stale, nil
+}
+
+// holds reports whether the link is the reused link of the long link and whether it is active
+func (s *ShardedUrlStorage) holds(short string, owner string, long string, now int64) (bool, bool) {
+	shard := s.shard(short)
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	link, ok := shard.links[short]
+	held := ok && link.indexes(owner, long)
+	return held, held && link.Active(now)
+}
+
+// takeOver clears the reverse flag of the link that held the long link,
+// a replay gives it to the later link anyway so the change is not logged
+func (s *ShardedUrlStorage) takeOver(short string, owner string, long string) {
+	shard := s.shard(short)
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	if link, ok := shard.links[short]; ok && link.indexes(owner, long) {
+		link.Reverse = false
+	}
+}
+
+// put journals the link and then stores it in its shard, reverse flags it as the
+// reused link of its long link. It returns the replaced link if it had a reverse entry
+func (s *ShardedUrlStorage) put(urlData domain.URLData, reverse bool, now int64, mode conflict) ([]domain.URLData, error) {
+	shard := s.shard(urlData.URLShort)
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	var stale []domain.URLData
+	if old, ok := shard.links[urlData.URLShort]; ok {
+		// an expired link that is not reaped yet gives its short link away
+		if mode == keepAll || mode == keepActive && !old.Expired(now) {
+			return nil, domain.ErrorShortExists
+		}
+		if old.Reverse {
+			stale = append(stale, domain.URLData{URLShort: urlData.URLShort, URLLong: old.URLLong})
+		}
+	}
+
+	link := &shardedLink{URLLong: urlData.URLLong, Alias: urlData.Alias, Reverse: reverse}
+	if err := s.log(record(urlData.URLShort, link)); err != nil {
+		return nil, err
+	}
+	shard.links[urlData.URLShort] = link
+
+	return stale, nil
+}
+
+func (s *ShardedUrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
+	shard := s.shard(shortUrl)
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	link, ok := shard.links[shortUrl]
+	if !ok {
+		return &domain.URLLong{}, domain.ErrorLinkNotFound
+	}
+
+	longUrl := link.URLLong
+	return &longUrl, nil
+}
+
+func (s *ShardedUrlStorage) GetUrls(ctx context.Context, shorts []string) (map[string]domain.URLLong, error) {
+	found := make(map[string]domain.URLLong, len(shorts))
+	for _, short := range shorts {
+		shard := s.shard(short)
+		shard.mux.RLock()
+		if link, ok := shard.links[short]; ok {
+			found[short] = link.URLLong
+		}
+		shard.mux.RUnlock()
+	}
+
+	return found, nil
+}
+
+func (s *ShardedUrlStorage) GetShort(ctx context.Context, longUrl string, owner string) (string, error) {
+	key := reverseKey(owner, longUrl)
+	reverse := s.reverse(key)
+	reverse.mux.RLock()
+	shortUrl, ok := reverse.shorts[key]
+	reverse.mux.RUnlock()
+	if !ok {
+		return "", domain.ErrorLinkNotFound
+	}
+
+	shard := s.shard(shortUrl)
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	link, ok := shard.links[shortUrl]
+	if !ok || !link.indexes(owner, longUrl) || !link.Active(time.Now().Unix()) {
+		return "", domain.ErrorLinkNotFound
+	}
+
+	return shortUrl, nil
+}
+
+func (s *ShardedUrlStorage) DeleteExpired(ctx context.Context, now int64) (int64, error) {
+	return s.deleteWhere(func(link *shardedLink) bool {
+		return link.Expired(now)
+	})
+}
+
+func (s *ShardedUrlStorage) DeleteUrl(ctx context.Context, shortUrl string, deletedAt int64) error {
+	return s.update(shortUrl, func(link *shardedLink) bool {
+		link.DeletedAt = deletedAt
+		return true
+	})
+}
+
+func (s *ShardedUrlStorage) RestoreUrl(ctx context.Context, shortUrl string) error {
+	return s.update(shortUrl, func(link *shardedLink) bool {
+		if link.DeletedAt == 0 {
+			return false
+		}
+		link.DeletedAt = 0
+		return true
+	})
+}
+
+func (s *ShardedUrlStorage) DisableUrl(ctx context.Context, shortUrl string, disabled bool) error {
+	return s.update(shortUrl, func(link *shardedLink) bool {
+		if link.DeletedAt != 0 {
+			return false
+		}
+		link.Disabled = disabled
+		return true
+	})
+}
+
+func (s *ShardedUrlStorage) UpdateUrl(ctx context.Context, shortUrl string, update domain.URLUpdate) error {
+	var old domain.URLData
+	err := s.update(shortUrl, func(link *shardedLink) bool {
+		// an expired link is not retargeted, its short link is up for the taking
+		if link.DeletedAt != 0 || link.Expired(update.ChangedAt) {
+			return false
+		}
+
+		// a copy, so a failed change leaves the stored history as it is
+		history := link.History[:len(link.History):len(link.History)]
+		link.History = append(history, domain.URLVersion{
+			Version:   int64(len(link.History) + 1),
+			LongURL:   link.LongURL,
+			ChangedAt: update.ChangedAt,
+			Actor:     update.Actor,
+		})
+
+		// a retargeted link is not reused for its old long link any more
+		if link.Reverse {
+			old = domain.URLData{URLShort: shortUrl, URLLong: link.URLLong}
+			link.Reverse = false
+		}
+		link.LongURL = update.LongURL
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if old.URLShort != "" {
+		s.unindex(old)
+	}
+	return nil
+}
+
+func (s *ShardedUrlStorage) GetHistory(ctx context.Context, shortUrl string) ([]domain.URLVersion, error) {
+	shard := s.shard(shortUrl)
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	link, ok := shard.links[shortUrl]
+	if !ok {
+		return nil, domain.ErrorLinkNotFound
+	}
+
+	return append([]domain.URLVersion{}, link.History...), nil
+}
+
+func (s *ShardedUrlStorage) PurgeDeleted(ctx context.Context, before int64) (int64, error) {
+	return s.deleteWhere(func(link *shardedLink) bool {
+		return link.DeletedAt != 0 && link.DeletedAt <= before
+	})
+}
+
+func (s *ShardedUrlStorage) ExportUrls(ctx context.Context, fn func(domain.URLData) error) error {
+	for _, shard := range s.Shards {
+		// a copy of the shard, so a slow writer does not hold the lock
+		shard.mux.RLock()
+		batch := make([]domain.URLData, 0, len(shard.links))
+		for short, link := range shard.links {
+			batch = append(batch, domain.URLData{URLShort: short, URLLong: link.URLLong, Alias: link.Alias})
+		}
+		shard.mux.RUnlock()
+
+		for _, urlData := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(urlData); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *ShardedUrlStorage) ImportUrls(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+	mode := keepAll
+	if overwrite {
+		mode = replace
+	}
+
+	errs := make([]error, len(batch))
+	for i, urlData := range batch {
+		errs[i] = s.add(urlData, 0, mode)
+	}
+
+	return errs, nil
+}
+
+// Compact replaces the logs of the journal with a snapshot of the links
+func (s *ShardedUrlStorage) Compact() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	// the appends happen under the write lock of a shard, and a generated
+	// link moves the reverse flag under the lock of its reverse entry, so the
+	// snapshot holds every shard in the lock order of the storage
+	for _, reverse := range s.Reverse {
+		reverse.mux.RLock()
+	}
+	for _, shard := range s.Shards {
+		shard.mux.RLock()
+	}
+	generation, err := s.Journal.Rotate()
+	var entries []interface{}
+	if err == nil && generation != 0 {
+		for _, shard := range s.Shards {
+			for short, link := range shard.links {
+				entries = append(entries, record(short, link))
+			}
+		}
+	}
+	for _, shard := range s.Shards {
+		shard.mux.RUnlock()
+	}
+	for _, reverse := range s.Reverse {
+		reverse.mux.RUnlock()
+	}
+	if err != nil || generation == 0 {
+		return err
+	}
+
+	return s.Journal.Snapshot(generation, entries)
+}
+
+func (s *ShardedUrlStorage) Close() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	return s.Journal.Close()
+}
+
+func (s *ShardedUrlStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// update changes a copy of the link in its shard, journals it and then stores it,
+// fn returns false for a link it can not change
+func (s *ShardedUrlStorage) update(shortUrl string, fn func(*shardedLink) bool) error {
+	shard := s.shard(shortUrl)
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	link, ok := shard.links[shortUrl]
+	if !ok {
+		return domain.ErrorLinkNotFound
+	}
+	changed := *link
+	if !fn(&changed) {
+		return domain.ErrorLinkNotFound
+	}
+	if err := s.log(record(shortUrl, &changed)); err != nil {
+		return err
+	}
+
+	*link = changed
+	return nil
+}
+
+// deleteWhere removes the links fn matches one shard at a time
+func (s *ShardedUrlStorage) deleteWhere(fn func(*shardedLink) bool) (int64, error) {
+	var deleted int64
+	for _, shard := range s.Shards {
+		var entries []linkEntry
+		var stale []domain.URLData
+
+		shard.mux.Lock()
+		for short, link := range shard.links {
+			if fn(link) {
+				entries = append(entries, linkEntry{Delete: short})
+			}
+		}
+		err := s.log(entries...)
+		if err == nil {
+			for _, entry := range entries {
+				if link := shard.links[entry.Delete]; link.Reverse {
+					stale = append(stale, domain.URLData{URLShort: entry.Delete, URLLong: link.URLLong})
+				}
+				delete(shard.links, entry.Delete)
+			}
+		}
+		shard.mux.Unlock()
+
+		if err != nil {
+			return deleted, err
+		}
+		deleted += int64(len(entries))
+		s.unindex(stale...)
+	}
+
+	return deleted, nil
+}
+
+// unindex drops the reverse entries of the links unless they point to a link again
+func (s *ShardedUrlStorage) unindex(links ...domain.URLData) {
+	for _, urlData := range links {
+		key := reverseKey(urlData.Owner, urlData.LongURL)
+		reverse := s.reverse(key)
+		reverse.mux.Lock()
+		if reverse.shorts[key] == urlData.URLShort && !s.indexed(urlData) {
+			delete(reverse.shorts, key)
+		}
+		reverse.mux.Unlock()
+	}
+}
+
+func (s *ShardedUrlStorage) indexed(urlData domain.URLData) bool {
+	shard := s.shard(urlData.URLShort)
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	link, ok := shard.links[urlData.URLShort]
+	return ok && link.indexes(urlData.Owner, urlData.LongURL)
+}
+
+// log appends the changes to the journal before they are made, so a failed append
+// leaves the shard as it is. The caller must hold the write lock of their shard
+func (s *ShardedUrlStorage) log(entries ...linkEntry) error {
+	if s.Journal == nil || len(entries) == 0 {
+		return nil
+	}
+
+	changes := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		changes[i] = entry
+	}
+
+	return s.Journal.Append(changes...)
+}
+
+// apply replays a change of the journal, nothing else runs yet so no lock is taken
+func (s *ShardedUrlStorage) apply(line json.RawMessage) error {
+	var entry linkEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return err
+	}
+
+	short := entry.Delete
+	if entry.Put != nil {
+		short = entry.Put.URLShort
+	}
+	shard := s.shard(short)
+	if old, ok := shard.links[short]; ok {
+		delete(shard.links, short)
+		if old.Reverse {
+			key := reverseKey(old.Owner, old.LongURL)
+			if reverse := s.reverse(key); reverse.shorts[key] == short {
+				delete(reverse.shorts, key)
+			}
+		}
+	}
+	if entry.Put == nil {
+		return nil
+	}
+
+	record := entry.Put
+	shard.links[short] = &shardedLink{URLLong: record.URLLong, Alias: record.Alias, Reverse: record.Reverse, History: record.History}
+	if record.Reverse {
+		key := reverseKey(record.Owner, record.LongURL)
+		reverse := s.reverse(key)
+		// the later link takes the long link over
+		if previous, ok := reverse.shorts[key]; ok && previous != short {
+			if link, ok := s.shard(previous).links[previous]; ok {
+				link.Reverse = false
+			}
+		}
+		reverse.shorts[key] = short
+	}
+
+	return nil
+}
+
+func (s *ShardedUrlStorage) shard(short string) *urlShard {
+	return s.Shards[hash(short)%uint32(len(s.Shards))]
+}
+
+func (s *ShardedUrlStorage) reverse(key string) *reverseShard {
+	return s.Reverse[hash(key)%uint32(len(s.Reverse))]
+}
+
+// record returns the whole state of the link, the caller must hold the lock of its shard
+func record(short string, link *shardedLink) linkEntry {
+	return linkEntry{Put: &linkRecord{
+		URLData: domain.URLData{URLShort: short, URLLong: link.URLLong, Alias: link.Alias},
+		Reverse: link.Reverse,
+		History: link.History,
+	}}
+}
+
+// hash is 32 bit FNV-1a, inlined so hashing a key does not allocate
+func hash(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,342 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedAddUrl_LongExists(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+
+	first := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	second := domain.NewURLData("An0ther_L1", "example.com", 1686557091)
+	alias := domain.NewURLData("summer_sale", "example.com", 1686557091)
+	alias.Alias = true
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *first))
+	require.Equal(t, domain.ErrorLongExists, urlStorage.AddUrl(ctx, *second))
+	require.NoError(t, urlStorage.AddUrl(ctx, *alias))
+	require.Equal(t, domain.ErrorShortExists, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.org", 1686557091)))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, first.URLShort, short)
+
+	long, err := urlStorage.GetUrl(ctx, alias.URLShort)
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+
+	_, err = urlStorage.GetShort(ctx, "example.com", "0123456789abcdef")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestShardedAddUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin0", "example.net", 1686557090)))
+
+	batch := []domain.URLData{
+		*domain.NewURLData("S0mE__Lin1", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin2", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin1", "example.org", 1686557090),
+		*domain.NewURLData("S0mE__Lin3", "example.net", 1686557090),
+	}
+
+	errs, err := urlStorage.AddUrls(ctx, batch)
+
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, domain.ErrorLongExists, domain.ErrorShortExists, domain.ErrorLongExists}, errs)
+
+	found, err := urlStorage.GetUrls(ctx, []string{"S0mE__Lin0", "S0mE__Lin1", "S0mE__Lin2"})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, "example.com", found["S0mE__Lin1"].LongURL)
+}
+
+func TestShardedAddUrl_ReplacesExpired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+
+	expired := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	fresh := domain.NewURLData("An0ther_L1", "example.com", 1686557092)
+	reused := domain.NewURLData("S0mE__Lin4", "example.org", 1686557092)
+	reused.Alias = true
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+	require.NoError(t, urlStorage.AddUrl(ctx, *fresh))
+	require.NoError(t, urlStorage.AddUrl(ctx, *reused))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, fresh.URLShort, short)
+
+	long, err := urlStorage.GetUrl(ctx, reused.URLShort)
+	require.NoError(t, err)
+	require.Equal(t, reused.LongURL, long.LongURL)
+
+	// the expired link lost its reverse entry to the fresh one
+	deleted, err := urlStorage.DeleteExpired(ctx, 1686557100)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), deleted)
+	require.Equal(t, []string{fresh.URLShort}, reverseEntries(urlStorage))
+}
+
+func TestShardedAddUrl_Concurrent(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+
+	var added int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			urldata := domain.NewURLData(fmt.Sprintf("Link%06d", i), "example.com", 1686557090)
+			if err := urlStorage.AddUrl(ctx, *urldata); err == nil {
+				atomic.AddInt32(&added, 1)
+			}
+			_, _ = urlStorage.GetShort(ctx, "example.com", "")
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), added)
+	require.Len(t, reverseEntries(urlStorage), 1)
+}
+
+func TestShardedDeleteUrl_Restore(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "S0mE__Lin4", 1686557091))
+
+	// a deleted link is not reused for its long link and keeps its short link
+	_, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, domain.ErrorShortExists, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.org", 1686557090)))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DisableUrl(ctx, "S0mE__Lin4", true))
+
+	require.NoError(t, urlStorage.RestoreUrl(ctx, "S0mE__Lin4"))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.RestoreUrl(ctx, "S0mE__Lin4"))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "S0mE__Lin4", short)
+
+	require.NoError(t, urlStorage.DisableUrl(ctx, "S0mE__Lin4", true))
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.True(t, long.Disabled)
+
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DeleteUrl(ctx, "N0t_F0uNd1", 1686557091))
+}
+
+func TestShardedUpdateUrl_History(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.org", ChangedAt: 1686557091, Actor: "127.0.0.1"}))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.com", ChangedAt: 1686557092}))
+
+	history, err := urlStorage.GetHistory(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLVersion{
+		{Version: 1, LongURL: "example.com", ChangedAt: 1686557091, Actor: "127.0.0.1"},
+		{Version: 2, LongURL: "example.org", ChangedAt: 1686557092},
+	}, history)
+
+	// a retargeted link is not reused even when it points to its old long link again
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Empty(t, reverseEntries(urlStorage))
+
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "S0mE__Lin4", 1686557093))
+	purged, err := urlStorage.PurgeDeleted(ctx, 1686557093)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+	_, err = urlStorage.GetHistory(ctx, "S0mE__Lin4")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestShardedExportImportUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+
+	stored := []domain.URLData{
+		{URLShort: "F1rst_Lin4", URLLong: domain.URLLong{LongURL: "example.org", AddedAt: 1686557091}},
+		{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.net", AddedAt: 1686557092, Redirect: 301}, Alias: true},
+	}
+
+	errs, err := urlStorage.ImportUrls(ctx, stored, false)
+	require.NoError(t, err)
+	require.Equal(t, []error{domain.ErrorShortExists, nil}, errs)
+
+	errs, err = urlStorage.ImportUrls(ctx, stored[:1], true)
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, errs)
+
+	// the overwritten long link is free again
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, []string{"F1rst_Lin4"}, reverseEntries(urlStorage))
+
+	exported := make(map[string]domain.URLData)
+	err = urlStorage.ExportUrls(ctx, func(urlData domain.URLData) error {
+		exported[urlData.URLShort] = urlData
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]domain.URLData{"F1rst_Lin4": stored[0], "summer_sale": stored[1]}, exported)
+}
+
+func TestShardedImportUrls_KeepsReverse(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+
+	errs, err := urlStorage.ImportUrls(ctx, []domain.URLData{
+		*domain.NewURLData("Sec0nd_Lin", "example.com", 1686557090),
+		*domain.NewURLData("Th1rd_Lin4", "example.org", 1686557090),
+		*domain.NewURLData("F0urth_Li4", "example.org", 1686557090),
+	}, false)
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, nil, nil}, errs)
+
+	// the stored link and the first one of the batch are reused for their long links
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "F1rst_Lin4", short)
+	short, err = urlStorage.GetShort(ctx, "example.org", "")
+	require.NoError(t, err)
+	require.Equal(t, "Th1rd_Lin4", short)
+	require.ElementsMatch(t, []string{"F1rst_Lin4", "Th1rd_Lin4"}, reverseEntries(urlStorage))
+}
+
+func TestOpenShardedUrlStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	urlStorage, err := OpenShardedUrlStorage(dir, false, 4)
+	require.NoError(t, err)
+
+	expired := domain.NewURLData("Expired123", "example.com", 1)
+	expired.ExpiresAt = 2
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.AddUrl(ctx, domain.URLData{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.org", AddedAt: 1686557090}, Alias: true}))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "summer_sale", domain.URLUpdate{LongURL: "example.io", ChangedAt: 1686557091, Actor: "127.0.0.1"}))
+	require.NoError(t, urlStorage.Compact())
+
+	// changes after the snapshot are replayed from the log
+	require.NoError(t, urlStorage.DisableUrl(ctx, "summer_sale", true))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "F1rst_Lin4", 1686557092))
+	require.NoError(t, urlStorage.RestoreUrl(ctx, "F1rst_Lin4"))
+	require.NoError(t, urlStorage.Close())
+
+	// the files are the same as the ones of the single lock storage
+	reopened, err := OpenUrlStorage(dir, false)
+	require.NoError(t, err)
+	require.Len(t, reopened.Storage, 3)
+	require.Equal(t, map[string]string{reverseKey("", "example.com"): "F1rst_Lin4"}, reopened.Reverse)
+	require.True(t, reopened.Storage["summer_sale"].Disabled)
+	require.NoError(t, reopened.AddUrl(ctx, *domain.NewURLData("Sec0nd_Lin", "example.net", 1686557090)))
+	require.NoError(t, reopened.Close())
+
+	sharded, err := OpenShardedUrlStorage(dir, false, 16)
+	require.NoError(t, err)
+	defer sharded.Close()
+
+	short, err := sharded.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "F1rst_Lin4", short)
+	history, err := sharded.GetHistory(ctx, "summer_sale")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.ElementsMatch(t, []string{"F1rst_Lin4", "Sec0nd_Lin"}, reverseEntries(sharded))
+}
+
+// reverseEntries returns the short links of the reverse entries that count
+func reverseEntries(s *ShardedUrlStorage) []string {
+	var shorts []string
+	for _, reverse := range s.Reverse {
+		for key, short := range reverse.shorts {
+			link, ok := s.shard(short).links[short]
+			if ok && reverseKey(link.Owner, link.LongURL) == key && link.Reverse {
+				shorts = append(shorts, short)
+			}
+		}
+	}
+	return shorts
+}
+
+func TestShardedUrlStorage_JournalError(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage, err := OpenShardedUrlStorage(t.TempDir(), false, 4)
+	require.NoError(t, err)
+	stored := domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)
+	require.NoError(t, urlStorage.AddUrl(ctx, *stored))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "F1rst_Lin4", 1686557091))
+
+	// every later change fails to be journaled and is not made
+	require.NoError(t, urlStorage.Journal.file.Close())
+	defer urlStorage.Journal.lock.Close()
+
+	require.Error(t, urlStorage.AddUrl(ctx, *domain.NewURLData("Sec0nd_Lin", "example.net", 1686557090)))
+	// a batch is not atomic, so its links fail one by one
+	errs, err := urlStorage.AddUrls(ctx, []domain.URLData{*domain.NewURLData("Sec0nd_Lin", "example.net", 1686557090)})
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	require.Error(t, errs[0])
+	require.Error(t, urlStorage.RestoreUrl(ctx, "F1rst_Lin4"))
+	require.Error(t, urlStorage.UpdateUrl(ctx, "F1rst_Lin4", domain.URLUpdate{LongURL: "example.io", ChangedAt: 1686557092}))
+	_, err = urlStorage.PurgeDeleted(ctx, 1686557092)
+	require.Error(t, err)
+
+	long, err := urlStorage.GetUrl(ctx, "F1rst_Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+	require.Equal(t, int64(1686557091), long.DeletedAt)
+	history, err := urlStorage.GetHistory(ctx, "F1rst_Lin4")
+	require.NoError(t, err)
+	require.Empty(t, history)
+	_, err = urlStorage.GetUrl(ctx, "Sec0nd_Lin")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, []string{"F1rst_Lin4"}, reverseEntries(urlStorage))
+}
+
+func TestShardedUpdateUrl_Expired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewShardedUrlStorage(8)
+
+	expired := domain.NewURLData("Expired123", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+
+	// the history of an expired link is dropped with it, so it is not retargeted
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.UpdateUrl(ctx, "Expired123", domain.URLUpdate{LongURL: "example.org", ChangedAt: 1686557091}))
+
+	long, err := urlStorage.GetUrl(ctx, "Expired123")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+}
@@ -2,7 +2,9 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 )
@@ -10,23 +12,127 @@ import (
 type UrlStorage struct {
 	Mux     *sync.RWMutex
 	Storage map[string]domain.URLLong
+	Reverse map[string]string // owner and long link -> generated short link, see reverseKey
+	History map[string][]domain.URLVersion
+	Aliases map[string]bool // short links chosen by the client
+	Journal *Journal        // persists the links, nil keeps them in memory only
+}
+
+// linkEntry is a change of the links in the journal
+type linkEntry struct {
+	Put    *linkRecord `json:"put,omitempty"`
+	Delete string      `json:"delete,omitempty"`
+}
+
+// linkRecord is the whole state of a link, a put replaces the stored link
+type linkRecord struct {
+	domain.URLData
+	Reverse bool                `json:"reverse,omitempty"` // the link is reused for its long link
+	History []domain.URLVersion `json:"history,omitempty"`
 }
 
 func NewUrlStorage() *UrlStorage {
 	return &UrlStorage{
 		Mux:     new(sync.RWMutex),
 		Storage: map[string]domain.URLLong{},
+		Reverse: map[string]string{},
+		History: map[string][]domain.URLVersion{},
+		Aliases: map[string]bool{},
+	}
+}
+
+// OpenUrlStorage replays the links persisted in the directory
+// and persists every later change there
+func OpenUrlStorage(dir string, sync bool) (*UrlStorage, error) {
+	s := NewUrlStorage()
+
+	journal, err := OpenJournal(dir, "links", sync, s.apply)
+	if err != nil {
+		return nil, err
 	}
+	s.Journal = journal
+
+	return s, nil
 }
 
 func (s *UrlStorage) AddUrl(ctx context.Context, urlData domain.URLData) error {
 	s.Mux.Lock()
 	defer s.Mux.Unlock()
 
-	s.Storage[urlData.URLShort] = urlData.URLLong
+	now := time.Now().Unix()
+	if err := s.check(urlData, now, nil, nil); err != nil {
+		return err
+	}
+	if err := s.log(added(urlData)); err != nil {
+		return err
+	}
+
+	s.add(urlData, now)
 	return nil
 }
 
+func (s *UrlStorage) AddUrls(ctx context.Context, batch []domain.URLData) ([]error, error) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	// the links of the batch count as stored for the later ones
+	now := time.Now().Unix()
+	shorts := make(map[string]bool, len(batch))
+	longs := make(map[string]bool, len(batch))
+
+	errs := make([]error, len(batch))
+	entries := make([]linkEntry, 0, len(batch))
+	for i, urlData := range batch {
+		errs[i] = s.check(urlData, now, shorts, longs)
+		if errs[i] != nil {
+			continue
+		}
+		shorts[urlData.URLShort] = !urlData.Expired(now)
+		if !urlData.Alias {
+			longs[reverseKey(urlData.Owner, urlData.LongURL)] = urlData.Active(now)
+		}
+		entries = append(entries, added(urlData))
+	}
+
+	if err := s.log(entries...); err != nil {
+		return nil, err
+	}
+	for i, urlData := range batch {
+		if errs[i] == nil {
+			s.add(urlData, now)
+		}
+	}
+
+	return errs, nil
+}
+
+// check reports why the link can not be stored, shorts and longs hold the short links
+// and the reverse keys stored by the batch so far, the caller must hold the lock
+func (s *UrlStorage) check(urlData domain.URLData, now int64, shorts map[string]bool, longs map[string]bool) error {
+	if !urlData.Alias {
+		key := reverseKey(urlData.Owner, urlData.LongURL)
+		if short, ok := s.Reverse[key]; ok && s.Storage[short].Active(now) || longs[key] {
+			return domain.ErrorLongExists
+		}
+	}
+
+	if long, ok := s.Storage[urlData.URLShort]; ok && !long.Expired(now) || shorts[urlData.URLShort] {
+		return domain.ErrorShortExists
+	}
+
+	return nil
+}
+
+// add stores the checked link, the caller must hold the write lock
+func (s *UrlStorage) add(urlData domain.URLData, now int64) {
+	if long, ok := s.Storage[urlData.URLShort]; ok {
+		// an expired link that is not reaped yet gives its short link away
+		s.delete(urlData.URLShort, long)
+	}
+
+	s.put(urlData)
+}
+
 func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
 	s.Mux.RLock()
 	defer s.Mux.RUnlock()
@@ -38,3 +144,353 @@ func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLo
 
 	return &longUrl, nil
 }
+
+func (s *UrlStorage) GetUrls(ctx context.Context, shorts []string) (map[string]domain.URLLong, error) {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	found := make(map[string]domain.URLLong, len(shorts))
+	for _, short := range shorts {
+		if long, ok := s.Storage[short]; ok {
+			found[short] = long
+		}
+	}
+
+	return found, nil
+}
+
+func (s *UrlStorage) GetShort(ctx context.Context, longUrl string, owner string) (string, error) {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	shortUrl, ok := s.Reverse[reverseKey(owner, longUrl)]
+	if !ok || !s.Storage[shortUrl].Active(time.Now().Unix()) {
+		return "", domain.ErrorLinkNotFound
+	}
+
+	return shortUrl, nil
+}
+
+func (s *UrlStorage) DeleteExpired(ctx context.Context, now int64) (int64, error) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	return s.deleteWhere(func(long domain.URLLong) bool {
+		return long.Expired(now)
+	})
+}
+
+func (s *UrlStorage) DeleteUrl(ctx context.Context, shortUrl string, deletedAt int64) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	long, ok := s.Storage[shortUrl]
+	if !ok {
+		return domain.ErrorLinkNotFound
+	}
+
+	long.DeletedAt = deletedAt
+	return s.change(shortUrl, long)
+}
+
+func (s *UrlStorage) RestoreUrl(ctx context.Context, shortUrl string) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	long, ok := s.Storage[shortUrl]
+	if !ok || long.DeletedAt == 0 {
+		return domain.ErrorLinkNotFound
+	}
+
+	long.DeletedAt = 0
+	return s.change(shortUrl, long)
+}
+
+func (s *UrlStorage) DisableUrl(ctx context.Context, shortUrl string, disabled bool) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	long, ok := s.Storage[shortUrl]
+	if !ok || long.DeletedAt != 0 {
+		return domain.ErrorLinkNotFound
+	}
+
+	long.Disabled = disabled
+	return s.change(shortUrl, long)
+}
+
+func (s *UrlStorage) UpdateUrl(ctx context.Context, shortUrl string, update domain.URLUpdate) error {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	// an expired link is not retargeted, its short link is up for the taking
+	long, ok := s.Storage[shortUrl]
+	if !ok || long.DeletedAt != 0 || long.Expired(update.ChangedAt) {
+		return domain.ErrorLinkNotFound
+	}
+
+	// a copy, so a failed change leaves the stored history as it is
+	history := s.History[shortUrl]
+	history = append(history[:len(history):len(history)], domain.URLVersion{
+		Version:   int64(len(history) + 1),
+		LongURL:   long.LongURL,
+		ChangedAt: update.ChangedAt,
+		Actor:     update.Actor,
+	})
+
+	// a retargeted link is not reused for its old long link any more
+	retargeted := long
+	retargeted.LongURL = update.LongURL
+	entry := s.record(shortUrl)
+	entry.Put.URLLong = retargeted
+	entry.Put.Reverse = false
+	entry.Put.History = history
+	if err := s.log(entry); err != nil {
+		return err
+	}
+
+	if key := reverseKey(long.Owner, long.LongURL); s.Reverse[key] == shortUrl {
+		delete(s.Reverse, key)
+	}
+	s.Storage[shortUrl] = retargeted
+	s.History[shortUrl] = history
+	return nil
+}
+
+func (s *UrlStorage) GetHistory(ctx context.Context, shortUrl string) ([]domain.URLVersion, error) {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	if _, ok := s.Storage[shortUrl]; !ok {
+		return nil, domain.ErrorLinkNotFound
+	}
+
+	return append([]domain.URLVersion{}, s.History[shortUrl]...), nil
+}
+
+func (s *UrlStorage) PurgeDeleted(ctx context.Context, before int64) (int64, error) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	return s.deleteWhere(func(long domain.URLLong) bool {
+		return long.DeletedAt != 0 && long.DeletedAt <= before
+	})
+}
+
+func (s *UrlStorage) ExportUrls(ctx context.Context, fn func(domain.URLData) error) error {
+	// a snapshot, so a slow writer does not hold the lock
+	s.Mux.RLock()
+	batch := make([]domain.URLData, 0, len(s.Storage))
+	for short, long := range s.Storage {
+		batch = append(batch, domain.URLData{URLShort: short, URLLong: long, Alias: s.Aliases[short]})
+	}
+	s.Mux.RUnlock()
+
+	for _, urlData := range batch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(urlData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *UrlStorage) ImportUrls(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	// the links of the batch count as stored for the later ones
+	shorts := make(map[string]bool, len(batch))
+	longs := make(map[string]bool, len(batch))
+
+	errs := make([]error, len(batch))
+	entries := make([]linkEntry, 0, len(batch))
+	for i, urlData := range batch {
+		if _, ok := s.Storage[urlData.URLShort]; (ok || shorts[urlData.URLShort]) && !overwrite {
+			errs[i] = domain.ErrorShortExists
+			continue
+		}
+		shorts[urlData.URLShort] = true
+
+		// a generated link takes its long link only if no other link holds it, as in the other storages
+		key := reverseKey(urlData.Owner, urlData.LongURL)
+		holder, held := s.Reverse[key]
+		reverse := !urlData.Alias && !longs[key] && (!held || holder == urlData.URLShort)
+		if !urlData.Alias {
+			longs[key] = true
+		}
+		entries = append(entries, linkEntry{Put: &linkRecord{URLData: urlData, Reverse: reverse}})
+	}
+
+	if err := s.log(entries...); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		s.store(entry.Put)
+	}
+
+	return errs, nil
+}
+
+// Compact replaces the logs of the journal with a snapshot of the links
+func (s *UrlStorage) Compact() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	// the appends happen under the write lock
+	s.Mux.RLock()
+	generation, err := s.Journal.Rotate()
+	if err != nil || generation == 0 {
+		s.Mux.RUnlock()
+		return err
+	}
+	entries := make([]interface{}, 0, len(s.Storage))
+	for short := range s.Storage {
+		entries = append(entries, s.record(short))
+	}
+	s.Mux.RUnlock()
+
+	return s.Journal.Snapshot(generation, entries)
+}
+
+func (s *UrlStorage) Close() error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	return s.Journal.Close()
+}
+
+// Ping never fails, the links are in the memory of the process
+func (s *UrlStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// record returns the whole state of the stored link, the caller must hold the lock
+func (s *UrlStorage) record(short string) linkEntry {
+	long := s.Storage[short]
+
+	return linkEntry{Put: &linkRecord{
+		URLData: domain.URLData{URLShort: short, URLLong: long, Alias: s.Aliases[short]},
+		Reverse: s.Reverse[reverseKey(long.Owner, long.LongURL)] == short,
+		History: s.History[short],
+	}}
+}
+
+// added returns the entry of a new link, a generated link takes its long link over
+func added(urlData domain.URLData) linkEntry {
+	return linkEntry{Put: &linkRecord{URLData: urlData, Reverse: !urlData.Alias}}
+}
+
+// change journals the new state of the stored link and then stores it,
+// the caller must hold the write lock
+func (s *UrlStorage) change(short string, long domain.URLLong) error {
+	entry := s.record(short)
+	entry.Put.URLLong = long
+	if err := s.log(entry); err != nil {
+		return err
+	}
+
+	s.Storage[short] = long
+	return nil
+}
+
+// deleteWhere journals the removal of the links fn matches and then removes them,
+// the caller must hold the write lock
+func (s *UrlStorage) deleteWhere(fn func(domain.URLLong) bool) (int64, error) {
+	var entries []linkEntry
+	for short, long := range s.Storage {
+		if fn(long) {
+			entries = append(entries, linkEntry{Delete: short})
+		}
+	}
+	if err := s.log(entries...); err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		s.delete(entry.Delete, s.Storage[entry.Delete])
+	}
+
+	return int64(len(entries)), nil
+}
+
+// log appends the changes to the journal before they are made, so a failed
+// append leaves the storage as it is. The caller must hold the write lock
+func (s *UrlStorage) log(entries ...linkEntry) error {
+	if s.Journal == nil || len(entries) == 0 {
+		return nil
+	}
+
+	changes := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		changes[i] = entry
+	}
+
+	return s.Journal.Append(changes...)
+}
+
+// apply replays a change of the journal
+func (s *UrlStorage) apply(line json.RawMessage) error {
+	var entry linkEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return err
+	}
+
+	if entry.Put == nil {
+		if long, ok := s.Storage[entry.Delete]; ok {
+			s.delete(entry.Delete, long)
+		}
+		return nil
+	}
+
+	s.store(entry.Put)
+	return nil
+}
+
+// store replaces the link with the whole state of the record, the caller must hold the write lock
+func (s *UrlStorage) store(record *linkRecord) {
+	if long, ok := s.Storage[record.URLShort]; ok {
+		s.delete(record.URLShort, long)
+	}
+	s.Storage[record.URLShort] = record.URLLong
+	if record.Alias {
+		s.Aliases[record.URLShort] = true
+	}
+	if record.Reverse {
+		s.Reverse[reverseKey(record.Owner, record.LongURL)] = record.URLShort
+	}
+	if len(record.History) > 0 {
+		s.History[record.URLShort] = record.History
+	}
+}
+
+// put stores the link, the caller must hold the write lock
+func (s *UrlStorage) put(urlData domain.URLData) {
+	s.Storage[urlData.URLShort] = urlData.URLLong
+	if urlData.Alias {
+		s.Aliases[urlData.URLShort] = true
+	} else {
+		s.Reverse[reverseKey(urlData.Owner, urlData.LongURL)] = urlData.URLShort
+	}
+}
+
+// delete removes the link, the caller must hold the write lock
+func (s *UrlStorage) delete(short string, long domain.URLLong) {
+	delete(s.Storage, short)
+	delete(s.History, short)
+	delete(s.Aliases, short)
+	if key := reverseKey(long.Owner, long.LongURL); s.Reverse[key] == short {
+		delete(s.Reverse, key)
+	}
+}
+
+// reverseKey keeps the deduplication apart between owners,
+// key ids never contain the separator
+func reverseKey(owner string, long string) string {
+	return owner + "|" + long
+}
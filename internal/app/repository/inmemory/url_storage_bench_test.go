@@ -0,0 +1,88 @@
+package inmemory
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+const benchLinks = 10000
+
+// go test ./internal/app/repository/inmemory -run '^$' -bench . -cpu 1,8,32
+func BenchmarkUrlStorage(b *testing.B) {
+	storages := []struct {
+		name string
+		new  func() domain.IUrlStorage
+	}{
+		{"mutex", func() domain.IUrlStorage { return NewUrlStorage() }},
+		{"sharded-16", func() domain.IUrlStorage { return NewShardedUrlStorage(16) }},
+		{"sharded-64", func() domain.IUrlStorage { return NewShardedUrlStorage(64) }},
+	}
+	workloads := []struct {
+		name   string
+		writes int // percent of the operations that add a link
+	}{
+		{"read-heavy", 2},
+		{"mixed", 30},
+	}
+
+	for _, workload := range workloads {
+		for _, storage := range storages {
+			b.Run(workload.name+"/"+storage.name, func(b *testing.B) {
+				benchWorkload(b, storage.new(), workload.writes)
+			})
+		}
+	}
+}
+
+// benchWorkload resolves stored links and looks up their long links,
+// writes percent of the operations add new links
+func benchWorkload(b *testing.B, urlStorage domain.IUrlStorage, writes int) {
+	ctx := context.Background()
+	for i := 0; i < benchLinks; i++ {
+		if err := urlStorage.AddUrl(ctx, *domain.NewURLData(benchShort(i), benchLong(i), 1686557090)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	next := int64(benchLinks)
+	var seed int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		random := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			operation := random.Intn(100)
+			i := random.Intn(benchLinks)
+			switch {
+			case operation < writes:
+				n := int(atomic.AddInt64(&next, 1))
+				if err := urlStorage.AddUrl(ctx, *domain.NewURLData(benchShort(n), benchLong(n), 1686557090)); err != nil {
+					b.Error(err)
+					return
+				}
+			case operation%4 == 0:
+				if _, err := urlStorage.GetShort(ctx, benchLong(i), ""); err != nil {
+					b.Error(err)
+					return
+				}
+			default:
+				if _, err := urlStorage.GetUrl(ctx, benchShort(i)); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}
+	})
+}
+
+func benchShort(i int) string {
+	return "bench_" + strconv.Itoa(i)
+}
+
+func benchLong(i int) string {
+	return "https://example.com/" + strconv.Itoa(i)
+}
@@ -3,6 +3,8 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
@@ -37,6 +39,200 @@ func TestAddUrl_Success(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestAddUrl_LongExists(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	first := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	second := domain.NewURLData("An0ther_L1", "example.com", 1686557091)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *first))
+	require.Equal(t, domain.ErrorLongExists, urlStorage.AddUrl(ctx, *second))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, first.URLShort, short)
+}
+
+func TestAddUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin0", "example.net", 1686557090)))
+
+	batch := []domain.URLData{
+		*domain.NewURLData("S0mE__Lin1", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin2", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin1", "example.org", 1686557090),
+		*domain.NewURLData("S0mE__Lin3", "example.net", 1686557090),
+	}
+
+	errs, err := urlStorage.AddUrls(ctx, batch)
+
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, domain.ErrorLongExists, domain.ErrorShortExists, domain.ErrorLongExists}, errs)
+
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin1")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+}
+
+func TestAddUrl_Alias(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	generated := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	alias := domain.NewURLData("summer_sale", "example.com", 1686557091)
+	alias.Alias = true
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *generated))
+	require.NoError(t, urlStorage.AddUrl(ctx, *alias))
+
+	long, err := urlStorage.GetUrl(ctx, alias.URLShort)
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, generated.URLShort, short)
+}
+
+func TestAddUrl_ShortExists(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	first := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	second := domain.NewURLData("S0mE__Lin4", "google.com", 1686557091)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *first))
+	require.Equal(t, domain.ErrorShortExists, urlStorage.AddUrl(ctx, *second))
+
+	long, err := urlStorage.GetUrl(ctx, first.URLShort)
+	require.NoError(t, err)
+	require.Equal(t, first.LongURL, long.LongURL)
+}
+
+func TestAddUrl_ReusesExpiredShort(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	expired := domain.NewURLData("summer_sale", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	fresh := domain.NewURLData("summer_sale", "google.com", 1686557092)
+	fresh.Alias = true
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+	require.NoError(t, urlStorage.AddUrl(ctx, *fresh))
+
+	long, err := urlStorage.GetUrl(ctx, fresh.URLShort)
+	require.NoError(t, err)
+	require.Equal(t, fresh.LongURL, long.LongURL)
+	require.NotContains(t, urlStorage.Reverse, expired.LongURL)
+}
+
+func TestAddUrl_Concurrent(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	var added int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			urldata := domain.NewURLData(fmt.Sprintf("Link%06d", i), "example.com", 1686557090)
+			if err := urlStorage.AddUrl(ctx, *urldata); err == nil {
+				atomic.AddInt32(&added, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), added)
+	require.Len(t, urlStorage.Storage, 1)
+}
+
+func TestGetUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin1", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin2", "example.org", 1686557090)))
+
+	found, err := urlStorage.GetUrls(ctx, []string{"S0mE__Lin1", "S0mE__Lin2", "Missing123"})
+
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, "example.com", found["S0mE__Lin1"].LongURL)
+	require.Equal(t, "example.org", found["S0mE__Lin2"].LongURL)
+}
+
+func TestGetShort(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+	urldata := domain.NewURLData("NormalLink", "example.com", 1686557090)
+	_ = urlStorage.AddUrl(ctx, *urldata)
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "NormalLink", short)
+
+	_, err = urlStorage.GetShort(ctx, "missing.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestAddUrl_ReplacesExpired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	expired := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	fresh := domain.NewURLData("An0ther_L1", "example.com", 1686557092)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+
+	_, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *fresh))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, fresh.URLShort, short)
+}
+
+func TestDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	expired := domain.NewURLData("S0mE__Lin4", "expired.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	alive := domain.NewURLData("An0ther_L1", "alive.com", 1686557090)
+	alive.ExpiresAt = 1686557999
+	forever := domain.NewURLData("Forever123", "forever.com", 1686557090)
+
+	for _, urldata := range []*domain.URLData{expired, alive, forever} {
+		require.NoError(t, urlStorage.AddUrl(ctx, *urldata))
+	}
+
+	deleted, err := urlStorage.DeleteExpired(ctx, 1686557100)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = urlStorage.GetUrl(ctx, expired.URLShort)
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.NotContains(t, urlStorage.Reverse, expired.LongURL)
+	require.Len(t, urlStorage.Storage, 2)
+}
+
 func TestGetUrl(t *testing.T) {
 	var tests = []TestCase{
 		TestCase{
@@ -71,3 +267,282 @@ func TestGetUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteUrl_Restore(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	urldata := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	require.NoError(t, urlStorage.AddUrl(ctx, *urldata))
+
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "S0mE__Lin4", 1686557091))
+
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, int64(1686557091), long.DeletedAt)
+
+	// a deleted link is not reused for its long link and keeps its short link
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, domain.ErrorShortExists, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.org", 1686557090)))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DisableUrl(ctx, "S0mE__Lin4", true))
+
+	require.NoError(t, urlStorage.RestoreUrl(ctx, "S0mE__Lin4"))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.RestoreUrl(ctx, "S0mE__Lin4"))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "S0mE__Lin4", short)
+
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DeleteUrl(ctx, "N0t_F0uNd1", 1686557091))
+}
+
+func TestDisableUrl(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	urldata := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	require.NoError(t, urlStorage.AddUrl(ctx, *urldata))
+
+	require.NoError(t, urlStorage.DisableUrl(ctx, "S0mE__Lin4", true))
+
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.True(t, long.Disabled)
+
+	require.NoError(t, urlStorage.DisableUrl(ctx, "S0mE__Lin4", false))
+
+	long, err = urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.False(t, long.Disabled)
+
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DisableUrl(ctx, "N0t_F0uNd1", true))
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("Old___Lin4", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("New___Lin4", "example.org", 1686557090)))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("Kept__Lin4", "example.net", 1686557090)))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "Old___Lin4", 1000))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "New___Lin4", 2000))
+
+	purged, err := urlStorage.PurgeDeleted(ctx, 1500)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+
+	_, err = urlStorage.GetUrl(ctx, "Old___Lin4")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	_, err = urlStorage.GetUrl(ctx, "New___Lin4")
+	require.NoError(t, err)
+	require.NotContains(t, urlStorage.Reverse, "example.com")
+}
+
+func TestUpdateUrl_History(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)))
+
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.org", ChangedAt: 1686557091, Actor: "127.0.0.1"}))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.net", ChangedAt: 1686557092}))
+
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.net", long.LongURL)
+
+	history, err := urlStorage.GetHistory(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLVersion{
+		{Version: 1, LongURL: "example.com", ChangedAt: 1686557091, Actor: "127.0.0.1"},
+		{Version: 2, LongURL: "example.org", ChangedAt: 1686557092},
+	}, history)
+
+	// the old long link gets a new short link
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "S0mE__Lin4", 1686557093))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.com"}))
+
+	_, err = urlStorage.PurgeDeleted(ctx, 1686557093)
+	require.NoError(t, err)
+	_, err = urlStorage.GetHistory(ctx, "S0mE__Lin4")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.NotContains(t, urlStorage.History, "S0mE__Lin4")
+}
+
+func TestGetShort_Owner(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	first := domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)
+	first.Owner = "0123456789abcdef"
+	require.NoError(t, urlStorage.AddUrl(ctx, *first))
+
+	// another owner gets its own link for the same long link
+	second := domain.NewURLData("Sec0nd_Lin", "example.com", 1686557090)
+	second.Owner = "fedcba9876543210"
+	require.NoError(t, urlStorage.AddUrl(ctx, *second))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, "F1rst_Lin4", short)
+
+	short, err = urlStorage.GetShort(ctx, "example.com", "fedcba9876543210")
+	require.NoError(t, err)
+	require.Equal(t, "Sec0nd_Lin", short)
+
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestExportImportUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+
+	stored := []domain.URLData{
+		{URLShort: "F1rst_Lin4", URLLong: domain.URLLong{LongURL: "example.org", AddedAt: 1686557091}},
+		{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.net", AddedAt: 1686557092, Redirect: 301}, Alias: true},
+	}
+
+	errs, err := urlStorage.ImportUrls(ctx, stored, false)
+	require.NoError(t, err)
+	require.Equal(t, []error{domain.ErrorShortExists, nil}, errs)
+
+	errs, err = urlStorage.ImportUrls(ctx, stored[:1], true)
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, errs)
+
+	// the overwritten long link is free again
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+
+	exported := make(map[string]domain.URLData)
+	err = urlStorage.ExportUrls(ctx, func(urlData domain.URLData) error {
+		exported[urlData.URLShort] = urlData
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]domain.URLData{"F1rst_Lin4": stored[0], "summer_sale": stored[1]}, exported)
+}
+
+func TestImportUrls_KeepsReverse(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	urlStorage, err := OpenUrlStorage(dir, false)
+	require.NoError(t, err)
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+
+	errs, err := urlStorage.ImportUrls(ctx, []domain.URLData{
+		*domain.NewURLData("Sec0nd_Lin", "example.com", 1686557090),
+		*domain.NewURLData("Th1rd_Lin4", "example.org", 1686557090),
+		*domain.NewURLData("F0urth_Li4", "example.org", 1686557090),
+	}, false)
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, nil, nil}, errs)
+
+	// the stored link and the first one of the batch are reused for their long links
+	require.Equal(t, map[string]string{
+		reverseKey("", "example.com"): "F1rst_Lin4",
+		reverseKey("", "example.org"): "Th1rd_Lin4",
+	}, urlStorage.Reverse)
+	require.NoError(t, urlStorage.Close())
+
+	reopened, err := OpenUrlStorage(dir, false)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	require.Equal(t, urlStorage.Reverse, reopened.Reverse)
+}
+
+func TestOpenUrlStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	urlStorage, err := OpenUrlStorage(dir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.AddUrl(ctx, domain.URLData{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.org", AddedAt: 1686557090}, Alias: true}))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("Expired123", "example.net", 1)))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "F1rst_Lin4", domain.URLUpdate{LongURL: "example.io", ChangedAt: 1686557091, Actor: "127.0.0.1"}))
+	require.NoError(t, urlStorage.Compact())
+
+	// changes after the snapshot are replayed from the log
+	require.NoError(t, urlStorage.DisableUrl(ctx, "summer_sale", true))
+	_, err = urlStorage.PurgeDeleted(ctx, 1686557092)
+	require.NoError(t, err)
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "Expired123", 1686557092))
+	_, err = urlStorage.PurgeDeleted(ctx, 1686557092)
+	require.NoError(t, err)
+	require.NoError(t, urlStorage.Close())
+
+	reopened, err := OpenUrlStorage(dir, false)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	require.Equal(t, urlStorage.Storage, reopened.Storage)
+	require.Equal(t, urlStorage.Reverse, reopened.Reverse)
+	require.Equal(t, urlStorage.Aliases, reopened.Aliases)
+	require.Equal(t, urlStorage.History, reopened.History)
+	require.Len(t, reopened.Storage, 2)
+}
+
+func TestUrlStorage_JournalError(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage, err := OpenUrlStorage(t.TempDir(), false)
+	require.NoError(t, err)
+	stored := domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)
+	require.NoError(t, urlStorage.AddUrl(ctx, *stored))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "F1rst_Lin4", 1686557091))
+
+	// every later change fails to be journaled and is not made
+	require.NoError(t, urlStorage.Journal.file.Close())
+	defer urlStorage.Journal.lock.Close()
+
+	require.Error(t, urlStorage.AddUrl(ctx, *domain.NewURLData("Sec0nd_Lin", "example.net", 1686557090)))
+	_, err = urlStorage.AddUrls(ctx, []domain.URLData{*domain.NewURLData("Sec0nd_Lin", "example.net", 1686557090)})
+	require.Error(t, err)
+	require.Error(t, urlStorage.RestoreUrl(ctx, "F1rst_Lin4"))
+	require.Error(t, urlStorage.UpdateUrl(ctx, "F1rst_Lin4", domain.URLUpdate{LongURL: "example.io", ChangedAt: 1686557092}))
+	_, err = urlStorage.PurgeDeleted(ctx, 1686557092)
+	require.Error(t, err)
+	_, err = urlStorage.ImportUrls(ctx, []domain.URLData{*domain.NewURLData("F1rst_Lin4", "example.org", 1)}, true)
+	require.Error(t, err)
+
+	long := stored.URLLong
+	long.DeletedAt = 1686557091
+	require.Equal(t, map[string]domain.URLLong{"F1rst_Lin4": long}, urlStorage.Storage)
+	require.Equal(t, map[string]string{reverseKey("", "example.com"): "F1rst_Lin4"}, urlStorage.Reverse)
+	require.Empty(t, urlStorage.History)
+}
+
+func TestUpdateUrl_Expired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage()
+
+	expired := domain.NewURLData("Expired123", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+
+	// the history of an expired link is dropped with it, so it is not retargeted
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.UpdateUrl(ctx, "Expired123", domain.URLUpdate{LongURL: "example.org", ChangedAt: 1686557091}))
+
+	long, err := urlStorage.GetUrl(ctx, "Expired123")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StorageMetrics are the operation latencies and errors of the link storages,
+// shared by every decorated storage and told apart by the storage label
+type StorageMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func NewStorageMetrics(registerer prometheus.Registerer) *StorageMetrics {
+	m := &StorageMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shorturl_storage_operation_duration_seconds",
+			Help:    "Latency of the link storage operations by storage and operation.",
+			Buckets: []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"storage", "operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shorturl_storage_operation_errors_total",
+			Help: "Failed link storage operations by storage and operation, missing and taken links are answers and not counted.",
+		}, []string{"storage", "operation"}),
+	}
+
+	registerer.MustRegister(m.duration, m.errors)
+	return m
+}
+
+// UrlStorage observes every operation of another storage under its name
+type UrlStorage struct {
+	DB      domain.IUrlStorage
+	Name    string
+	metrics *StorageMetrics
+}
+
+func NewUrlStorage(db domain.IUrlStorage, name string, metrics *StorageMetrics) *UrlStorage {
+	return &UrlStorage{
+		DB:      db,
+		Name:    name,
+		metrics: metrics,
+	}
+}
+
+// observe records the operation started at start, it is deferred with the named error of the operation
+func (s *UrlStorage) observe(operation string, start time.Time, err *error) {
+	s.metrics.duration.WithLabelValues(s.Name, operation).Observe(time.Since(start).Seconds())
+	switch *err {
+	case nil, domain.ErrorLinkNotFound, domain.ErrorShortExists, domain.ErrorLongExists:
+	default:
+		s.metrics.errors.WithLabelValues(s.Name, operation).Inc()
+	}
+}
+
+func (s *UrlStorage) AddUrl(ctx context.Context, urlData domain.URLData) (err error) {
+	defer s.observe("AddUrl", time.Now(), &err)
+	return s.DB.AddUrl(ctx, urlData)
+}
+
+// AddUrls counts the failed batches, not the links that failed in them
+func (s *UrlStorage) AddUrls(ctx context.Context, batch []domain.URLData) (_ []error, err error) {
+	defer s.observe("AddUrls", time.Now(), &err)
+	return s.DB.AddUrls(ctx, batch)
+}
+
+func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (_ *domain.URLLong, err error) {
+	defer s.observe("GetUrl", time.Now(), &err)
+	return s.DB.GetUrl(ctx, shortUrl)
+}
+
+func (s *UrlStorage) GetUrls(ctx context.Context, shortUrls []string) (_ map[string]domain.URLLong, err error) {
+	defer s.observe("GetUrls", time.Now(), &err)
+	return s.DB.GetUrls(ctx, shortUrls)
+}
+
+func (s *UrlStorage) GetShort(ctx context.Context, longUrl string, owner string) (_ string, err error) {
+	defer s.observe("GetShort", time.Now(), &err)
+	return s.DB.GetShort(ctx, longUrl, owner)
+}
+
+func (s *UrlStorage) DeleteExpired(ctx context.Context, now int64) (_ int64, err error) {
+	defer s.observe("DeleteExpired", time.Now(), &err)
+	return s.DB.DeleteExpired(ctx, now)
+}
+
+func (s *UrlStorage) DeleteUrl(ctx context.Context, shortUrl string, deletedAt int64) (err error) {
+	defer s.observe("DeleteUrl", time.Now(), &err)
+	return s.DB.DeleteUrl(ctx, shortUrl, deletedAt)
+}
+
+func (s *UrlStorage) RestoreUrl(ctx context.Context, shortUrl string) (err error) {
+	defer s.observe("RestoreUrl", time.Now(), &err)
+	return s.DB.RestoreUrl(ctx, shortUrl)
+}
+
+func (s *UrlStorage) DisableUrl(ctx context.Context, shortUrl string, disabled bool) (err error) {
+	defer s.observe("DisableUrl", time.Now(), &err)
+	return s.DB.DisableUrl(ctx, shortUrl, disabled)
+}
+
+func (s *UrlStorage) UpdateUrl(ctx context.Context, shortUrl string, update domain.URLUpdate) (err error) {
+	defer s.observe("UpdateUrl", time.Now(), &err)
+	return s.DB.UpdateUrl(ctx, shortUrl, update)
+}
+
+func (s *UrlStorage) GetHistory(ctx context.Context, shortUrl string) (_ []domain.URLVersion, err error) {
+	defer s.observe("GetHistory", time.Now(), &err)
+	return s.DB.GetHistory(ctx, shortUrl)
+}
+
+// ExportUrls observes the whole export, the time spent in fn included
+func (s *UrlStorage) ExportUrls(ctx context.Context, fn func(domain.URLData) error) (err error) {
+	defer s.observe("ExportUrls", time.Now(), &err)
+	return s.DB.ExportUrls(ctx, fn)
+}
+
+func (s *UrlStorage) ImportUrls(ctx context.Context, batch []domain.URLData, overwrite bool) (_ []error, err error) {
+	defer s.observe("ImportUrls", time.Now(), &err)
+	return s.DB.ImportUrls(ctx, batch, overwrite)
+}
+
+func (s *UrlStorage) PurgeDeleted(ctx context.Context, before int64) (_ int64, err error) {
+	defer s.observe("PurgeDeleted", time.Now(), &err)
+	return s.DB.PurgeDeleted(ctx, before)
+}
+
+func (s *UrlStorage) Ping(ctx context.Context) (err error) {
+	defer s.observe("Ping", time.Now(), &err)
+	return s.DB.Ping(ctx)
+}
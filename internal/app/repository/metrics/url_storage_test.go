@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUrlStorage(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	registry := prometheus.NewRegistry()
+	metrics := NewStorageMetrics(registry)
+	db := mocks.NewMockIUrlStorage(ctrl)
+	cache := mocks.NewMockIUrlStorage(ctrl)
+	urlStorage := NewUrlStorage(db, "pgx", metrics)
+	cached := NewUrlStorage(cache, "cache", metrics)
+
+	broken := errors.New("connection reset")
+	db.EXPECT().GetUrl(ctx, "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil)
+	db.EXPECT().GetUrl(ctx, "missing").Return(nil, domain.ErrorLinkNotFound)
+	db.EXPECT().AddUrl(ctx, gomock.Any()).Return(broken)
+	cache.EXPECT().GetUrl(ctx, "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil)
+
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+	_, err = urlStorage.GetUrl(ctx, "missing")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, broken, urlStorage.AddUrl(ctx, domain.URLData{URLShort: "S0mE__Lin4"}))
+	_, err = cached.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+
+	expected := `
+		# HELP shorturl_storage_operation_errors_total Failed link storage operations by storage and operation, missing and taken links are answers and not counted.
+		# TYPE shorturl_storage_operation_errors_total counter
+		shorturl_storage_operation_errors_total{operation="AddUrl",storage="pgx"} 1
+	`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "shorturl_storage_operation_errors_total"))
+	require.Equal(t, 3, testutil.CollectAndCount(registry, "shorturl_storage_operation_duration_seconds"))
+}
@@ -0,0 +1,62 @@
+package postgresql
+
+import (
+	"context"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+var clickColumns = []string{"short", "clicked_at", "referrer", "user_agent", "ip"}
+
+type ClickStorage struct {
+	Pool domain.IPool
+}
+
+func NewClickStorage(pool domain.IPool) *ClickStorage {
+	return &ClickStorage{
+		Pool: pool,
+	}
+}
+
+func (s *ClickStorage) AddClick(ctx context.Context, click domain.Click) error {
+	_, err := s.Pool.Exec(ctx, "INSERT INTO clicks(short, clicked_at, referrer, user_agent, ip) VALUES ($1, $2, $3, $4, $5)", click.Short, click.At, click.Referrer, click.UserAgent, click.IP)
+	return err
+}
+
+// AddClicks streams the batch with COPY, one round trip for the whole batch
+func (s *ClickStorage) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	_, err := s.Pool.CopyFrom(ctx, pgx.Identifier{"clicks"}, clickColumns, pgx.CopyFromSlice(len(clicks), func(i int) ([]interface{}, error) {
+		click := clicks[i]
+		return []interface{}{click.Short, click.At, click.Referrer, click.UserAgent, click.IP}, nil
+	}))
+	return err
+}
+
+func (s *ClickStorage) GetStats(ctx context.Context, request domain.StatsRequest) (*domain.Stats, error) {
+	stats := &domain.Stats{Short: request.Short}
+	if err := s.Pool.QueryRow(ctx, "SELECT count(*) FROM clicks WHERE short = $1", request.Short).Scan(&stats.Total); err != nil {
+		return &domain.Stats{}, err
+	}
+
+	rows, err := s.Pool.Query(ctx, `SELECT $2 + (clicked_at - $2) / $4 * $4 AS start, count(*) FROM clicks
+		WHERE short = $1 AND clicked_at >= $2 AND clicked_at < $3 GROUP BY start ORDER BY start`,
+		request.Short, request.From, request.To, request.Interval)
+	if err != nil {
+		return &domain.Stats{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket domain.Bucket
+		if err := rows.Scan(&bucket.Start, &bucket.Count); err != nil {
+			return &domain.Stats{}, err
+		}
+		stats.Buckets = append(stats.Buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return &domain.Stats{}, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,154 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+var click = domain.Click{
+	Short:  "S0mE__Lin4",
+	At:     1686557090,
+	Client: domain.Client{Referrer: "ya.ru", UserAgent: "curl/8.0", IP: "127.0.0.1"},
+}
+
+func TestAddClick_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	clickStorage := NewClickStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), click.Short, click.At, click.Referrer, click.UserAgent, click.IP).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+	err := clickStorage.AddClick(ctx, click)
+
+	require.NoError(t, err)
+}
+
+func TestAddClick_ExecError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	clickStorage := NewClickStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, ErrExec)
+
+	err := clickStorage.AddClick(ctx, click)
+
+	require.Equal(t, ErrExec, err)
+}
+
+func TestClickGetStats_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	clickStorage := NewClickStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+	mockRows := mocks.NewMockRows(ctrl)
+
+	request := domain.StatsRequest{Short: "S0mE__Lin4", From: 1000, To: 1300, Interval: 100}
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), request.Short).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+		*args[0].(*int64) = 5
+		return nil
+	})
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any(), request.Short, request.From, request.To, request.Interval).Return(mockRows, nil)
+
+	gomock.InOrder(
+		mockRows.EXPECT().Next().Return(true),
+		mockRows.EXPECT().Scan(gomock.Any(), gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+			*args[0].(*int64) = 1100
+			*args[1].(*int64) = 3
+			return nil
+		}),
+		mockRows.EXPECT().Next().Return(false),
+	)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	stats, err := clickStorage.GetStats(ctx, request)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(5), stats.Total)
+	require.Equal(t, []domain.Bucket{{Start: 1100, Count: 3}}, stats.Buckets)
+}
+
+func TestClickGetStats_ScanError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	clickStorage := NewClickStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).Return(ErrScan)
+
+	_, err := clickStorage.GetStats(ctx, domain.StatsRequest{Short: "S0mE__Lin4", From: 1000, To: 1300, Interval: 100})
+
+	require.Equal(t, ErrScan, err)
+}
+
+func TestAddClicks_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	clickStorage := NewClickStorage(pool)
+
+	pool.EXPECT().CopyFrom(gomock.Any(), pgx.Identifier{"clicks"}, clickColumns, gomock.Any()).DoAndReturn(func(ctx context.Context, table pgx.Identifier, columns []string, source pgx.CopyFromSource) (int64, error) {
+		var rows int64
+		for source.Next() {
+			values, err := source.Values()
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{click.Short, click.At, click.Referrer, click.UserAgent, click.IP}, values)
+			rows++
+		}
+		return rows, nil
+	})
+
+	err := clickStorage.AddClicks(ctx, []domain.Click{click, click})
+
+	require.NoError(t, err)
+}
+
+func TestAddClicks_CopyError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	clickStorage := NewClickStorage(pool)
+
+	pool.EXPECT().CopyFrom(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), ErrExec)
+
+	err := clickStorage.AddClicks(ctx, []domain.Click{click})
+
+	require.Equal(t, ErrExec, err)
+}
@@ -2,9 +2,12 @@ package postgresql
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Pool struct {
@@ -18,3 +21,50 @@ func (pool *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
 	}
 	return Tx, nil
 }
+
+// PoolConfig tunes the connection pool, zero values keep the pgx defaults
+type PoolConfig struct {
+	MaxConns         int32
+	MinConns         int32
+	MaxConnLifetime  time.Duration
+	MaxConnIdleTime  time.Duration
+	StatementTimeout time.Duration // the server cancels longer statements
+}
+
+// ParsePoolConfig returns the pgxpool config of the url with the settings applied.
+// Statements are prepared once per connection and reused from its cache
+func ParsePoolConfig(url string, config PoolConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = config.MaxConns
+	}
+	if config.MinConns > 0 {
+		poolConfig.MinConns = config.MinConns
+	}
+	if config.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.MaxConnLifetime
+	}
+	if config.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	}
+	if config.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+	}
+	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+
+	return poolConfig, nil
+}
+
+// NewPool connects a pool to the database of the url, the connections are opened lazily
+func NewPool(ctx context.Context, url string, config PoolConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := ParsePoolConfig(url, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
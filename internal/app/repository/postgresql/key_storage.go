@@ -0,0 +1,57 @@
+package postgresql
+
+import (
+	"context"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+type KeyStorage struct {
+	Pool domain.IPool
+}
+
+func NewKeyStorage(pool domain.IPool) *KeyStorage {
+	return &KeyStorage{
+		Pool: pool,
+	}
+}
+
+func (s *KeyStorage) AddKey(ctx context.Context, key domain.APIKey) error {
+	_, err := s.Pool.Exec(ctx, "INSERT INTO api_keys(id, name, hash, created, revoked) VALUES ($1, $2, $3, $4, $5)", key.ID, key.Name, key.Hash, key.CreatedAt, key.RevokedAt)
+	return err
+}
+
+func (s *KeyStorage) GetKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	key := &domain.APIKey{ID: id}
+	if err := s.Pool.QueryRow(ctx, "SELECT name, hash, created, revoked FROM api_keys WHERE id = $1", id).Scan(&key.Name, &key.Hash, &key.CreatedAt, &key.RevokedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.APIKey{}, domain.ErrorKeyNotFound
+		} else {
+			return &domain.APIKey{}, err
+		}
+	}
+
+	return key, nil
+}
+
+func (s *KeyStorage) RevokeKey(ctx context.Context, id string, revokedAt int64) error {
+	return s.update(ctx, "UPDATE api_keys SET revoked = $2 WHERE id = $1 AND revoked = 0", id, revokedAt)
+}
+
+func (s *KeyStorage) RotateKey(ctx context.Context, id string, hash string) error {
+	return s.update(ctx, "UPDATE api_keys SET hash = $2 WHERE id = $1 AND revoked = 0", id, hash)
+}
+
+// update runs a single key update, ErrorKeyNotFound if no active key matches
+func (s *KeyStorage) update(ctx context.Context, sql string, args ...interface{}) error {
+	tag, err := s.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrorKeyNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKey = domain.APIKey{
+	ID:        "0123456789abcdef",
+	Name:      "ci",
+	Hash:      "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8",
+	CreatedAt: 1686557090,
+}
+
+func TestAddKey_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	keyStorage := NewKeyStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), apiKey.ID, apiKey.Name, apiKey.Hash, apiKey.CreatedAt, apiKey.RevokedAt).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+	err := keyStorage.AddKey(ctx, apiKey)
+
+	require.NoError(t, err)
+}
+
+func TestGetKey_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	keyStorage := NewKeyStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), apiKey.ID).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+		*args[0].(*string) = apiKey.Name
+		*args[1].(*string) = apiKey.Hash
+		*args[2].(*int64) = apiKey.CreatedAt
+		return nil
+	})
+
+	key, err := keyStorage.GetKey(ctx, apiKey.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, apiKey, *key)
+}
+
+func TestGetKey_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	keyStorage := NewKeyStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), apiKey.ID).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).Return(pgx.ErrNoRows)
+
+	_, err := keyStorage.GetKey(ctx, apiKey.ID)
+
+	require.Equal(t, domain.ErrorKeyNotFound, err)
+}
+
+func TestRevokeKey_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	keyStorage := NewKeyStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), apiKey.ID, int64(1686557091)).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+
+	err := keyStorage.RevokeKey(ctx, apiKey.ID, 1686557091)
+
+	require.Equal(t, domain.ErrorKeyNotFound, err)
+}
+
+func TestRotateKey_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	keyStorage := NewKeyStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), apiKey.ID, "new").Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+	err := keyStorage.RotateKey(ctx, apiKey.ID, "new")
+
+	require.NoError(t, err)
+}
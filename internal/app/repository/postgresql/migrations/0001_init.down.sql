@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS clicks;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS link_history;
+DROP TABLE IF EXISTS links;
@@ -0,0 +1,56 @@
+-- the schema of scripts/sql/init.sql, databases made from it keep their tables
+-- and get the columns added to links since then
+CREATE TABLE IF NOT EXISTS links (
+    id SERIAL PRIMARY KEY,
+    short VARCHAR(255) NOT NULL,
+    "long" VARCHAR(255) NOT NULL,
+    added BIGINT,
+    expires BIGINT NOT NULL DEFAULT 0,
+    alias BOOLEAN NOT NULL DEFAULT false,
+    redirect SMALLINT NOT NULL DEFAULT 0,
+    deleted BIGINT NOT NULL DEFAULT 0,
+    disabled BOOLEAN NOT NULL DEFAULT false,
+    owner VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS expires BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS alias BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS redirect SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS deleted BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS owner VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS links_short_idx ON links (short);
+CREATE INDEX IF NOT EXISTS links_long_idx ON links ("long");
+CREATE INDEX IF NOT EXISTS links_expires_idx ON links (expires) WHERE expires <> 0;
+CREATE INDEX IF NOT EXISTS links_deleted_idx ON links (deleted) WHERE deleted <> 0;
+
+CREATE TABLE IF NOT EXISTS link_history (
+    id BIGSERIAL PRIMARY KEY,
+    short VARCHAR(255) NOT NULL REFERENCES links (short) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    "long" VARCHAR(255) NOT NULL,
+    changed_at BIGINT NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS link_history_short_version_idx ON link_history (short, version);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    hash VARCHAR(64) NOT NULL,
+    created BIGINT NOT NULL,
+    revoked BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short VARCHAR(255) NOT NULL,
+    clicked_at BIGINT NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_short_at_idx ON clicks (short, clicked_at);
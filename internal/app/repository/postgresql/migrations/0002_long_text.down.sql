@@ -0,0 +1,5 @@
+-- fails if a stored long link is longer than 255 characters
+DROP INDEX IF EXISTS links_long_idx;
+ALTER TABLE link_history ALTER COLUMN "long" TYPE VARCHAR(255);
+ALTER TABLE links ALTER COLUMN "long" TYPE VARCHAR(255);
+CREATE INDEX links_long_idx ON links ("long");
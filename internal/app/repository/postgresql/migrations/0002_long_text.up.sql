@@ -0,0 +1,8 @@
+-- long links are not limited to 255 characters any more
+ALTER TABLE links ALTER COLUMN "long" TYPE TEXT;
+ALTER TABLE link_history ALTER COLUMN "long" TYPE TEXT;
+
+-- a btree entry must fit in a third of a page, a hash index takes links of any length
+-- and the long links are only ever looked up by equality
+DROP INDEX IF EXISTS links_long_idx;
+CREATE INDEX links_long_idx ON links USING hash ("long");
@@ -0,0 +1,14 @@
+-- the reverse link of a long link is the one its owner gets back when the long
+-- link is created again, aliases never are. The unique index keeps one per long
+-- link, so a create needs no lock to win the race against a concurrent one
+ALTER TABLE links ADD COLUMN IF NOT EXISTS reverse BOOLEAN NOT NULL DEFAULT false;
+
+-- the newest active link of every long link, the newest link when none is active
+UPDATE links SET reverse = true WHERE id IN (
+    SELECT DISTINCT ON (owner, "long") id FROM links
+    WHERE NOT alias
+    ORDER BY owner, "long", (deleted = 0 AND NOT disabled AND (expires = 0 OR expires > extract(epoch from now()))) DESC, id DESC
+);
+
+-- long links are not limited in length, the index keeps their digests
+CREATE UNIQUE INDEX IF NOT EXISTS links_owner_long_idx ON links (owner, md5("long")) WHERE reverse;
@@ -0,0 +1,3 @@
+-- IRREVERSIBLE: the up migration deleted the history of links that are gone,
+-- it is not restored, rolling back only lowers the schema version
+SELECT 1;
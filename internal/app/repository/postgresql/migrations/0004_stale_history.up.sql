@@ -0,0 +1,5 @@
+-- IRREVERSIBLE: the down migration does not bring the deleted rows back.
+-- the history left by expired links whose short links were taken over, the
+-- versions of the link that holds a short link now start at one
+DELETE FROM link_history h USING links l
+WHERE h.short = l.short AND h.changed_at < l.added;
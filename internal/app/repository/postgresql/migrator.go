@@ -0,0 +1,274 @@
+package postgresql
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// migrationLock is the advisory lock key replicas take before they migrate
+const migrationLock int64 = 0x73686f727475726c // "shorturl"
+
+// Migration is a versioned change of the schema with the SQL that undoes it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration with the unix time it was applied at,
+// zero means the migration is pending
+type MigrationStatus struct {
+	Migration
+	AppliedAt int64
+}
+
+// Migrator applies the migrations in the order of their versions and keeps
+// the applied ones in the schema_migrations table. Every run is a single
+// transaction under an advisory lock, so replicas started together migrate
+// one after another and a failed migration leaves the schema as it was
+type Migrator struct {
+	Pool       domain.IPool
+	Migrations []Migration
+}
+
+// NewMigrator returns a migrator of the migrations embedded in the binary
+func NewMigrator(pool domain.IPool) (*Migrator, error) {
+	migrations, err := LoadMigrations(embedded, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		Pool:       pool,
+		Migrations: migrations,
+	}, nil
+}
+
+// LoadMigrations reads the <version>_<name>.up.sql and <version>_<name>.down.sql
+// files of the directory, every version needs both of them
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		name := file.Name()
+		var up bool
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			up = true
+			name = strings.TrimSuffix(name, ".up.sql")
+		case strings.HasSuffix(name, ".down.sql"):
+			name = strings.TrimSuffix(name, ".down.sql")
+		default:
+			continue
+		}
+
+		number, title, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(number, 10, 64)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("unexpected migration file: %s", file.Name())
+		}
+
+		sql, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: title}
+			byVersion[version] = migration
+		}
+		if migration.Name != title {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, title)
+		}
+		if up {
+			migration.Up = string(sql)
+		} else {
+			migration.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs an up and a down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies the pending migrations and returns them, it refuses a database
+// migrated by a newer binary
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.locked(ctx, func(tx pgx.Tx, done map[int64]int64) error {
+		if err := m.checkKnown(done); err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		for _, migration := range m.Migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations(version, name, applied_at) VALUES ($1, $2, $3)", migration.Version, migration.Name, now)
+			if err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Down undoes the last steps applied migrations, newest first, and returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var undone []Migration
+
+	err := m.locked(ctx, func(tx pgx.Tx, done map[int64]int64) error {
+		for i := len(m.Migrations) - 1; i >= 0 && len(undone) < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			if _, err := tx.Exec(ctx, migration.Down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+			if err != nil {
+				return err
+			}
+			undone = append(undone, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return undone, nil
+}
+
+// Status returns every known migration with the time it was applied at
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.locked(ctx, func(tx pgx.Tx, done map[int64]int64) error {
+		for _, migration := range m.Migrations {
+			statuses = append(statuses, MigrationStatus{Migration: migration, AppliedAt: done[migration.Version]})
+		}
+		return m.checkKnown(done)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// checkKnown fails when a version was applied by a newer binary,
+// an older one must not run against the schema it left
+func (m *Migrator) checkKnown(done map[int64]int64) error {
+	for version := range done {
+		if !m.known(version) {
+			return fmt.Errorf("%w: %d", domain.ErrorUnknownMigration, version)
+		}
+	}
+	return nil
+}
+
+// locked runs fn in a transaction that holds the migration lock,
+// with the applied versions and the times they were applied at
+func (m *Migrator) locked(ctx context.Context, fn func(pgx.Tx, map[int64]int64) error) error {
+	tx, err := m.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLock)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	done, err := m.applied(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx, done); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// applied returns the applied versions with the times they were applied at
+func (m *Migrator) applied(ctx context.Context, tx pgx.Tx) (map[int64]int64, error) {
+	rows, err := tx.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int64]int64)
+	for rows.Next() {
+		var version, appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+
+	return done, rows.Err()
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
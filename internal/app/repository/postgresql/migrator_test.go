@@ -0,0 +1,232 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "init", Up: "CREATE TABLE a", Down: "DROP TABLE a"},
+	{Version: 2, Name: "b", Up: "CREATE TABLE b", Down: "DROP TABLE b"},
+	{Version: 3, Name: "c", Up: "CREATE TABLE c", Down: "DROP TABLE c"},
+}
+
+// expectLocked expects the lock and returns the applied versions with their times
+func expectLocked(ctrl *gomock.Controller, mockTx *mocks.MockTx, applied map[int64]int64) {
+	mockTx.EXPECT().Exec(gomock.Any(), "SELECT pg_advisory_xact_lock($1)", migrationLock).Return(pgconn.CommandTag{}, nil)
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, nil)
+
+	mockRows := mocks.NewMockRows(ctrl)
+	for version, appliedAt := range applied {
+		version, appliedAt := version, appliedAt
+		mockRows.EXPECT().Next().Return(true)
+		mockRows.EXPECT().Scan(gomock.Any(), gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+			*args[0].(*int64) = version
+			*args[1].(*int64) = appliedAt
+			return nil
+		})
+	}
+	mockRows.EXPECT().Next().Return(false)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	mockTx.EXPECT().Query(gomock.Any(), "SELECT version, applied_at FROM schema_migrations").Return(mockRows, nil)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_b.up.sql":      {Data: []byte("CREATE TABLE b")},
+		"migrations/0002_b.down.sql":    {Data: []byte("DROP TABLE b")},
+		"migrations/0001_init.up.sql":   {Data: []byte("CREATE TABLE a")},
+		"migrations/0001_init.down.sql": {Data: []byte("DROP TABLE a")},
+		"migrations/README.md":          {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+
+	require.NoError(t, err)
+	require.Equal(t, testMigrations[:2], migrations)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"Missing Down": {
+			"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE a")},
+		},
+		"Bad Version": {
+			"migrations/first_init.up.sql":   {Data: []byte("CREATE TABLE a")},
+			"migrations/first_init.down.sql": {Data: []byte("DROP TABLE a")},
+		},
+		"Two Names": {
+			"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE a")},
+			"migrations/0001_a.down.sql":  {Data: []byte("DROP TABLE a")},
+		},
+	}
+
+	for title, fsys := range tests {
+		t.Run(title, func(t *testing.T) {
+			_, err := LoadMigrations(fsys, "migrations")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewMigrator_Embedded(t *testing.T) {
+	migrator, err := NewMigrator(nil)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrator.Migrations)
+	for i, migration := range migrator.Migrations {
+		require.Equal(t, int64(i+1), migration.Version, "versions have no gaps")
+	}
+}
+
+func TestMigratorUp(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	migrator := &Migrator{Pool: pool, Migrations: testMigrations}
+
+	mockTx := mocks.NewMockTx(ctrl)
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	expectLocked(ctrl, mockTx, map[int64]int64{1: 1686557090})
+
+	gomock.InOrder(
+		mockTx.EXPECT().Exec(gomock.Any(), "CREATE TABLE b").Return(pgconn.CommandTag{}, nil),
+		mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), int64(2), "b", gomock.Any()).Return(pgconn.CommandTag{}, nil),
+		mockTx.EXPECT().Exec(gomock.Any(), "CREATE TABLE c").Return(pgconn.CommandTag{}, nil),
+		mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), int64(3), "c", gomock.Any()).Return(pgconn.CommandTag{}, nil),
+	)
+
+	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	applied, err := migrator.Up(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, testMigrations[1:], applied)
+}
+
+func TestMigratorUp_Error(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	migrator := &Migrator{Pool: pool, Migrations: testMigrations}
+
+	mockTx := mocks.NewMockTx(ctrl)
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	expectLocked(ctrl, mockTx, map[int64]int64{})
+
+	mockTx.EXPECT().Exec(gomock.Any(), "CREATE TABLE a").Return(pgconn.CommandTag{}, ErrExec)
+
+	// nothing is committed
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	_, err := migrator.Up(ctx)
+
+	require.True(t, errors.Is(err, ErrExec))
+}
+
+func TestMigratorUp_Unknown(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	migrator := &Migrator{Pool: pool, Migrations: testMigrations[:2]}
+
+	mockTx := mocks.NewMockTx(ctrl)
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	// migrated by a newer binary, nothing is applied
+	expectLocked(ctrl, mockTx, map[int64]int64{1: 1686557090, 3: 1686557091})
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	_, err := migrator.Up(ctx)
+
+	require.True(t, errors.Is(err, domain.ErrorUnknownMigration))
+}
+
+func TestMigratorDown(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	migrator := &Migrator{Pool: pool, Migrations: testMigrations}
+
+	mockTx := mocks.NewMockTx(ctrl)
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	expectLocked(ctrl, mockTx, map[int64]int64{1: 1686557090, 2: 1686557090})
+
+	gomock.InOrder(
+		mockTx.EXPECT().Exec(gomock.Any(), "DROP TABLE b").Return(pgconn.CommandTag{}, nil),
+		mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), int64(2)).Return(pgconn.CommandTag{}, nil),
+	)
+
+	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	undone, err := migrator.Down(ctx, 1)
+
+	require.NoError(t, err)
+	require.Equal(t, testMigrations[1:2], undone)
+}
+
+func TestMigratorStatus(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	migrator := &Migrator{Pool: pool, Migrations: testMigrations[:2]}
+
+	mockTx := mocks.NewMockTx(ctrl)
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil).Times(2)
+
+	expectLocked(ctrl, mockTx, map[int64]int64{1: 1686557090})
+
+	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil).Times(2)
+
+	statuses, err := migrator.Status(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStatus{{Migration: testMigrations[0], AppliedAt: 1686557090}, {Migration: testMigrations[1]}}, statuses)
+
+	// migrated by a newer binary
+	expectLocked(ctrl, mockTx, map[int64]int64{1: 1686557090, 3: 1686557091})
+
+	_, err = migrator.Status(ctx)
+
+	require.True(t, errors.Is(err, domain.ErrorUnknownMigration))
+}
@@ -0,0 +1,74 @@
+package postgresql
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports the statistics of the pgx pool, they are read at every scrape
+type PoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	acquireSeconds    *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	newConns          *prometheus.Desc
+	lifetimeDestroyed *prometheus.Desc
+	idleDestroyed     *prometheus.Desc
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc("shorturl_pgxpool_"+name, help, nil, nil)
+	}
+
+	return &PoolCollector{
+		stat:              pool.Stat,
+		acquiredConns:     desc("acquired_conns", "Connections of the pool in use."),
+		idleConns:         desc("idle_conns", "Idle connections of the pool."),
+		constructingConns: desc("constructing_conns", "Connections of the pool being opened."),
+		totalConns:        desc("total_conns", "Connections of the pool, open and being opened."),
+		maxConns:          desc("max_conns", "Max connections of the pool."),
+		acquires:          desc("acquires_total", "Connections acquired from the pool."),
+		acquireSeconds:    desc("acquire_duration_seconds_total", "Time spent acquiring connections from the pool."),
+		canceledAcquires:  desc("canceled_acquires_total", "Acquires cancelled by their context."),
+		emptyAcquires:     desc("empty_acquires_total", "Acquires that waited for a connection because none was idle."),
+		newConns:          desc("new_conns_total", "Connections opened by the pool."),
+		lifetimeDestroyed: desc("max_lifetime_destroys_total", "Connections closed for reaching the max lifetime."),
+		idleDestroyed:     desc("max_idle_destroys_total", "Connections closed for being idle for too long."),
+	}
+}
+
+func (c *PoolCollector) Describe(descs chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, descs)
+}
+
+func (c *PoolCollector) Collect(metrics chan<- prometheus.Metric) {
+	stat := c.stat()
+
+	gauge := func(desc *prometheus.Desc, value int32) {
+		metrics <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		metrics <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, stat.AcquiredConns())
+	gauge(c.idleConns, stat.IdleConns())
+	gauge(c.constructingConns, stat.ConstructingConns())
+	gauge(c.totalConns, stat.TotalConns())
+	gauge(c.maxConns, stat.MaxConns())
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.acquireSeconds, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.newConns, float64(stat.NewConnsCount()))
+	counter(c.lifetimeDestroyed, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.idleDestroyed, float64(stat.MaxIdleDestroyCount()))
+}
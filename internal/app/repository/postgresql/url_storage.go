@@ -2,11 +2,43 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// active filters out expired links that the reaper has not deleted yet
+// and links taken down by their owner
+const active = "(expires = 0 OR expires > extract(epoch from now())) AND deleted = 0 AND NOT disabled"
+
+// insertLink stores a link in a single statement. The link an owner gets back for
+// a long link is marked reverse and links_owner_long_idx keeps one per long link,
+// so a concurrent create of the same long link fails on the index. An inactive
+// link gives its long link away first, the count makes that happen before the
+// insert. An expired link that is not reaped yet gives its short link away
+// together with its history, so the versions of the new link start at one
+const insertLink = `WITH released AS (
+		UPDATE links SET reverse = false
+		WHERE long = $2 AND owner = $7 AND reverse AND short <> $1 AND NOT $5 AND NOT (` + active + `)
+		RETURNING id
+	), forgotten AS (
+		DELETE FROM link_history h USING links l
+		WHERE h.short = $1 AND l.short = $1 AND l.expires <> 0 AND l.expires <= extract(epoch from now())
+	)
+	INSERT INTO links(short, long, added, expires, alias, redirect, owner, reverse)
+	SELECT $1, $2, $3::bigint, $4::bigint, $5, $6::smallint, $7, NOT $5 FROM (SELECT count(*) FROM released) AS released_count
+	ON CONFLICT (short) DO UPDATE SET long = EXCLUDED.long, added = EXCLUDED.added, expires = EXCLUDED.expires, alias = EXCLUDED.alias,
+	redirect = EXCLUDED.redirect, owner = EXCLUDED.owner, reverse = EXCLUDED.reverse, deleted = 0, disabled = false
+	WHERE links.expires <> 0 AND links.expires <= extract(epoch from now())`
+
+// ownerLongIndex is the unique index of the reverse links
+const ownerLongIndex = "links_owner_long_idx"
+
+// selectLink reads a link by its short link
+const selectLink = "SELECT long, added, expires, redirect, deleted, disabled, owner FROM links WHERE short = $1"
+
 type UrlStorage struct {
 	Pool domain.IPool
 }
@@ -17,35 +49,245 @@ func NewUrlStorage(pool domain.IPool) *UrlStorage {
 	}
 }
 
+// AddUrl stores the link in a single round trip, an alias does not take part
+// in the long link deduplication
 func (s *UrlStorage) AddUrl(ctx context.Context, urlData domain.URLData) error {
-	tx, err := s.Pool.Begin(ctx)
+	tag, err := s.Pool.Exec(ctx, insertLink, urlData.URLShort, urlData.LongURL, urlData.AddedAt, urlData.ExpiresAt, urlData.Alias, urlData.Redirect, urlData.Owner)
 	if err != nil {
+		if violates(err, ownerLongIndex) {
+			return domain.ErrorLongExists
+		}
 		return err
 	}
-	defer tx.Rollback(ctx)
+	if tag.RowsAffected() == 0 {
+		return domain.ErrorShortExists
+	}
 
-	_, err = tx.Exec(ctx, "INSERT INTO links(short, long, added) VALUES ($1, $2, $3)", urlData.URLShort, urlData.LongURL, urlData.AddedAt)
+	return nil
+}
+
+// AddUrls stores the batch in one transaction with a statement per step
+// instead of a statement per link. A long link of the batch created by
+// a concurrent request fails the whole batch with ErrorLongExists
+func (s *UrlStorage) AddUrls(ctx context.Context, batch []domain.URLData) ([]error, error) {
+	errs := make([]error, len(batch))
+
+	// links colliding inside the batch fail before they reach the database
+	longs := make(map[string]bool)
+	shorts := make(map[string]bool)
+	var keys, links []string
+	for i, urlData := range batch {
+		key := urlData.Owner + "|" + urlData.LongURL
+		switch {
+		case !urlData.Alias && longs[key]:
+			errs[i] = domain.ErrorLongExists
+		case shorts[urlData.URLShort]:
+			errs[i] = domain.ErrorShortExists
+		case !urlData.Alias:
+			longs[key] = true
+			keys = append(keys, key)
+			links = append(links, urlData.LongURL)
+			fallthrough
+		default:
+			shorts[urlData.URLShort] = true
+		}
+	}
+
+	tx, err := s.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(keys) > 0 {
+		// inactive links give their long links away, as in AddUrl
+		_, err = tx.Exec(ctx, "UPDATE links SET reverse = false WHERE long = ANY($1::text[]) AND owner || '|' || long = ANY($2::text[]) AND reverse AND NOT ("+active+")", links, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		exists, err := s.set(ctx, tx, "SELECT owner || '|' || long FROM links WHERE long = ANY($1::text[]) AND owner || '|' || long = ANY($2::text[]) AND reverse", links, keys)
+		if err != nil {
+			return nil, err
+		}
+		for i, urlData := range batch {
+			if errs[i] == nil && !urlData.Alias && exists[urlData.Owner+"|"+urlData.LongURL] {
+				errs[i] = domain.ErrorLongExists
+			}
+		}
+	}
+
+	var (
+		short, long, owner []string
+		added, expires     []int64
+		alias, reverse     []bool
+		redirect           []int16
+	)
+	for i, urlData := range batch {
+		if errs[i] != nil {
+			continue
+		}
+		short = append(short, urlData.URLShort)
+		long = append(long, urlData.LongURL)
+		added = append(added, urlData.AddedAt)
+		expires = append(expires, urlData.ExpiresAt)
+		alias = append(alias, urlData.Alias)
+		redirect = append(redirect, int16(urlData.Redirect))
+		owner = append(owner, urlData.Owner)
+		reverse = append(reverse, !urlData.Alias)
+	}
+
+	if len(short) > 0 {
+		// an expired link that is not reaped yet gives its short link away with its history
+		inserted, err := s.set(ctx, tx, `WITH forgotten AS (
+				DELETE FROM link_history h USING links l
+				WHERE h.short = l.short AND l.short = ANY($1::text[]) AND l.expires <> 0 AND l.expires <= extract(epoch from now())
+			)
+			INSERT INTO links(short, long, added, expires, alias, redirect, owner, reverse)
+			SELECT * FROM unnest($1::text[], $2::text[], $3::bigint[], $4::bigint[], $5::boolean[], $6::smallint[], $7::text[], $8::boolean[])
+			ON CONFLICT (short) DO UPDATE SET long = EXCLUDED.long, added = EXCLUDED.added, expires = EXCLUDED.expires, alias = EXCLUDED.alias,
+			redirect = EXCLUDED.redirect, owner = EXCLUDED.owner, reverse = EXCLUDED.reverse, deleted = 0, disabled = false
+			WHERE links.expires <> 0 AND links.expires <= extract(epoch from now())
+			RETURNING short`,
+			short, long, added, expires, alias, redirect, owner, reverse)
+		if violates(err, ownerLongIndex) {
+			return nil, domain.ErrorLongExists
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, urlData := range batch {
+			if errs[i] == nil && !inserted[urlData.URLShort] {
+				errs[i] = domain.ErrorShortExists
+			}
+		}
 	}
 
 	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// ExportUrls streams the links in the order they were added, a single
+// query reads a consistent snapshot
+func (s *UrlStorage) ExportUrls(ctx context.Context, fn func(domain.URLData) error) error {
+	rows, err := s.Pool.Query(ctx, "SELECT short, long, added, expires, alias, redirect, deleted, disabled, owner FROM links ORDER BY id")
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
-	return nil
+	for rows.Next() {
+		var urlData domain.URLData
+		if err := rows.Scan(&urlData.URLShort, &urlData.LongURL, &urlData.AddedAt, &urlData.ExpiresAt, &urlData.Alias, &urlData.Redirect, &urlData.DeletedAt, &urlData.Disabled, &urlData.Owner); err != nil {
+			return err
+		}
+		if err := fn(urlData); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
 }
 
-func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
+// ImportUrls writes the batch with a single insert, the batch must not
+// contain the same short link twice. An imported link is the reverse link
+// of its long link unless a stored link or an earlier one of the batch is
+func (s *UrlStorage) ImportUrls(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+	var (
+		short, long, owner      []string
+		added, expires, deleted []int64
+		alias, disabled, first  []bool
+		redirect                []int16
+	)
+	seen := make(map[string]bool)
+	for _, urlData := range batch {
+		key := urlData.Owner + "|" + urlData.LongURL
+		first = append(first, !urlData.Alias && !seen[key])
+		if !urlData.Alias {
+			seen[key] = true
+		}
+
+		short = append(short, urlData.URLShort)
+		long = append(long, urlData.LongURL)
+		added = append(added, urlData.AddedAt)
+		expires = append(expires, urlData.ExpiresAt)
+		alias = append(alias, urlData.Alias)
+		redirect = append(redirect, int16(urlData.Redirect))
+		deleted = append(deleted, urlData.DeletedAt)
+		disabled = append(disabled, urlData.Disabled)
+		owner = append(owner, urlData.Owner)
+	}
+
 	tx, err := s.Pool.Begin(ctx)
 	if err != nil {
-		return &domain.URLLong{}, err
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
+	conflict := "DO NOTHING"
+	if overwrite {
+		// the history belongs to the replaced link
+		_, err = tx.Exec(ctx, "DELETE FROM link_history WHERE short = ANY($1::text[])", short)
+		if err != nil {
+			return nil, err
+		}
+		conflict = `DO UPDATE SET long = EXCLUDED.long, added = EXCLUDED.added, expires = EXCLUDED.expires, alias = EXCLUDED.alias,
+			redirect = EXCLUDED.redirect, deleted = EXCLUDED.deleted, disabled = EXCLUDED.disabled, owner = EXCLUDED.owner, reverse = EXCLUDED.reverse`
+	}
+
+	stored, err := s.set(ctx, tx, `INSERT INTO links(short, long, added, expires, alias, redirect, deleted, disabled, owner, reverse)
+		SELECT u.short, u.long, u.added, u.expires, u.alias, u.redirect, u.deleted, u.disabled, u.owner,
+			u.first AND NOT EXISTS (SELECT 1 FROM links l WHERE l.long = u.long AND l.owner = u.owner AND l.reverse AND l.short <> u.short)
+		FROM unnest($1::text[], $2::text[], $3::bigint[], $4::bigint[], $5::boolean[], $6::smallint[], $7::bigint[], $8::boolean[], $9::text[], $10::boolean[])
+			AS u(short, long, added, expires, alias, redirect, deleted, disabled, owner, first)
+		ON CONFLICT (short) `+conflict+` RETURNING short`,
+		short, long, added, expires, alias, redirect, deleted, disabled, owner, first)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(batch))
+	for i, urlData := range batch {
+		if !stored[urlData.URLShort] {
+			errs[i] = domain.ErrorShortExists
+		}
+	}
+
+	return errs, nil
+}
+
+// set collects the values of a single text column query
+func (s *UrlStorage) set(ctx context.Context, tx pgx.Tx, sql string, args ...interface{}) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := make(map[string]bool)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		set[value] = true
+	}
+
+	return set, rows.Err()
+}
+
+func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
 	urllong := &domain.URLLong{}
-	if err := tx.QueryRow(ctx, "SELECT long, added FROM links WHERE short = $1", shortUrl).Scan(&urllong.LongURL, &urllong.AddedAt); err != nil {
+	if err := s.Pool.QueryRow(ctx, selectLink, shortUrl).Scan(&urllong.LongURL, &urllong.AddedAt, &urllong.ExpiresAt, &urllong.Redirect, &urllong.DeletedAt, &urllong.Disabled, &urllong.Owner); err != nil {
 		if err == pgx.ErrNoRows {
 			return &domain.URLLong{}, domain.ErrorLinkNotFound
 		} else {
@@ -53,10 +295,166 @@ func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLo
 		}
 	}
 
+	return urllong, nil
+}
+
+func (s *UrlStorage) GetUrls(ctx context.Context, shorts []string) (map[string]domain.URLLong, error) {
+	rows, err := s.Pool.Query(ctx, "SELECT short, long, added, expires, redirect, deleted, disabled, owner FROM links WHERE short = ANY($1::text[])", shorts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]domain.URLLong, len(shorts))
+	for rows.Next() {
+		var short string
+		var urllong domain.URLLong
+		if err := rows.Scan(&short, &urllong.LongURL, &urllong.AddedAt, &urllong.ExpiresAt, &urllong.Redirect, &urllong.DeletedAt, &urllong.Disabled, &urllong.Owner); err != nil {
+			return nil, err
+		}
+		found[short] = urllong
+	}
+
+	return found, rows.Err()
+}
+
+func (s *UrlStorage) GetShort(ctx context.Context, longUrl string, owner string) (string, error) {
+	var shortUrl string
+	if err := s.Pool.QueryRow(ctx, "SELECT short FROM links WHERE long = $1 AND owner = $2 AND reverse AND "+active, longUrl, owner).Scan(&shortUrl); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", domain.ErrorLinkNotFound
+		} else {
+			return "", err
+		}
+	}
+
+	return shortUrl, nil
+}
+
+func (s *UrlStorage) DeleteExpired(ctx context.Context, now int64) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, "DELETE FROM links WHERE expires <> 0 AND expires <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (s *UrlStorage) DeleteUrl(ctx context.Context, shortUrl string, deletedAt int64) error {
+	return s.update(ctx, "UPDATE links SET deleted = $2 WHERE short = $1", shortUrl, deletedAt)
+}
+
+func (s *UrlStorage) RestoreUrl(ctx context.Context, shortUrl string) error {
+	return s.update(ctx, "UPDATE links SET deleted = 0 WHERE short = $1 AND deleted <> 0", shortUrl)
+}
+
+func (s *UrlStorage) DisableUrl(ctx context.Context, shortUrl string, disabled bool) error {
+	return s.update(ctx, "UPDATE links SET disabled = $2 WHERE short = $1 AND deleted = 0", shortUrl, disabled)
+}
+
+func (s *UrlStorage) PurgeDeleted(ctx context.Context, before int64) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, "DELETE FROM links WHERE deleted <> 0 AND deleted <= $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Ping fails when the pool can not acquire a connection before ctx is done
+func (s *UrlStorage) Ping(ctx context.Context) error {
+	return s.Pool.Ping(ctx)
+}
+
+func (s *UrlStorage) UpdateUrl(ctx context.Context, shortUrl string, update domain.URLUpdate) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the row lock keeps concurrent updates from taking the same version,
+	// an expired link is not retargeted, its short link is up for the taking
+	var previous string
+	if err := tx.QueryRow(ctx, "SELECT long FROM links WHERE short = $1 AND deleted = 0 AND (expires = 0 OR expires > $2) FOR UPDATE",
+		shortUrl, update.ChangedAt).Scan(&previous); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.ErrorLinkNotFound
+		} else {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO link_history(short, version, long, changed_at, actor)
+		SELECT $1, count(*) + 1, $2, $3, $4 FROM link_history WHERE short = $1`,
+		shortUrl, previous, update.ChangedAt, update.Actor)
+	if err != nil {
+		return err
+	}
+
+	// a retargeted link is not reused for its old long link any more
+	_, err = tx.Exec(ctx, "UPDATE links SET long = $2, reverse = false WHERE short = $1", shortUrl, update.LongURL)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
-		return &domain.URLLong{}, err
+		return err
 	}
 
-	return urllong, nil
+	return nil
+}
+
+func (s *UrlStorage) GetHistory(ctx context.Context, shortUrl string) ([]domain.URLVersion, error) {
+	// the link comes with a row of nulls if it has no history
+	rows, err := s.Pool.Query(ctx, `SELECT h.version, h.long, h.changed_at, h.actor FROM links l
+		LEFT JOIN link_history h ON h.short = l.short
+		WHERE l.short = $1 ORDER BY h.version`, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found bool
+	history := []domain.URLVersion{}
+	for rows.Next() {
+		found = true
+		var version, changedAt *int64
+		var long, actor *string
+		if err := rows.Scan(&version, &long, &changedAt, &actor); err != nil {
+			return nil, err
+		}
+		if version == nil {
+			continue
+		}
+		history = append(history, domain.URLVersion{Version: *version, LongURL: *long, ChangedAt: *changedAt, Actor: *actor})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, domain.ErrorLinkNotFound
+	}
+
+	return history, nil
+}
+
+// update runs a single link update, ErrorLinkNotFound if no link matches
+func (s *UrlStorage) update(ctx context.Context, sql string, args ...interface{}) error {
+	tag, err := s.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrorLinkNotFound
+	}
+
+	return nil
+}
+
+// violates reports the unique violation of the index
+func violates(err error, index string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == index
 }
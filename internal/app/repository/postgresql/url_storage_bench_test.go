@@ -0,0 +1,109 @@
+package postgresql
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the benchmarks need a database, pg_url=user:pass@localhost:5432/db go test -bench . -run ^$
+func benchPool(b *testing.B) *pgxpool.Pool {
+	url := os.Getenv("pg_url")
+	if url == "" {
+		b.Skip("pg_url is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := NewPool(ctx, "postgres://"+url, PoolConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(pool.Close)
+
+	migrator, err := NewMigrator(pool)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		b.Fatal(err)
+	}
+
+	return pool
+}
+
+func BenchmarkGetUrl(b *testing.B) {
+	pool := benchPool(b)
+	ctx := context.Background()
+
+	urlData := domain.NewURLData("bench__Lin4", "https://example.com/bench", 1686557090)
+	urlData.Alias = true
+	if _, err := pool.Exec(ctx, "DELETE FROM links WHERE short = $1", urlData.URLShort); err != nil {
+		b.Fatal(err)
+	}
+	urlStorage := NewUrlStorage(pool)
+	if err := urlStorage.AddUrl(ctx, *urlData); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { pool.Exec(ctx, "DELETE FROM links WHERE short = $1", urlData.URLShort) })
+
+	// the transaction around a single statement every query used to run in,
+	// the statement and the scan are the ones of GetUrl
+	b.Run("tx", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				tx, err := pool.Begin(ctx)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				var urllong domain.URLLong
+				err = tx.QueryRow(ctx, selectLink, urlData.URLShort).Scan(&urllong.LongURL, &urllong.AddedAt, &urllong.ExpiresAt, &urllong.Redirect, &urllong.DeletedAt, &urllong.Disabled, &urllong.Owner)
+				if err == nil {
+					err = tx.Commit(ctx)
+				}
+				tx.Rollback(ctx)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+
+	b.Run("direct", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := urlStorage.GetUrl(ctx, urlData.URLShort); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkAddUrl(b *testing.B) {
+	pool := benchPool(b)
+	ctx := context.Background()
+	urlStorage := NewUrlStorage(pool)
+
+	b.Cleanup(func() { pool.Exec(ctx, "DELETE FROM links WHERE long = 'https://example.com/bench/add'") })
+
+	var added int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			short := "bench_add_" + strconv.FormatInt(atomic.AddInt64(&added, 1), 10)
+			urlData := domain.NewURLData(short, "https://example.com/bench/add", 1686557090)
+			urlData.Alias = true
+			if err := urlStorage.AddUrl(ctx, *urlData); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
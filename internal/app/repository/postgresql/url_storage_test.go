@@ -107,23 +107,54 @@ func TestAddUrl_Success(t *testing.T) {
 	pool := mocks.NewMockIPool(ctrl)
 	urlStorage := NewUrlStorage(pool)
 
-	mockTx := mocks.NewMockTx(ctrl)
+	urldata := domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)
 
-	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+	// a single statement, without a transaction
+	pool.EXPECT().Exec(gomock.Any(), insertLink, urldata.URLShort, urldata.LongURL, urldata.AddedAt, urldata.ExpiresAt, false, urldata.Redirect, urldata.Owner).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
 
-	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, nil)
+	err := urlStorage.AddUrl(ctx, *urldata)
 
-	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
+	require.NoError(t, err)
+}
 
-	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+func TestAddUrl_ExecError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), insertLink, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, ErrExec)
+
+	urldata := domain.NewURLData(Tests[2].Short, Tests[2].Long, Tests[2].AddedAt)
+	err := urlStorage.AddUrl(ctx, *urldata)
+
+	require.Error(t, err)
+	require.True(t, errors.Is(err, Tests[2].Error))
+}
+
+func TestAddUrl_Alias(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	urldata := domain.NewURLData("summer_sale", Tests[0].Long, Tests[0].AddedAt)
+	urldata.Alias = true
+
+	pool.EXPECT().Exec(gomock.Any(), insertLink, urldata.URLShort, urldata.LongURL, urldata.AddedAt, urldata.ExpiresAt, true, urldata.Redirect, urldata.Owner).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
 
-	urldata := domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)
 	err := urlStorage.AddUrl(ctx, *urldata)
 
 	require.NoError(t, err)
 }
 
-func TestAddUrl_BeginError(t *testing.T) {
+func TestAddUrl_LongExists(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -132,16 +163,33 @@ func TestAddUrl_BeginError(t *testing.T) {
 	pool := mocks.NewMockIPool(ctrl)
 	urlStorage := NewUrlStorage(pool)
 
-	pool.EXPECT().Begin(gomock.Any()).Return(nil, ErrBegin)
+	// the reverse link of the long link is active
+	pool.EXPECT().Exec(gomock.Any(), insertLink, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: "23505", ConstraintName: ownerLongIndex})
 
-	urldata := domain.NewURLData(Tests[1].Short, Tests[1].Long, Tests[1].AddedAt)
+	urldata := domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)
 	err := urlStorage.AddUrl(ctx, *urldata)
 
-	require.Error(t, err)
-	require.True(t, errors.Is(err, Tests[1].Error))
+	require.Equal(t, domain.ErrorLongExists, err)
 }
 
-func TestAddUrl_ExecError(t *testing.T) {
+func TestAddUrl_ShortExists(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), insertLink, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.NewCommandTag("INSERT 0 0"), nil)
+
+	urldata := domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)
+	err := urlStorage.AddUrl(ctx, *urldata)
+
+	require.Equal(t, domain.ErrorShortExists, err)
+}
+
+func TestAddUrls(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -152,20 +200,36 @@ func TestAddUrl_ExecError(t *testing.T) {
 
 	mockTx := mocks.NewMockTx(ctrl)
 
+	batch := []domain.URLData{
+		*domain.NewURLData("S0mE__Lin1", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin2", "example.com", 1686557090),
+		{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.com"}, Alias: true},
+		{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.org"}, Alias: true},
+		*domain.NewURLData("S0mE__Lin5", "example.net", 1686557090),
+		*domain.NewURLData("S0mE__Lin6", "example.io", 1686557090),
+	}
+
 	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
 
-	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, ErrExec)
+	keys := []string{"|example.com", "|example.net", "|example.io"}
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), []string{"example.com", "example.net", "example.io"}, keys).Return(pgconn.CommandTag{}, nil)
+
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), []string{"example.com", "example.net", "example.io"}, keys).Return(rows(ctrl, "|example.net"), nil)
+
+	// the aliases are not reverse links
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), []string{"S0mE__Lin1", "summer_sale", "S0mE__Lin6"}, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), []bool{true, false, true}).Return(rows(ctrl, "S0mE__Lin1", "summer_sale"), nil)
+
+	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
 
 	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
 
-	urldata := domain.NewURLData(Tests[2].Short, Tests[2].Long, Tests[2].AddedAt)
-	err := urlStorage.AddUrl(ctx, *urldata)
+	errs, err := urlStorage.AddUrls(ctx, batch)
 
-	require.Error(t, err)
-	require.True(t, errors.Is(err, Tests[2].Error))
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, domain.ErrorLongExists, nil, domain.ErrorShortExists, domain.ErrorLongExists, domain.ErrorShortExists}, errs)
 }
 
-func TestAddUrl_CommitError(t *testing.T) {
+func TestAddUrls_LongRace(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -178,22 +242,21 @@ func TestAddUrl_CommitError(t *testing.T) {
 
 	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
 
-	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, nil)
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, nil)
+
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(rows(ctrl), nil)
 
-	mockTx.EXPECT().Commit(gomock.Any()).Return(ErrCommit)
+	// created by a concurrent request after the check
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, &pgconn.PgError{Code: "23505", ConstraintName: ownerLongIndex})
 
 	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
 
-	urldata := domain.NewURLData(Tests[3].Short, Tests[3].Long, Tests[3].AddedAt)
-	err := urlStorage.AddUrl(ctx, *urldata)
+	_, err := urlStorage.AddUrls(ctx, []domain.URLData{*domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)})
 
-	require.Error(t, err)
-	require.True(t, errors.Is(err, Tests[3].Error))
+	require.Equal(t, domain.ErrorLongExists, err)
 }
 
-// GetUrl
-
-func TestGetUrl_Success(t *testing.T) {
+func TestAddUrls_QueryError(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -203,31 +266,93 @@ func TestGetUrl_Success(t *testing.T) {
 	urlStorage := NewUrlStorage(pool)
 
 	mockTx := mocks.NewMockTx(ctrl)
-	mockRow := mocks.NewMockRow(ctrl)
 
 	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
 
-	mockTx.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[4].Short).Return(mockRow)
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pgconn.CommandTag{}, nil)
 
-	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
-		long := args[0].(*string)
-		added := args[1].(*int64)
-		*long = Tests[4].Long
-		*added = Tests[4].AddedAt
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, ErrExec)
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	_, err := urlStorage.AddUrls(ctx, []domain.URLData{*domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)})
+
+	require.Equal(t, ErrExec, err)
+}
+
+func TestExportUrls_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRows := mocks.NewMockRows(ctrl)
+
+	want := domain.URLData{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: Tests[0].Long, AddedAt: Tests[0].AddedAt, Redirect: 301, Owner: "0123456789abcdef"}, Alias: true}
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any()).Return(mockRows, nil)
+
+	gomock.InOrder(
+		mockRows.EXPECT().Next().Return(true),
+		mockRows.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+			*args[0].(*string) = want.URLShort
+			*args[1].(*string) = want.LongURL
+			*args[2].(*int64) = want.AddedAt
+			*args[4].(*bool) = want.Alias
+			*args[5].(*int) = want.Redirect
+			*args[8].(*string) = want.Owner
+			return nil
+		}),
+		mockRows.EXPECT().Next().Return(false),
+	)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	var exported []domain.URLData
+	err := urlStorage.ExportUrls(ctx, func(urlData domain.URLData) error {
+		exported = append(exported, urlData)
 		return nil
 	})
 
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLData{want}, exported)
+}
+
+func TestImportUrls_Skip(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockTx := mocks.NewMockTx(ctrl)
+
+	batch := []domain.URLData{
+		*domain.NewURLData("S0mE__Lin1", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin2", "example.com", 1686557090),
+	}
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	// only the first link of a long link may be its reverse link
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), []string{"S0mE__Lin1", "S0mE__Lin2"}, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), []bool{true, false}).Return(rows(ctrl, "S0mE__Lin2"), nil)
+
 	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
 
 	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
 
-	long, err := urlStorage.GetUrl(ctx, Tests[4].Short)
+	errs, err := urlStorage.ImportUrls(ctx, batch, false)
 
 	require.NoError(t, err)
-	require.Equal(t, Tests[4].Long, long.LongURL)
+	require.Equal(t, []error{domain.ErrorShortExists, nil}, errs)
 }
 
-func TestGetUrl_BeginError(t *testing.T) {
+func TestImportUrls_Overwrite(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -236,15 +361,48 @@ func TestGetUrl_BeginError(t *testing.T) {
 	pool := mocks.NewMockIPool(ctrl)
 	urlStorage := NewUrlStorage(pool)
 
-	pool.EXPECT().Begin(ctx).Return(nil, ErrBegin)
+	mockTx := mocks.NewMockTx(ctrl)
 
-	_, err := urlStorage.GetUrl(ctx, Tests[5].Short)
+	batch := []domain.URLData{*domain.NewURLData(Tests[0].Short, Tests[0].Long, Tests[0].AddedAt)}
 
-	require.Error(t, err)
-	require.True(t, errors.Is(err, Tests[5].Error))
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), []string{Tests[0].Short}).Return(pgconn.CommandTag{}, nil)
+
+	mockTx.EXPECT().Query(gomock.Any(), gomock.Any(), []string{Tests[0].Short}, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(rows(ctrl, Tests[0].Short), nil)
+
+	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	errs, err := urlStorage.ImportUrls(ctx, batch, true)
+
+	require.NoError(t, err)
+	require.Equal(t, []error{nil}, errs)
 }
 
-func TestGetUrl_ScanError(t *testing.T) {
+// rows returns the values as the rows of a single text column
+func rows(ctrl *gomock.Controller, values ...string) *mocks.MockRows {
+	mockRows := mocks.NewMockRows(ctrl)
+
+	for _, value := range values {
+		value := value
+		mockRows.EXPECT().Next().Return(true)
+		mockRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+			*args[0].(*string) = value
+			return nil
+		})
+	}
+	mockRows.EXPECT().Next().Return(false)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	return mockRows
+}
+
+// GetUrl
+
+func TestGetUrl_Success(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -253,16 +411,38 @@ func TestGetUrl_ScanError(t *testing.T) {
 	pool := mocks.NewMockIPool(ctrl)
 	urlStorage := NewUrlStorage(pool)
 
-	mockTx := mocks.NewMockTx(ctrl)
 	mockRow := mocks.NewMockRow(ctrl)
 
-	pool.EXPECT().Begin(ctx).Return(mockTx, nil)
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[4].Short).Return(mockRow)
 
-	mockTx.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[6].Short).Return(mockRow)
+	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+		long := args[0].(*string)
+		added := args[1].(*int64)
+		*long = Tests[4].Long
+		*added = Tests[4].AddedAt
+		return nil
+	})
 
-	mockRow.EXPECT().Scan(gomock.Any()).Return(ErrScan)
+	long, err := urlStorage.GetUrl(ctx, Tests[4].Short)
 
-	mockTx.EXPECT().Rollback(ctx).Return(nil)
+	require.NoError(t, err)
+	require.Equal(t, Tests[4].Long, long.LongURL)
+}
+
+func TestGetUrl_ScanError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[6].Short).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).Return(ErrScan)
 
 	_, err := urlStorage.GetUrl(ctx, Tests[6].Short)
 
@@ -279,12 +459,9 @@ func TestGetUrl_LinkNotFoundError(t *testing.T) {
 	pool := mocks.NewMockIPool(ctrl)
 	urlStorage := NewUrlStorage(pool)
 
-	mockTx := mocks.NewMockTx(ctrl)
 	mockRow := mocks.NewMockRow(ctrl)
 
-	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
-
-	mockTx.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[7].Short).Return(mockRow)
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[7].Short).Return(mockRow)
 
 	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
 		long := args[0].(*string)
@@ -294,15 +471,246 @@ func TestGetUrl_LinkNotFoundError(t *testing.T) {
 		return pgx.ErrNoRows
 	})
 
-	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
-
 	_, err := urlStorage.GetUrl(ctx, Tests[7].Short)
 
 	require.Error(t, err)
 	require.True(t, errors.Is(err, Tests[7].Error))
 }
 
-func TestGetUrl_CommitError(t *testing.T) {
+// GetShort
+
+func TestGetUrls_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRows := mocks.NewMockRows(ctrl)
+
+	shorts := []string{Tests[4].Short, "Missing123"}
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any(), shorts).Return(mockRows, nil)
+
+	gomock.InOrder(
+		mockRows.EXPECT().Next().Return(true),
+		mockRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+			*args[0].(*string) = Tests[4].Short
+			*args[1].(*string) = Tests[4].Long
+			*args[2].(*int64) = Tests[4].AddedAt
+			return nil
+		}),
+		mockRows.EXPECT().Next().Return(false),
+	)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	found, err := urlStorage.GetUrls(ctx, shorts)
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]domain.URLLong{Tests[4].Short: {LongURL: Tests[4].Long, AddedAt: Tests[4].AddedAt}}, found)
+}
+
+func TestGetUrls_QueryError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, ErrExec)
+
+	_, err := urlStorage.GetUrls(ctx, []string{Tests[4].Short})
+
+	require.Equal(t, ErrExec, err)
+}
+
+func TestGetShort_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[4].Long, gomock.Any()).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+		short := args[0].(*string)
+		*short = Tests[4].Short
+		return nil
+	})
+
+	short, err := urlStorage.GetShort(ctx, Tests[4].Long, "")
+
+	require.NoError(t, err)
+	require.Equal(t, Tests[4].Short, short)
+}
+
+func TestGetShort_LinkNotFoundError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[4].Long, gomock.Any()).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).Return(pgx.ErrNoRows)
+
+	_, err := urlStorage.GetShort(ctx, Tests[4].Long, "")
+
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestGetShort_ScanError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[0].Long, "").Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).Return(ErrScan)
+
+	_, err := urlStorage.GetShort(ctx, Tests[0].Long, "")
+
+	require.True(t, errors.Is(err, ErrScan))
+}
+
+// DeleteExpired
+
+func TestDeleteExpired_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].AddedAt).Return(pgconn.NewCommandTag("DELETE 2"), nil)
+
+	deleted, err := urlStorage.DeleteExpired(ctx, Tests[0].AddedAt)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+}
+
+func TestDeleteExpired_ExecError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].AddedAt).Return(pgconn.CommandTag{}, ErrExec)
+
+	_, err := urlStorage.DeleteExpired(ctx, Tests[0].AddedAt)
+
+	require.True(t, errors.Is(err, ErrExec))
+}
+
+func TestDeleteUrl_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].Short, Tests[0].AddedAt).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+	err := urlStorage.DeleteUrl(ctx, Tests[0].Short, Tests[0].AddedAt)
+
+	require.NoError(t, err)
+}
+
+func TestRestoreUrl_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].Short).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+
+	err := urlStorage.RestoreUrl(ctx, Tests[0].Short)
+
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestDisableUrl_ExecError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].Short, true).Return(pgconn.CommandTag{}, ErrExec)
+
+	err := urlStorage.DisableUrl(ctx, Tests[0].Short, true)
+
+	require.Equal(t, ErrExec, err)
+}
+
+func TestPurgeDeleted_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].AddedAt).Return(pgconn.NewCommandTag("DELETE 3"), nil)
+
+	purged, err := urlStorage.PurgeDeleted(ctx, Tests[0].AddedAt)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+}
+
+func TestPing_Error(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	pool.EXPECT().Ping(ctx).Return(context.DeadlineExceeded)
+
+	err := urlStorage.Ping(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestUpdateUrl_Success(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -314,24 +722,135 @@ func TestGetUrl_CommitError(t *testing.T) {
 	mockTx := mocks.NewMockTx(ctrl)
 	mockRow := mocks.NewMockRow(ctrl)
 
+	update := domain.URLUpdate{LongURL: "example.org", ChangedAt: Tests[0].AddedAt, Actor: "127.0.0.1"}
+
 	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
 
-	mockTx.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[8].Short).Return(mockRow)
+	mockTx.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[0].Short, update.ChangedAt).Return(mockRow)
 
 	mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...interface{}) error {
-		long := args[0].(*string)
-		added := args[1].(*int64)
-		*long = Tests[8].Long
-		*added = Tests[8].AddedAt
+		*args[0].(*string) = Tests[0].Long
 		return nil
 	})
 
-	mockTx.EXPECT().Commit(gomock.Any()).Return(ErrCommit)
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].Short, Tests[0].Long, update.ChangedAt, update.Actor).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+	mockTx.EXPECT().Exec(gomock.Any(), gomock.Any(), Tests[0].Short, update.LongURL).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+	mockTx.EXPECT().Commit(gomock.Any()).Return(nil)
 
 	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
 
-	_, err := urlStorage.GetUrl(ctx, Tests[8].Short)
+	err := urlStorage.UpdateUrl(ctx, Tests[0].Short, update)
 
-	require.Error(t, err)
-	require.True(t, errors.Is(err, Tests[8].Error))
+	require.NoError(t, err)
+}
+
+func TestUpdateUrl_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockTx := mocks.NewMockTx(ctrl)
+	mockRow := mocks.NewMockRow(ctrl)
+
+	pool.EXPECT().Begin(gomock.Any()).Return(mockTx, nil)
+
+	mockTx.EXPECT().QueryRow(gomock.Any(), gomock.Any(), Tests[0].Short, int64(0)).Return(mockRow)
+
+	mockRow.EXPECT().Scan(gomock.Any()).Return(pgx.ErrNoRows)
+
+	mockTx.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+	err := urlStorage.UpdateUrl(ctx, Tests[0].Short, domain.URLUpdate{LongURL: "example.org"})
+
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestGetHistory_Success(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRows := mocks.NewMockRows(ctrl)
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any(), Tests[0].Short).Return(mockRows, nil)
+
+	gomock.InOrder(
+		mockRows.EXPECT().Next().Return(true),
+		mockRows.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(args ...interface{}) error {
+			version, long, changedAt, actor := int64(1), Tests[0].Long, Tests[0].AddedAt+1, "127.0.0.1"
+			*args[0].(**int64) = &version
+			*args[1].(**string) = &long
+			*args[2].(**int64) = &changedAt
+			*args[3].(**string) = &actor
+			return nil
+		}),
+		mockRows.EXPECT().Next().Return(false),
+	)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	history, err := urlStorage.GetHistory(ctx, Tests[0].Short)
+
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLVersion{{Version: 1, LongURL: Tests[0].Long, ChangedAt: Tests[0].AddedAt + 1, Actor: "127.0.0.1"}}, history)
+}
+
+func TestGetHistory_Empty(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRows := mocks.NewMockRows(ctrl)
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any(), Tests[0].Short).Return(mockRows, nil)
+
+	// the link without history comes with a row of nulls
+	gomock.InOrder(
+		mockRows.EXPECT().Next().Return(true),
+		mockRows.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+		mockRows.EXPECT().Next().Return(false),
+	)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	history, err := urlStorage.GetHistory(ctx, Tests[0].Short)
+
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
+func TestGetHistory_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := mocks.NewMockIPool(ctrl)
+	urlStorage := NewUrlStorage(pool)
+
+	mockRows := mocks.NewMockRows(ctrl)
+
+	pool.EXPECT().Query(gomock.Any(), gomock.Any(), Tests[0].Short).Return(mockRows, nil)
+
+	mockRows.EXPECT().Next().Return(false)
+	mockRows.EXPECT().Err().Return(nil)
+	mockRows.EXPECT().Close()
+
+	_, err := urlStorage.GetHistory(ctx, Tests[0].Short)
+
+	require.Equal(t, domain.ErrorLinkNotFound, err)
 }
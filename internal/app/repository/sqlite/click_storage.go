@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+const insertClick = "INSERT INTO clicks(short, clicked_at, referrer, user_agent, ip) VALUES ($1, $2, $3, $4, $5)"
+
+type ClickStorage struct {
+	DB *sql.DB
+}
+
+func NewClickStorage(db *sql.DB) *ClickStorage {
+	return &ClickStorage{
+		DB: db,
+	}
+}
+
+func (s *ClickStorage) AddClick(ctx context.Context, click domain.Click) error {
+	_, err := s.DB.ExecContext(ctx, insertClick, click.Short, click.At, click.Referrer, click.UserAgent, click.IP)
+	return err
+}
+
+// AddClicks writes the batch in one transaction, so the file is synced once per batch
+func (s *ClickStorage) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	return inTx(ctx, s.DB, func(tx *sql.Tx) error {
+		insert, err := tx.PrepareContext(ctx, insertClick)
+		if err != nil {
+			return err
+		}
+		defer insert.Close()
+
+		for _, click := range clicks {
+			if _, err := insert.ExecContext(ctx, click.Short, click.At, click.Referrer, click.UserAgent, click.IP); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *ClickStorage) GetStats(ctx context.Context, request domain.StatsRequest) (*domain.Stats, error) {
+	stats := &domain.Stats{Short: request.Short}
+	if err := s.DB.QueryRowContext(ctx, "SELECT count(*) FROM clicks WHERE short = $1", request.Short).Scan(&stats.Total); err != nil {
+		return &domain.Stats{}, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT $2 + (clicked_at - $2) / $4 * $4 AS start, count(*) FROM clicks
+		WHERE short = $1 AND clicked_at >= $2 AND clicked_at < $3 GROUP BY start ORDER BY start`,
+		request.Short, request.From, request.To, request.Interval)
+	if err != nil {
+		return &domain.Stats{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket domain.Bucket
+		if err := rows.Scan(&bucket.Start, &bucket.Count); err != nil {
+			return &domain.Stats{}, err
+		}
+		stats.Buckets = append(stats.Buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return &domain.Stats{}, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStorage_GetStats(t *testing.T) {
+	ctx := context.Background()
+
+	clickStorage := NewClickStorage(open(t))
+
+	require.NoError(t, clickStorage.AddClick(ctx, domain.Click{Short: "GoodLink12", At: 1010, Client: domain.Client{IP: "127.0.0.1"}}))
+	require.NoError(t, clickStorage.AddClicks(ctx, []domain.Click{
+		{Short: "GoodLink12", At: 1020},
+		{Short: "GoodLink12", At: 1250},
+		{Short: "GoodLink12", At: 5000},
+		{Short: "OtherLink1", At: 1030},
+	}))
+
+	stats, err := clickStorage.GetStats(ctx, domain.StatsRequest{Short: "GoodLink12", From: 1000, To: 1300, Interval: 100})
+
+	require.NoError(t, err)
+	require.Equal(t, &domain.Stats{
+		Short:   "GoodLink12",
+		Total:   4,
+		Buckets: []domain.Bucket{{Start: 1000, Count: 2}, {Start: 1200, Count: 1}},
+	}, stats)
+}
+
+func TestClickStorage_AddClicksError(t *testing.T) {
+	ctx := context.Background()
+
+	db := open(t)
+	clickStorage := NewClickStorage(db)
+	require.NoError(t, db.Close())
+
+	require.Error(t, clickStorage.AddClicks(ctx, []domain.Click{{Short: "GoodLink12", At: 1010}}))
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// open returns a database with the schema in a file of the test
+func open(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "shorturl.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, Setup(context.Background(), db))
+	return db
+}
+
+func TestSetup_Again(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shorturl.db")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, Setup(ctx, db))
+	require.NoError(t, NewKeyStorage(db).AddKey(ctx, testKey))
+	require.NoError(t, db.Close())
+
+	// the second start keeps the data
+	db, err = Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Setup(ctx, db))
+
+	key, err := NewKeyStorage(db).GetKey(ctx, testKey.ID)
+	require.NoError(t, err)
+	require.Equal(t, testKey, *key)
+}
+
+func TestOpen_Error(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing", "shorturl.db"))
+	require.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+type KeyStorage struct {
+	DB *sql.DB
+}
+
+func NewKeyStorage(db *sql.DB) *KeyStorage {
+	return &KeyStorage{
+		DB: db,
+	}
+}
+
+func (s *KeyStorage) AddKey(ctx context.Context, key domain.APIKey) error {
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO api_keys(id, name, hash, created, revoked) VALUES ($1, $2, $3, $4, $5)", key.ID, key.Name, key.Hash, key.CreatedAt, key.RevokedAt)
+	return err
+}
+
+func (s *KeyStorage) GetKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	key := &domain.APIKey{ID: id}
+	if err := s.DB.QueryRowContext(ctx, "SELECT name, hash, created, revoked FROM api_keys WHERE id = $1", id).Scan(&key.Name, &key.Hash, &key.CreatedAt, &key.RevokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &domain.APIKey{}, domain.ErrorKeyNotFound
+		} else {
+			return &domain.APIKey{}, err
+		}
+	}
+
+	return key, nil
+}
+
+func (s *KeyStorage) RevokeKey(ctx context.Context, id string, revokedAt int64) error {
+	return s.update(ctx, "UPDATE api_keys SET revoked = $2 WHERE id = $1 AND revoked = 0", id, revokedAt)
+}
+
+func (s *KeyStorage) RotateKey(ctx context.Context, id string, hash string) error {
+	return s.update(ctx, "UPDATE api_keys SET hash = $2 WHERE id = $1 AND revoked = 0", id, hash)
+}
+
+// update runs a single key update, ErrorKeyNotFound if no active key matches
+func (s *KeyStorage) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrorKeyNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = domain.APIKey{ID: "0123456789abcdef", Name: "ci", Hash: "hash", CreatedAt: 1686557090}
+
+func TestKeyStorage(t *testing.T) {
+	ctx := context.Background()
+
+	keyStorage := NewKeyStorage(open(t))
+
+	require.NoError(t, keyStorage.AddKey(ctx, testKey))
+	require.Error(t, keyStorage.AddKey(ctx, testKey))
+
+	require.NoError(t, keyStorage.RotateKey(ctx, testKey.ID, "rotated"))
+	key, err := keyStorage.GetKey(ctx, testKey.ID)
+	require.NoError(t, err)
+	require.Equal(t, "rotated", key.Hash)
+
+	require.NoError(t, keyStorage.RevokeKey(ctx, testKey.ID, 1686557091))
+	key, err = keyStorage.GetKey(ctx, testKey.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1686557091), key.RevokedAt)
+
+	// a revoked key can not change any more
+	require.Equal(t, domain.ErrorKeyNotFound, keyStorage.RevokeKey(ctx, testKey.ID, 1686557092))
+	require.Equal(t, domain.ErrorKeyNotFound, keyStorage.RotateKey(ctx, testKey.ID, "hash"))
+
+	_, err = keyStorage.GetKey(ctx, "fedcba9876543210")
+	require.Equal(t, domain.ErrorKeyNotFound, err)
+}
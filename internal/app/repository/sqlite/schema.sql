@@ -0,0 +1,49 @@
+-- the schema of the sqlite database, the tables match the ones of the pgx
+-- database after its migrations. Every statement runs on every start
+CREATE TABLE IF NOT EXISTS links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short TEXT NOT NULL UNIQUE,
+    "long" TEXT NOT NULL,
+    added INTEGER NOT NULL DEFAULT 0,
+    expires INTEGER NOT NULL DEFAULT 0,
+    alias BOOLEAN NOT NULL DEFAULT false,
+    redirect INTEGER NOT NULL DEFAULT 0,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    disabled BOOLEAN NOT NULL DEFAULT false,
+    owner TEXT NOT NULL DEFAULT '',
+    -- the link an owner gets back when the long link is created again
+    reverse BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS links_owner_long_idx ON links (owner, "long") WHERE reverse;
+CREATE INDEX IF NOT EXISTS links_expires_idx ON links (expires) WHERE expires <> 0;
+CREATE INDEX IF NOT EXISTS links_deleted_idx ON links (deleted) WHERE deleted <> 0;
+
+CREATE TABLE IF NOT EXISTS link_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short TEXT NOT NULL REFERENCES links (short) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    "long" TEXT NOT NULL,
+    changed_at INTEGER NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    UNIQUE (short, version)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    hash TEXT NOT NULL,
+    created INTEGER NOT NULL,
+    revoked INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short TEXT NOT NULL,
+    clicked_at INTEGER NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_short_at_idx ON clicks (short, clicked_at);
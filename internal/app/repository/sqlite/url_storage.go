@@ -0,0 +1,324 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+// active filters out expired links that the reaper has not deleted yet
+// and links taken down by their owner, $1 is the current unix time
+const active = "(expires = 0 OR expires > $1) AND deleted = 0 AND NOT disabled"
+
+// selectLink reads a link by its short link
+const selectLink = `SELECT "long", added, expires, redirect, deleted, disabled, owner FROM links WHERE short = $1`
+
+// UrlStorage keeps the links in the tables of the pgx database, the link an owner
+// gets back for a long link is marked reverse like there. The statements of the
+// single connection never run concurrently, so the checks of a link and its
+// insert run in a transaction without locks
+type UrlStorage struct {
+	DB *sql.DB
+}
+
+func NewUrlStorage(db *sql.DB) *UrlStorage {
+	return &UrlStorage{
+		DB: db,
+	}
+}
+
+func (s *UrlStorage) AddUrl(ctx context.Context, urlData domain.URLData) error {
+	return inTx(ctx, s.DB, func(tx *sql.Tx) error {
+		return s.add(ctx, tx, urlData, time.Now().Unix())
+	})
+}
+
+// AddUrls stores the batch in one transaction, the links of the batch
+// collide with the ones before them like separate AddUrl calls
+func (s *UrlStorage) AddUrls(ctx context.Context, batch []domain.URLData) ([]error, error) {
+	errs := make([]error, len(batch))
+	now := time.Now().Unix()
+	err := inTx(ctx, s.DB, func(tx *sql.Tx) error {
+		for i, urlData := range batch {
+			errs[i] = s.add(ctx, tx, urlData, now)
+			if errs[i] != domain.ErrorLongExists && errs[i] != domain.ErrorShortExists && errs[i] != nil {
+				return errs[i]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// add stores the link in the transaction. An inactive link gives its long link
+// away and an expired link that is not reaped yet gives its short link away
+func (s *UrlStorage) add(ctx context.Context, tx *sql.Tx, urlData domain.URLData, now int64) error {
+	if !urlData.Alias {
+		var short string
+		var isActive bool
+		err := tx.QueryRowContext(ctx, `SELECT short, `+active+` FROM links WHERE owner = $2 AND "long" = $3 AND reverse`,
+			now, urlData.Owner, urlData.LongURL).Scan(&short, &isActive)
+		switch {
+		case err == nil && isActive:
+			return domain.ErrorLongExists
+		case err == nil:
+			if _, err := tx.ExecContext(ctx, "UPDATE links SET reverse = false WHERE short = $1", short); err != nil {
+				return err
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+	}
+
+	var expires int64
+	err := tx.QueryRowContext(ctx, "SELECT expires FROM links WHERE short = $1", urlData.URLShort).Scan(&expires)
+	switch {
+	case err == nil && (expires == 0 || expires > now):
+		return domain.ErrorShortExists
+	case err == nil:
+		// the history of the expired link goes with it
+		if _, err := tx.ExecContext(ctx, "DELETE FROM links WHERE short = $1", urlData.URLShort); err != nil {
+			return err
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO links(short, "long", added, expires, alias, redirect, owner, reverse)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		urlData.URLShort, urlData.LongURL, urlData.AddedAt, urlData.ExpiresAt, urlData.Alias, urlData.Redirect, urlData.Owner, !urlData.Alias)
+	return err
+}
+
+func (s *UrlStorage) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
+	longUrl := &domain.URLLong{}
+	if err := s.DB.QueryRowContext(ctx, selectLink, shortUrl).Scan(&longUrl.LongURL, &longUrl.AddedAt, &longUrl.ExpiresAt, &longUrl.Redirect, &longUrl.DeletedAt, &longUrl.Disabled, &longUrl.Owner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &domain.URLLong{}, domain.ErrorLinkNotFound
+		} else {
+			return &domain.URLLong{}, err
+		}
+	}
+
+	return longUrl, nil
+}
+
+// GetUrls reads the links one by one on the single connection, a statement
+// per link costs no round trip in an embedded database
+func (s *UrlStorage) GetUrls(ctx context.Context, shorts []string) (map[string]domain.URLLong, error) {
+	found := make(map[string]domain.URLLong, len(shorts))
+	for _, short := range shorts {
+		long, err := s.GetUrl(ctx, short)
+		if err == domain.ErrorLinkNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[short] = *long
+	}
+
+	return found, nil
+}
+
+func (s *UrlStorage) GetShort(ctx context.Context, longUrl string, owner string) (string, error) {
+	var shortUrl string
+	if err := s.DB.QueryRowContext(ctx, `SELECT short FROM links WHERE owner = $2 AND "long" = $3 AND reverse AND `+active,
+		time.Now().Unix(), owner, longUrl).Scan(&shortUrl); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrorLinkNotFound
+		} else {
+			return "", err
+		}
+	}
+
+	return shortUrl, nil
+}
+
+func (s *UrlStorage) DeleteExpired(ctx context.Context, now int64) (int64, error) {
+	return s.delete(ctx, "DELETE FROM links WHERE expires <> 0 AND expires <= $1", now)
+}
+
+func (s *UrlStorage) DeleteUrl(ctx context.Context, shortUrl string, deletedAt int64) error {
+	return s.update(ctx, "UPDATE links SET deleted = $2 WHERE short = $1", shortUrl, deletedAt)
+}
+
+func (s *UrlStorage) RestoreUrl(ctx context.Context, shortUrl string) error {
+	return s.update(ctx, "UPDATE links SET deleted = 0 WHERE short = $1 AND deleted <> 0", shortUrl)
+}
+
+func (s *UrlStorage) DisableUrl(ctx context.Context, shortUrl string, disabled bool) error {
+	return s.update(ctx, "UPDATE links SET disabled = $2 WHERE short = $1 AND deleted = 0", shortUrl, disabled)
+}
+
+func (s *UrlStorage) UpdateUrl(ctx context.Context, shortUrl string, update domain.URLUpdate) error {
+	return inTx(ctx, s.DB, func(tx *sql.Tx) error {
+		// an expired link is not retargeted, its short link is up for the taking
+		var previous string
+		if err := tx.QueryRowContext(ctx, `SELECT "long" FROM links WHERE short = $1 AND deleted = 0 AND (expires = 0 OR expires > $2)`,
+			shortUrl, update.ChangedAt).Scan(&previous); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.ErrorLinkNotFound
+			} else {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO link_history(short, version, "long", changed_at, actor)
+			SELECT $1, count(*) + 1, $2, $3, $4 FROM link_history WHERE short = $1`,
+			shortUrl, previous, update.ChangedAt, update.Actor)
+		if err != nil {
+			return err
+		}
+
+		// a retargeted link is not reused for its old long link any more
+		_, err = tx.ExecContext(ctx, `UPDATE links SET "long" = $2, reverse = false WHERE short = $1`, shortUrl, update.LongURL)
+		return err
+	})
+}
+
+func (s *UrlStorage) GetHistory(ctx context.Context, shortUrl string) ([]domain.URLVersion, error) {
+	// the link comes with a row of nulls if it has no history
+	rows, err := s.DB.QueryContext(ctx, `SELECT h.version, h."long", h.changed_at, h.actor FROM links l
+		LEFT JOIN link_history h ON h.short = l.short
+		WHERE l.short = $1 ORDER BY h.version`, shortUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found bool
+	history := []domain.URLVersion{}
+	for rows.Next() {
+		found = true
+		var version, changedAt sql.NullInt64
+		var long, actor sql.NullString
+		if err := rows.Scan(&version, &long, &changedAt, &actor); err != nil {
+			return nil, err
+		}
+		if !version.Valid {
+			continue
+		}
+		history = append(history, domain.URLVersion{Version: version.Int64, LongURL: long.String, ChangedAt: changedAt.Int64, Actor: actor.String})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, domain.ErrorLinkNotFound
+	}
+
+	return history, nil
+}
+
+// ExportUrls streams the links in the order they were added, the connection
+// is held until the last link, so fn must not use the storage
+func (s *UrlStorage) ExportUrls(ctx context.Context, fn func(domain.URLData) error) error {
+	rows, err := s.DB.QueryContext(ctx, `SELECT short, "long", added, expires, alias, redirect, deleted, disabled, owner FROM links ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlData domain.URLData
+		if err := rows.Scan(&urlData.URLShort, &urlData.LongURL, &urlData.AddedAt, &urlData.ExpiresAt, &urlData.Alias, &urlData.Redirect, &urlData.DeletedAt, &urlData.Disabled, &urlData.Owner); err != nil {
+			return err
+		}
+		if err := fn(urlData); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ImportUrls writes the batch in one transaction. An imported link is the
+// reverse link of its long link unless a stored link or an earlier one of the batch is
+func (s *UrlStorage) ImportUrls(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+	first := make([]bool, len(batch))
+	seen := make(map[string]bool)
+	for i, urlData := range batch {
+		key := urlData.Owner + "|" + urlData.LongURL
+		first[i] = !urlData.Alias && !seen[key]
+		if !urlData.Alias {
+			seen[key] = true
+		}
+	}
+
+	errs := make([]error, len(batch))
+	err := inTx(ctx, s.DB, func(tx *sql.Tx) error {
+		for i, urlData := range batch {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM links WHERE short = $1)", urlData.URLShort).Scan(&exists); err != nil {
+				return err
+			}
+			if exists && !overwrite {
+				errs[i] = domain.ErrorShortExists
+				continue
+			}
+			if exists {
+				// the history belongs to the replaced link
+				if _, err := tx.ExecContext(ctx, "DELETE FROM links WHERE short = $1", urlData.URLShort); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.ExecContext(ctx, `INSERT INTO links(short, "long", added, expires, alias, redirect, deleted, disabled, owner, reverse)
+				SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9,
+					$10 AND NOT EXISTS (SELECT 1 FROM links WHERE "long" = $2 AND owner = $9 AND reverse)`,
+				urlData.URLShort, urlData.LongURL, urlData.AddedAt, urlData.ExpiresAt, urlData.Alias, urlData.Redirect, urlData.DeletedAt, urlData.Disabled, urlData.Owner, first[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+func (s *UrlStorage) PurgeDeleted(ctx context.Context, before int64) (int64, error) {
+	return s.delete(ctx, "DELETE FROM links WHERE deleted <> 0 AND deleted <= $1", before)
+}
+
+func (s *UrlStorage) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+// update runs a single link update, ErrorLinkNotFound if no link matches
+func (s *UrlStorage) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrorLinkNotFound
+	}
+
+	return nil
+}
+
+// delete removes the links the query matches with their history and returns their number
+func (s *UrlStorage) delete(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	result, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
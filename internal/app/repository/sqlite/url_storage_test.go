@@ -0,0 +1,262 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddUrl_LongExists(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+
+	first := domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)
+	require.NoError(t, urlStorage.AddUrl(ctx, *first))
+	require.Equal(t, domain.ErrorLongExists, urlStorage.AddUrl(ctx, *domain.NewURLData("An0ther_L1", "example.com", 1686557091)))
+	require.Equal(t, domain.ErrorShortExists, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "google.com", 1686557091)))
+
+	// an alias does not take part in the deduplication
+	alias := domain.NewURLData("summer_sale", "example.com", 1686557091)
+	alias.Alias = true
+	require.NoError(t, urlStorage.AddUrl(ctx, *alias))
+
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, first.URLShort, short)
+
+	// another owner gets its own link for the same long link
+	owned := domain.NewURLData("Sec0nd_Lin", "example.com", 1686557090)
+	owned.Owner = "0123456789abcdef"
+	require.NoError(t, urlStorage.AddUrl(ctx, *owned))
+	short, err = urlStorage.GetShort(ctx, "example.com", owned.Owner)
+	require.NoError(t, err)
+	require.Equal(t, owned.URLShort, short)
+}
+
+func TestAddUrl_ReplacesInactive(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+
+	expired := domain.NewURLData("summer_sale", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "summer_sale", domain.URLUpdate{LongURL: "example.com", ChangedAt: 1686557090}))
+
+	// the expired link gives its short link away with its history
+	fresh := domain.NewURLData("summer_sale", "google.com", 1686557092)
+	require.NoError(t, urlStorage.AddUrl(ctx, *fresh))
+	history, err := urlStorage.GetHistory(ctx, "summer_sale")
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	// a deleted link gives its long link away
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "summer_sale", 1686557093))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "google.com", 1686557094)))
+	short, err := urlStorage.GetShort(ctx, "google.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "S0mE__Lin4", short)
+
+	// and keeps it away when it is restored
+	require.NoError(t, urlStorage.RestoreUrl(ctx, "summer_sale"))
+	short, err = urlStorage.GetShort(ctx, "google.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "S0mE__Lin4", short)
+}
+
+func TestAddUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin0", "example.net", 1686557090)))
+
+	batch := []domain.URLData{
+		*domain.NewURLData("S0mE__Lin1", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin2", "example.com", 1686557090),
+		*domain.NewURLData("S0mE__Lin1", "example.org", 1686557090),
+		*domain.NewURLData("S0mE__Lin3", "example.net", 1686557090),
+	}
+
+	errs, err := urlStorage.AddUrls(ctx, batch)
+
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, domain.ErrorLongExists, domain.ErrorShortExists, domain.ErrorLongExists}, errs)
+
+	found, err := urlStorage.GetUrls(ctx, []string{"S0mE__Lin1", "S0mE__Lin2", "S0mE__Lin0"})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, "example.com", found["S0mE__Lin1"].LongURL)
+}
+
+func TestDeleteUrl_Restore(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)))
+
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "S0mE__Lin4", 1686557091))
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, int64(1686557091), long.DeletedAt)
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DisableUrl(ctx, "S0mE__Lin4", true))
+
+	require.NoError(t, urlStorage.RestoreUrl(ctx, "S0mE__Lin4"))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.RestoreUrl(ctx, "S0mE__Lin4"))
+	require.NoError(t, urlStorage.DisableUrl(ctx, "S0mE__Lin4", true))
+	long, err = urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.True(t, long.Disabled)
+
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.DeleteUrl(ctx, "N0t_F0uNd1", 1686557091))
+	_, err = urlStorage.GetUrl(ctx, "N0t_F0uNd1")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestDeleteExpired_Purge(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+
+	expired := domain.NewURLData("Expired123", "example.com", 1)
+	expired.ExpiresAt = 2
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("Old___Lin4", "example.org", 1686557090)))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("New___Lin4", "example.net", 1686557090)))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "Old___Lin4", 1000))
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "New___Lin4", 2000))
+
+	deleted, err := urlStorage.DeleteExpired(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	purged, err := urlStorage.PurgeDeleted(ctx, 1500)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), purged)
+
+	found, err := urlStorage.GetUrls(ctx, []string{"Expired123", "Old___Lin4", "New___Lin4"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Contains(t, found, "New___Lin4")
+}
+
+func TestUpdateUrl_History(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("S0mE__Lin4", "example.com", 1686557090)))
+
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.org", ChangedAt: 1686557091, Actor: "127.0.0.1"}))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.net", ChangedAt: 1686557092}))
+
+	long, err := urlStorage.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.net", long.LongURL)
+
+	history, err := urlStorage.GetHistory(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLVersion{
+		{Version: 1, LongURL: "example.com", ChangedAt: 1686557091, Actor: "127.0.0.1"},
+		{Version: 2, LongURL: "example.org", ChangedAt: 1686557092},
+	}, history)
+
+	// the old long link gets a new short link
+	_, err = urlStorage.GetShort(ctx, "example.com", "")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+
+	require.NoError(t, urlStorage.DeleteUrl(ctx, "S0mE__Lin4", 1686557093))
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.UpdateUrl(ctx, "S0mE__Lin4", domain.URLUpdate{LongURL: "example.com"}))
+
+	_, err = urlStorage.PurgeDeleted(ctx, 1686557093)
+	require.NoError(t, err)
+	_, err = urlStorage.GetHistory(ctx, "S0mE__Lin4")
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+}
+
+func TestExportImportUrls(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+	require.NoError(t, urlStorage.UpdateUrl(ctx, "F1rst_Lin4", domain.URLUpdate{LongURL: "example.io", ChangedAt: 1686557091}))
+
+	stored := []domain.URLData{
+		{URLShort: "F1rst_Lin4", URLLong: domain.URLLong{LongURL: "example.org", AddedAt: 1686557090, DeletedAt: 1686557095}},
+		{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "example.org", AddedAt: 1686557090, Redirect: 301, Disabled: true}, Alias: true},
+		{URLShort: "Sec0nd_Lin", URLLong: domain.URLLong{LongURL: "example.io", AddedAt: 1686557090, Owner: "0123456789abcdef"}},
+	}
+
+	errs, err := urlStorage.ImportUrls(ctx, stored[:1], false)
+	require.NoError(t, err)
+	require.Equal(t, []error{domain.ErrorShortExists}, errs)
+
+	errs, err = urlStorage.ImportUrls(ctx, stored, true)
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, nil, nil}, errs)
+
+	// the history belongs to the replaced link
+	history, err := urlStorage.GetHistory(ctx, "F1rst_Lin4")
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	var exported []domain.URLData
+	err = urlStorage.ExportUrls(ctx, func(urlData domain.URLData) error {
+		exported = append(exported, urlData)
+		return nil
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, stored, exported)
+}
+
+func TestImportUrls_KeepsReverse(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+	require.NoError(t, urlStorage.AddUrl(ctx, *domain.NewURLData("F1rst_Lin4", "example.com", 1686557090)))
+
+	errs, err := urlStorage.ImportUrls(ctx, []domain.URLData{
+		*domain.NewURLData("Sec0nd_Lin", "example.com", 1686557090),
+		*domain.NewURLData("Th1rd_Lin4", "example.org", 1686557090),
+		*domain.NewURLData("F0urth_Li4", "example.org", 1686557090),
+	}, false)
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, nil, nil}, errs)
+
+	// the stored link and the first one of the batch are reused for their long links
+	short, err := urlStorage.GetShort(ctx, "example.com", "")
+	require.NoError(t, err)
+	require.Equal(t, "F1rst_Lin4", short)
+	short, err = urlStorage.GetShort(ctx, "example.org", "")
+	require.NoError(t, err)
+	require.Equal(t, "Th1rd_Lin4", short)
+}
+
+func TestPing(t *testing.T) {
+	db := open(t)
+	urlStorage := NewUrlStorage(db)
+
+	require.NoError(t, urlStorage.Ping(context.Background()))
+	require.NoError(t, db.Close())
+	require.Error(t, urlStorage.Ping(context.Background()))
+}
+
+func TestUpdateUrl_Expired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := NewUrlStorage(open(t))
+
+	expired := domain.NewURLData("Expired123", "example.com", 1686557090)
+	expired.ExpiresAt = 1686557091
+	require.NoError(t, urlStorage.AddUrl(ctx, *expired))
+
+	// the history of an expired link is dropped with it, so it is not retargeted
+	require.Equal(t, domain.ErrorLinkNotFound, urlStorage.UpdateUrl(ctx, "Expired123", domain.URLUpdate{LongURL: "example.org", ChangedAt: 1686557091}))
+
+	long, err := urlStorage.GetUrl(ctx, "Expired123")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+}
@@ -0,0 +1,21 @@
+package service
+
+import "strings"
+
+const AliasMaxLength = 32
+
+// CheckAlias reports whether the alias can be used as a short link,
+// it must consist of the same characters as the generated ones
+func CheckAlias(alias string) bool {
+	if alias == "" || len(alias) > AliasMaxLength {
+		return false
+	}
+
+	for _, char := range alias {
+		if !strings.ContainsRune(Alphabet, char) {
+			return false
+		}
+	}
+
+	return true
+}
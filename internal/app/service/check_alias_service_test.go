@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAlias(t *testing.T) {
+	var tests = []struct {
+		Title   string
+		Alias   string
+		Correct bool
+	}{
+		{"Correct alias", "summer_sale", true},
+		{"Correct alias w/ digits", "Sale2023", true},
+		{"Correct alias of max length", "abcdefghijklmnopqrstuvwxyz012345", true},
+		{"Invalid alias: empty", "", false},
+		{"Invalid alias: too long", "abcdefghijklmnopqrstuvwxyz0123456", false},
+		{"Invalid alias: dash", "summer-sale", false},
+		{"Invalid alias: slash", "summer/sale", false},
+		{"Invalid alias: non latin", "распродажа", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Title, func(t *testing.T) {
+			require.Equal(t, test.Correct, CheckAlias(test.Alias))
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package service
+
+import "net/http"
+
+// CheckRedirect reports whether the status can be used to redirect to the long link,
+// zero is accepted and stands for the server default
+func CheckRedirect(status int) bool {
+	switch status {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
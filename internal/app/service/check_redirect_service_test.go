@@ -0,0 +1,31 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRedirect(t *testing.T) {
+	var tests = []struct {
+		Title   string
+		Status  int
+		Correct bool
+	}{
+		{"Server default", 0, true},
+		{"Moved Permanently", http.StatusMovedPermanently, true},
+		{"Found", http.StatusFound, true},
+		{"Temporary Redirect", http.StatusTemporaryRedirect, true},
+		{"Permanent Redirect", http.StatusPermanentRedirect, true},
+		{"Invalid status: See Other", http.StatusSeeOther, false},
+		{"Invalid status: OK", http.StatusOK, false},
+		{"Invalid status: negative", -301, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Title, func(t *testing.T) {
+			require.Equal(t, test.Correct, CheckRedirect(test.Status))
+		})
+	}
+}
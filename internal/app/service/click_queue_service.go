@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClickQueueService keeps click recording off the resolve path, clicks are
+// buffered in a bounded queue and written to the storage in batches by workers.
+// A full queue drops the click instead of blocking the resolve, the lost clicks
+// are exported as shorturl_clicks_dropped_total and shorturl_clicks_failed_total.
+type ClickQueueService struct {
+	Storage   domain.IClickStorage
+	Queue     chan domain.Click
+	BatchSize int
+	Interval  time.Duration // the longest time a click waits for its batch
+	Workers   int
+	Mux       *sync.RWMutex
+	closed    bool
+	done      *sync.WaitGroup
+	stop      context.Context // canceled when Close gives up waiting for the workers
+	cancel    context.CancelFunc
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+func NewClickQueueService(storage domain.IClickStorage, size, batchSize, workers int, interval time.Duration, registerer prometheus.Registerer) *ClickQueueService {
+	s := &ClickQueueService{
+		Storage:   storage,
+		Queue:     make(chan domain.Click, size),
+		BatchSize: batchSize,
+		Interval:  interval,
+		Workers:   workers,
+		Mux:       new(sync.RWMutex),
+		done:      new(sync.WaitGroup),
+	}
+	s.stop, s.cancel = context.WithCancel(context.Background())
+
+	registerer.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "shorturl_clicks_dropped_total",
+			Help: "Clicks lost because the click queue was full or closed.",
+		}, func() float64 { return float64(s.Dropped()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "shorturl_clicks_failed_total",
+			Help: "Queued clicks the click storage failed to write.",
+		}, func() float64 { return float64(s.Failed()) }),
+	)
+
+	return s
+}
+
+// Start runs the workers, they exit once the queue is closed and drained
+func (s *ClickQueueService) Start() {
+	for i := 0; i < s.Workers; i++ {
+		s.done.Add(1)
+		go s.work()
+	}
+}
+
+// Close stops accepting clicks and waits until the queued ones are written.
+// Once ctx is done the workers stop writing, the clicks they have not written
+// count as failed. Either way the workers are gone when Close returns,
+// so the storage may be closed after it
+func (s *ClickQueueService) Close(ctx context.Context) error {
+	s.Mux.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.Queue)
+	}
+	s.Mux.Unlock()
+
+	flushed := make(chan struct{})
+	go func() {
+		s.done.Wait()
+		close(flushed)
+	}()
+
+	var err error
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		s.cancel()
+		<-flushed
+		err = ctx.Err()
+	}
+	s.cancel()
+
+	if dropped, failed := s.Dropped(), s.Failed(); dropped != 0 || failed != 0 {
+		log.Printf("Click queue closed, dropped %d and failed to write %d clicks\n", dropped, failed)
+	}
+	return err
+}
+
+func (s *ClickQueueService) AddClick(ctx context.Context, click domain.Click) error {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return nil
+	}
+
+	select {
+	case s.Queue <- click:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+func (s *ClickQueueService) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	for _, click := range clicks {
+		if err := s.AddClick(ctx, click); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStats reads from the storage, clicks still in the queue are not counted
+func (s *ClickQueueService) GetStats(ctx context.Context, request domain.StatsRequest) (*domain.Stats, error) {
+	return s.Storage.GetStats(ctx, request)
+}
+
+// Dropped is the number of clicks lost to a full or closed queue
+func (s *ClickQueueService) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Failed is the number of clicks the storage did not accept
+func (s *ClickQueueService) Failed() int64 {
+	return s.failed.Load()
+}
+
+func (s *ClickQueueService) work() {
+	defer s.done.Done()
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	batch := make([]domain.Click, 0, s.BatchSize)
+	for {
+		select {
+		case click, ok := <-s.Queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= s.BatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		case <-s.stop.Done():
+			// the queue is closed before the stop, so the drain ends
+			failed := int64(len(batch))
+			for range s.Queue {
+				failed++
+			}
+			s.failed.Add(failed)
+			return
+		}
+	}
+}
+
+func (s *ClickQueueService) flush(batch []domain.Click) {
+	if len(batch) == 0 {
+		return
+	}
+	if s.stop.Err() != nil {
+		s.failed.Add(int64(len(batch)))
+		return
+	}
+
+	if err := s.Storage.AddClicks(s.stop, batch); err != nil {
+		s.failed.Add(int64(len(batch)))
+		log.Printf("Click batch error: %v\n", err)
+	}
+}
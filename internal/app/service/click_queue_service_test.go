@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickQueue_Batch(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockIClickStorage(ctrl)
+	queue := NewClickQueueService(storage, 10, 2, 1, time.Hour, prometheus.NewRegistry())
+
+	written := make(chan int, 2)
+	storage.EXPECT().AddClicks(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, clicks []domain.Click) error {
+		written <- len(clicks)
+		return nil
+	}).Times(2)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.AddClick(ctx, domain.Click{Short: "GoodLink12", At: int64(i)}))
+	}
+	queue.Start()
+
+	// the full batch goes first, the rest is flushed on close
+	require.Equal(t, 2, <-written)
+	require.NoError(t, queue.Close(ctx))
+	require.Equal(t, 1, <-written)
+	require.Equal(t, int64(0), queue.Dropped())
+}
+
+func TestClickQueue_Interval(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockIClickStorage(ctrl)
+	queue := NewClickQueueService(storage, 10, 100, 1, 10*time.Millisecond, prometheus.NewRegistry())
+	queue.Start()
+	defer queue.Close(ctx)
+
+	written := make(chan int, 1)
+	storage.EXPECT().AddClicks(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, clicks []domain.Click) error {
+		written <- len(clicks)
+		return nil
+	})
+
+	require.NoError(t, queue.AddClick(ctx, domain.Click{Short: "GoodLink12"}))
+
+	select {
+	case n := <-written:
+		require.Equal(t, 1, n)
+	case <-time.After(time.Second):
+		t.Fatal("batch is not flushed after the interval")
+	}
+}
+
+func TestClickQueue_Dropped(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockIClickStorage(ctrl)
+	registry := prometheus.NewRegistry()
+	queue := NewClickQueueService(storage, 1, 10, 1, time.Hour, registry)
+
+	require.NoError(t, queue.AddClick(ctx, domain.Click{Short: "GoodLink12"}))
+	require.NoError(t, queue.AddClick(ctx, domain.Click{Short: "GoodLink12"}))
+	require.Equal(t, int64(1), queue.Dropped())
+
+	storage.EXPECT().AddClicks(gomock.Any(), gomock.Len(1)).Return(ErrorDBShutdown)
+
+	queue.Start()
+	require.NoError(t, queue.Close(ctx))
+	require.Equal(t, int64(1), queue.Failed())
+
+	// closed queue drops instead of panicking on send
+	require.NoError(t, queue.AddClick(ctx, domain.Click{Short: "GoodLink12"}))
+	require.Equal(t, int64(2), queue.Dropped())
+
+	expected := `
+		# HELP shorturl_clicks_dropped_total Clicks lost because the click queue was full or closed.
+		# TYPE shorturl_clicks_dropped_total counter
+		shorturl_clicks_dropped_total 2
+		# HELP shorturl_clicks_failed_total Queued clicks the click storage failed to write.
+		# TYPE shorturl_clicks_failed_total counter
+		shorturl_clicks_failed_total 1
+	`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+func TestClickQueue_CloseTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockIClickStorage(ctrl)
+	queue := NewClickQueueService(storage, 10, 1, 1, time.Hour, prometheus.NewRegistry())
+
+	writing := make(chan struct{})
+	storage.EXPECT().AddClicks(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, clicks []domain.Click) error {
+		close(writing)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	require.NoError(t, queue.AddClick(context.Background(), domain.Click{Short: "GoodLink12"}))
+	require.NoError(t, queue.AddClick(context.Background(), domain.Click{Short: "GoodLink12"}))
+	queue.Start()
+	<-writing
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the workers are stopped, the batch being written and the queued click are lost
+	require.Equal(t, context.DeadlineExceeded, queue.Close(ctx))
+	require.Equal(t, int64(2), queue.Failed())
+	require.NoError(t, queue.Close(context.Background()))
+}
+
+func TestClickQueue_GetStats(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockIClickStorage(ctrl)
+	queue := NewClickQueueService(storage, 10, 10, 1, time.Hour, prometheus.NewRegistry())
+
+	request := domain.StatsRequest{Short: "GoodLink12", From: 1000, To: 1300, Interval: 100}
+	storage.EXPECT().GetStats(ctx, request).Return(&domain.Stats{Short: "GoodLink12", Total: 3}, nil)
+
+	stats, err := queue.GetStats(ctx, request)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.Total)
+}
@@ -8,37 +8,40 @@ import (
 )
 
 const (
-	Length           = 10
+	Length           = 10 // default length of the generated short links
 	LowercaseLetters = "abcdefghijklmnopqrstuvwxyz"
 	UppercaseLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	Numbers          = "0123456789"
 	Underscore       = "_"
+	Alphabet         = LowercaseLetters + UppercaseLetters + Numbers + Underscore
 )
 
 type GenerateLinkService struct {
-	Mux *sync.Mutex
+	Mux    *sync.Mutex
+	Length int
 }
 
 func NewGenerateLinkService() *GenerateLinkService {
 	return &GenerateLinkService{
-		Mux: new(sync.Mutex),
+		Mux:    new(sync.Mutex),
+		Length: Length,
 	}
 }
 
-func (s *GenerateLinkService) GenerateShortLink() (string, int64) {
+func (s *GenerateLinkService) GenerateShortLink() (string, int64, error) {
 	s.Mux.Lock()
 	unix := time.Now().Unix()
 	s.Mux.Unlock()
 
 	var builder strings.Builder
-	chars := []rune(LowercaseLetters + UppercaseLetters + Numbers + Underscore)
+	chars := []rune(Alphabet)
 
 	seed := rand.NewSource(unix)
 	random := rand.New(seed)
 
-	for i := 0; i < Length; i++ {
+	for i := 0; i < s.Length; i++ {
 		builder.WriteRune(chars[random.Intn(len(chars))])
 	}
 
-	return builder.String(), unix
+	return builder.String(), unix, nil
 }
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+// DefaultPingTimeout bounds the storage ping of a readiness check
+const DefaultPingTimeout = time.Second
+
+// HealthService tells the probes whether the server can take requests
+type HealthService struct {
+	DB      domain.IUrlStorage
+	Timeout time.Duration
+
+	draining atomic.Bool
+}
+
+func NewHealthService(db domain.IUrlStorage) *HealthService {
+	return &HealthService{
+		DB:      db,
+		Timeout: DefaultPingTimeout,
+	}
+}
+
+// Drain makes every later readiness check fail, it is called before the servers are stopped
+func (s *HealthService) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *HealthService) Ready(ctx context.Context) error {
+	if s.draining.Load() {
+		return domain.ErrorShuttingDown
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	if err := s.DB.Ping(ctx); err != nil {
+		log.Printf("Storage ping error: %v\n", err)
+		return domain.ErrorNotReady
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	health := NewHealthService(db)
+
+	db.EXPECT().Ping(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		return nil
+	})
+
+	require.NoError(t, health.Ready(context.Background()))
+}
+
+func TestReady_PingError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	health := NewHealthService(db)
+
+	db.EXPECT().Ping(gomock.Any()).Return(context.DeadlineExceeded)
+
+	require.Equal(t, domain.ErrorNotReady, health.Ready(context.Background()))
+}
+
+func TestReady_Draining(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	health := NewHealthService(db)
+
+	health.Drain()
+
+	require.Equal(t, domain.ErrorShuttingDown, health.Ready(context.Background()))
+}
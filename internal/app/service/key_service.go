@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+const (
+	keyIDBytes     = 8
+	keySecretBytes = 32
+	// token is the key id and the secret joined by the separator
+	keySeparator = "."
+)
+
+type KeyService struct {
+	DB domain.IKeyStorage
+}
+
+func NewKeyService(db domain.IKeyStorage) *KeyService {
+	return &KeyService{
+		DB: db,
+	}
+}
+
+func (s *KeyService) IssueKey(ctx context.Context, name string) (string, *domain.APIKey, error) {
+	id, err := random(keyIDBytes)
+	if err != nil {
+		return "", &domain.APIKey{}, err
+	}
+	secret, err := secret()
+	if err != nil {
+		return "", &domain.APIKey{}, err
+	}
+
+	key := domain.APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Hash:      hash(secret),
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := s.DB.AddKey(ctx, key); err != nil {
+		return "", &domain.APIKey{}, err
+	}
+
+	return key.ID + keySeparator + secret, &key, nil
+}
+
+func (s *KeyService) Authenticate(ctx context.Context, token string) (*domain.APIKey, error) {
+	id, secret, ok := strings.Cut(token, keySeparator)
+	if !ok || id == "" || secret == "" {
+		return &domain.APIKey{}, domain.ErrorUnauthorized
+	}
+
+	key, err := s.DB.GetKey(ctx, id)
+	if err == domain.ErrorKeyNotFound {
+		return &domain.APIKey{}, domain.ErrorUnauthorized
+	} else if err != nil {
+		return &domain.APIKey{}, err
+	}
+
+	if key.Revoked() || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash(secret))) != 1 {
+		return &domain.APIKey{}, domain.ErrorUnauthorized
+	}
+
+	return key, nil
+}
+
+func (s *KeyService) RevokeKey(ctx context.Context, id string) error {
+	return s.DB.RevokeKey(ctx, id, time.Now().Unix())
+}
+
+func (s *KeyService) RotateKey(ctx context.Context, id string) (string, error) {
+	secret, err := secret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.DB.RotateKey(ctx, id, hash(secret)); err != nil {
+		return "", err
+	}
+
+	return id + keySeparator + secret, nil
+}
+
+// hash is enough for secrets of full entropy, unlike passwords they need no slow hash
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func secret() (string, error) {
+	buf, err := random(keySecretBytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func random(size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueKey_Authenticate(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIKeyStorage(ctrl)
+	service := NewKeyService(db)
+
+	var stored domain.APIKey
+	db.EXPECT().AddKey(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, key domain.APIKey) error {
+		stored = key
+		return nil
+	})
+
+	token, key, err := service.IssueKey(ctx, "ci")
+
+	require.NoError(t, err)
+	require.Equal(t, "ci", key.Name)
+	require.True(t, strings.HasPrefix(token, key.ID+"."))
+	require.NotContains(t, token, stored.Hash)
+
+	db.EXPECT().GetKey(ctx, key.ID).Return(&stored, nil).Times(2)
+
+	authenticated, err := service.Authenticate(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, authenticated.ID)
+
+	_, err = service.Authenticate(ctx, key.ID+".wrong")
+	require.Equal(t, domain.ErrorUnauthorized, err)
+}
+
+func TestAuthenticate_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIKeyStorage(ctrl)
+	service := NewKeyService(db)
+
+	db.EXPECT().GetKey(ctx, "missing").Return(&domain.APIKey{}, domain.ErrorKeyNotFound)
+	db.EXPECT().GetKey(ctx, "revoked").Return(&domain.APIKey{ID: "revoked", Hash: hash("secret"), RevokedAt: 1686557090}, nil)
+	db.EXPECT().GetKey(ctx, "broken").Return(&domain.APIKey{}, ErrorDBShutdown)
+
+	for _, token := range []string{"", "no-separator", ".secret", "missing.secret", "revoked.secret"} {
+		_, err := service.Authenticate(ctx, token)
+		require.Equal(t, domain.ErrorUnauthorized, err, token)
+	}
+
+	_, err := service.Authenticate(ctx, "broken.secret")
+	require.Equal(t, ErrorDBShutdown, err)
+}
+
+func TestRotateKey(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIKeyStorage(ctrl)
+	service := NewKeyService(db)
+
+	var rotated string
+	db.EXPECT().RotateKey(ctx, "0123456789abcdef", gomock.Any()).DoAndReturn(func(ctx context.Context, id string, hash string) error {
+		rotated = hash
+		return nil
+	})
+	db.EXPECT().RevokeKey(ctx, "0123456789abcdef", gomock.Any()).Return(domain.ErrorKeyNotFound)
+
+	token, err := service.RotateKey(ctx, "0123456789abcdef")
+	require.NoError(t, err)
+
+	id, secret, _ := strings.Cut(token, ".")
+	require.Equal(t, "0123456789abcdef", id)
+	require.Equal(t, rotated, hash(secret))
+
+	require.Equal(t, domain.ErrorKeyNotFound, service.RevokeKey(ctx, "0123456789abcdef"))
+}
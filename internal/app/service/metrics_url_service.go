@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsUrlService observes the latency and the errors of every method of
+// another link service, and the short links every CreateUrl generated until
+// one was free. The generated links are counted by the storage of the
+// decorated service, which has to be wrapped with CountAttempts
+type MetricsUrlService struct {
+	Service domain.IUrlService
+
+	duration   *prometheus.HistogramVec
+	errors     *prometheus.CounterVec
+	attempts   prometheus.Histogram
+	collisions prometheus.Counter
+}
+
+func NewMetricsUrlService(service domain.IUrlService, registerer prometheus.Registerer) *MetricsUrlService {
+	s := &MetricsUrlService{
+		Service: service,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shorturl_service_duration_seconds",
+			Help:    "Latency of the link service methods.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shorturl_service_errors_total",
+			Help: "Link service calls that failed by method, invalid requests included.",
+		}, []string{"method"}),
+		attempts: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "shorturl_generator_attempts",
+			Help:    "Short links a CreateUrl generated until one was free.",
+			Buckets: []float64{1, 2, 3, 5, 10, 20, 50},
+		}),
+		collisions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shorturl_generator_collisions_total",
+			Help: "Generated short links that were already taken.",
+		}),
+	}
+
+	registerer.MustRegister(s.duration, s.errors, s.attempts, s.collisions)
+	return s
+}
+
+// attempts are the short links a single CreateUrl tried to store
+type attempts struct {
+	generated int64
+	collided  int64
+}
+
+type attemptsKey struct{}
+
+// CountAttempts counts the generated links stored through the storage into the
+// CreateUrl of MetricsUrlService they belong to, aliases are not generated
+func CountAttempts(db domain.IUrlStorage) domain.IUrlStorage {
+	return &attemptsStorage{IUrlStorage: db}
+}
+
+type attemptsStorage struct {
+	domain.IUrlStorage
+}
+
+func (s *attemptsStorage) AddUrl(ctx context.Context, urlData domain.URLData) error {
+	err := s.IUrlStorage.AddUrl(ctx, urlData)
+	if counted, ok := ctx.Value(attemptsKey{}).(*attempts); ok && !urlData.Alias {
+		atomic.AddInt64(&counted.generated, 1)
+		if err == domain.ErrorShortExists {
+			atomic.AddInt64(&counted.collided, 1)
+		}
+	}
+	return err
+}
+
+// observe records the method started at start, it is deferred with the named error of the method
+func (s *MetricsUrlService) observe(method string, start time.Time, err *error) {
+	s.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		s.errors.WithLabelValues(method).Inc()
+	}
+}
+
+func (s *MetricsUrlService) CreateUrl(ctx context.Context, request domain.URLRequest) (_ string, err error) {
+	defer s.observe("CreateUrl", time.Now(), &err)
+
+	counted := new(attempts)
+	defer func() {
+		// links that were stored before, and aliases, generate nothing
+		if generated := atomic.LoadInt64(&counted.generated); generated > 0 {
+			s.attempts.Observe(float64(generated))
+			s.collisions.Add(float64(atomic.LoadInt64(&counted.collided)))
+		}
+	}()
+
+	return s.Service.CreateUrl(context.WithValue(ctx, attemptsKey{}, counted), request)
+}
+
+func (s *MetricsUrlService) CreateUrls(ctx context.Context, requests []domain.URLRequest) (_ []domain.URLResult, err error) {
+	defer s.observe("CreateUrls", time.Now(), &err)
+	return s.Service.CreateUrls(ctx, requests)
+}
+
+func (s *MetricsUrlService) GetUrl(ctx context.Context, shortUrl string) (_ *domain.URLLong, err error) {
+	defer s.observe("GetUrl", time.Now(), &err)
+	return s.Service.GetUrl(ctx, shortUrl)
+}
+
+func (s *MetricsUrlService) ResolveUrls(ctx context.Context, shortUrls []string) (_ []domain.ResolveResult, err error) {
+	defer s.observe("ResolveUrls", time.Now(), &err)
+	return s.Service.ResolveUrls(ctx, shortUrls)
+}
+
+func (s *MetricsUrlService) GetStats(ctx context.Context, request domain.StatsRequest) (_ *domain.Stats, err error) {
+	defer s.observe("GetStats", time.Now(), &err)
+	return s.Service.GetStats(ctx, request)
+}
+
+func (s *MetricsUrlService) DeleteUrl(ctx context.Context, shortUrl string) (err error) {
+	defer s.observe("DeleteUrl", time.Now(), &err)
+	return s.Service.DeleteUrl(ctx, shortUrl)
+}
+
+func (s *MetricsUrlService) RestoreUrl(ctx context.Context, shortUrl string) (err error) {
+	defer s.observe("RestoreUrl", time.Now(), &err)
+	return s.Service.RestoreUrl(ctx, shortUrl)
+}
+
+func (s *MetricsUrlService) DisableUrl(ctx context.Context, shortUrl string, disabled bool) (err error) {
+	defer s.observe("DisableUrl", time.Now(), &err)
+	return s.Service.DisableUrl(ctx, shortUrl, disabled)
+}
+
+func (s *MetricsUrlService) UpdateUrl(ctx context.Context, shortUrl string, longUrl string) (err error) {
+	defer s.observe("UpdateUrl", time.Now(), &err)
+	return s.Service.UpdateUrl(ctx, shortUrl, longUrl)
+}
+
+func (s *MetricsUrlService) GetHistory(ctx context.Context, shortUrl string) (_ []domain.URLVersion, err error) {
+	defer s.observe("GetHistory", time.Now(), &err)
+	return s.Service.GetHistory(ctx, shortUrl)
+}
+
+func (s *MetricsUrlService) RollbackUrl(ctx context.Context, shortUrl string, version int64) (err error) {
+	defer s.observe("RollbackUrl", time.Now(), &err)
+	return s.Service.RollbackUrl(ctx, shortUrl, version)
+}
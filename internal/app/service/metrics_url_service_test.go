@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsCreateUrl_Attempts(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	registry := prometheus.NewRegistry()
+	service := NewMetricsUrlService(NewUrlService(CountAttempts(db), generator, clicks), registry)
+
+	db.EXPECT().GetShort(gomock.Any(), CreateTests[2].Long, "").Return("", domain.ErrorLinkNotFound)
+	gomock.InOrder(
+		generator.EXPECT().GenerateShortLink().Return(CreateTests[2].Short, CreateTests[2].AddedAt, nil),
+		generator.EXPECT().GenerateShortLink().Return(CreateTests[0].Short, CreateTests[0].AddedAt, nil),
+	)
+	gomock.InOrder(
+		db.EXPECT().AddUrl(gomock.Any(), gomock.Any()).Return(domain.ErrorShortExists),
+		db.EXPECT().AddUrl(gomock.Any(), gomock.Any()).Return(nil),
+	)
+	// aliases are not generated
+	db.EXPECT().AddUrl(gomock.Any(), gomock.Any()).Return(domain.ErrorShortExists)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[2].Long})
+	require.NoError(t, err)
+	require.Equal(t, CreateTests[0].Short, short)
+
+	_, err = service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[2].Long, Alias: "my_alias"})
+	require.Equal(t, domain.ErrorAliasTaken, err)
+
+	expected := `
+		# HELP shorturl_generator_attempts Short links a CreateUrl generated until one was free.
+		# TYPE shorturl_generator_attempts histogram
+		shorturl_generator_attempts_bucket{le="1"} 0
+		shorturl_generator_attempts_bucket{le="2"} 1
+		shorturl_generator_attempts_bucket{le="3"} 1
+		shorturl_generator_attempts_bucket{le="5"} 1
+		shorturl_generator_attempts_bucket{le="10"} 1
+		shorturl_generator_attempts_bucket{le="20"} 1
+		shorturl_generator_attempts_bucket{le="50"} 1
+		shorturl_generator_attempts_bucket{le="+Inf"} 1
+		shorturl_generator_attempts_sum 2
+		shorturl_generator_attempts_count 1
+		# HELP shorturl_generator_collisions_total Generated short links that were already taken.
+		# TYPE shorturl_generator_collisions_total counter
+		shorturl_generator_collisions_total 1
+		# HELP shorturl_service_errors_total Link service calls that failed by method, invalid requests included.
+		# TYPE shorturl_service_errors_total counter
+		shorturl_service_errors_total{method="CreateUrl"} 1
+	`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"shorturl_generator_attempts", "shorturl_generator_collisions_total", "shorturl_service_errors_total"))
+}
+
+func TestMetricsGetUrl(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	urls := mocks.NewMockIUrlService(ctrl)
+	registry := prometheus.NewRegistry()
+	service := NewMetricsUrlService(urls, registry)
+
+	urls.EXPECT().GetUrl(ctx, "S0mE__Lin4").Return(&domain.URLLong{LongURL: "example.com"}, nil)
+
+	long, err := service.GetUrl(ctx, "S0mE__Lin4")
+	require.NoError(t, err)
+	require.Equal(t, "example.com", long.LongURL)
+
+	require.Equal(t, 1, testutil.CollectAndCount(registry, "shorturl_service_duration_seconds"))
+	require.Equal(t, 0, testutil.CollectAndCount(registry, "shorturl_service_errors_total"))
+}
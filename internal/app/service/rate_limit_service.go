@@ -0,0 +1,205 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is a token bucket refilled with Rate tokens per second up to Burst tokens
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucketKey struct {
+	scope  string
+	client string
+}
+
+type bucket struct {
+	key     bucketKey
+	limiter *rate.Limiter
+	seen    time.Time
+	full    time.Duration // time an empty bucket takes to refill
+	element *list.Element
+}
+
+// DefaultMaxClients bounds the token buckets kept at once
+const DefaultMaxClients = 100000
+
+// RateLimitService keeps a token bucket per client for every limited scope,
+// buckets idle long enough to be full again are dropped by Run. Past MaxClients
+// buckets the least recently used one is dropped, its client starts over with a full bucket
+type RateLimitService struct {
+	Limits     map[string]RateLimit
+	Interval   time.Duration
+	MaxClients int
+	Mux        *sync.Mutex
+	buckets    map[bucketKey]*bucket
+	order      *list.List // most recently used first
+
+	requests  *prometheus.CounterVec
+	clients   *prometheus.GaugeVec
+	evictions prometheus.Counter
+}
+
+func NewRateLimitService(limits map[string]RateLimit, interval time.Duration, registerer prometheus.Registerer) *RateLimitService {
+	s := &RateLimitService{
+		Limits:     limits,
+		Interval:   interval,
+		MaxClients: DefaultMaxClients,
+		Mux:        &sync.Mutex{},
+		buckets:    make(map[bucketKey]*bucket),
+		order:      list.New(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shorturl_rate_limit_requests_total",
+			Help: "Requests checked by the rate limiter by scope and result (allowed or limited).",
+		}, []string{"scope", "result"}),
+		clients: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "shorturl_rate_limit_clients",
+			Help: "Clients with a token bucket by scope.",
+		}, []string{"scope"}),
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "shorturl_rate_limit_evictions_total",
+			Help: "Token buckets dropped before they were full because the limiter had too many clients.",
+		}),
+	}
+
+	rates := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "shorturl_rate_limit_rate",
+		Help: "Tokens per second added to the bucket of a client by scope.",
+	}, []string{"scope"})
+	bursts := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "shorturl_rate_limit_burst",
+		Help: "Size of the bucket of a client by scope.",
+	}, []string{"scope"})
+	for scope, limit := range limits {
+		rates.WithLabelValues(scope).Set(limit.Rate)
+		bursts.WithLabelValues(scope).Set(float64(limit.Burst))
+		s.clients.WithLabelValues(scope)
+	}
+
+	registerer.MustRegister(s.requests, s.clients, s.evictions, rates, bursts)
+	return s
+}
+
+func (s *RateLimitService) Allow(scope string, client string) (time.Duration, bool) {
+	limit, ok := s.Limits[scope]
+	if !ok {
+		return 0, true
+	}
+
+	now := time.Now()
+	key := bucketKey{scope: scope, client: client}
+
+	s.Mux.Lock()
+	b, ok := s.buckets[key]
+	if ok {
+		s.order.MoveToFront(b.element)
+	} else {
+		for len(s.buckets) >= s.MaxClients && s.order.Len() > 0 {
+			s.remove(s.order.Back().Value.(*bucket))
+			s.evictions.Inc()
+		}
+		b = &bucket{
+			key:     key,
+			limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst),
+			full:    time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)),
+		}
+		b.element = s.order.PushFront(b)
+		s.buckets[key] = b
+		s.clients.WithLabelValues(scope).Inc()
+	}
+	b.seen = now
+	s.Mux.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if wait := reservation.DelayFrom(now); wait > 0 {
+		// give the token back, the request is not going to use it
+		reservation.CancelAt(now)
+		s.requests.WithLabelValues(scope, "limited").Inc()
+		return wait, false
+	}
+
+	s.requests.WithLabelValues(scope, "allowed").Inc()
+	return 0, true
+}
+
+func (s *RateLimitService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Sweep(now)
+		}
+	}
+}
+
+// Sweep drops the buckets that are full at the time, a new bucket starts
+// full too, so the clients keep their limits. It returns the number of dropped buckets
+func (s *RateLimitService) Sweep(now time.Time) int {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	dropped := 0
+	for _, b := range s.buckets {
+		if now.Sub(b.seen) >= b.full {
+			s.remove(b)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// remove drops the bucket, the caller must hold the lock
+func (s *RateLimitService) remove(b *bucket) {
+	delete(s.buckets, b.key)
+	s.order.Remove(b.element)
+	s.clients.WithLabelValues(b.key.scope).Dec()
+}
+
+// ParseRateLimits reads comma separated scope=rate:burst pairs, such as
+// "POST /=1:20,/pb.ShortUrl/CreateUrl=1:20", rate is in tokens per second
+func ParseRateLimits(value string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	if strings.TrimSpace(value) == "" {
+		return limits, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		eq := strings.LastIndex(pair, "=")
+		if eq < 1 {
+			return nil, domain.ErrorInvalidLimit
+		}
+		scope := strings.TrimSpace(pair[:eq])
+
+		rateValue, burstValue, ok := strings.Cut(pair[eq+1:], ":")
+		if !ok {
+			return nil, domain.ErrorInvalidLimit
+		}
+		perSecond, err := strconv.ParseFloat(strings.TrimSpace(rateValue), 64)
+		if err != nil || perSecond <= 0 {
+			return nil, domain.ErrorInvalidLimit
+		}
+		burst, err := strconv.Atoi(strings.TrimSpace(burstValue))
+		if err != nil || burst < 1 {
+			return nil, domain.ErrorInvalidLimit
+		}
+
+		limits[scope] = RateLimit{Rate: perSecond, Burst: burst}
+	}
+
+	return limits, nil
+}
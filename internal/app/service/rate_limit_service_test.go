@@ -0,0 +1,136 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_Allow(t *testing.T) {
+	limits := map[string]RateLimit{"POST /": {Rate: 1, Burst: 2}}
+	limiter := NewRateLimitService(limits, time.Minute, prometheus.NewRegistry())
+
+	for i := 0; i < 2; i++ {
+		_, ok := limiter.Allow("POST /", "ip:1.1.1.1")
+		require.True(t, ok)
+	}
+
+	wait, ok := limiter.Allow("POST /", "ip:1.1.1.1")
+	require.False(t, ok)
+	require.InDelta(t, time.Second, wait, float64(100*time.Millisecond))
+
+	// other clients and scopes have their own buckets
+	_, ok = limiter.Allow("POST /", "ip:2.2.2.2")
+	require.True(t, ok)
+	for i := 0; i < 10; i++ {
+		_, ok = limiter.Allow("GET /:link", "ip:1.1.1.1")
+		require.True(t, ok)
+	}
+
+	require.Equal(t, float64(3), testutil.ToFloat64(limiter.requests.WithLabelValues("POST /", "allowed")))
+	require.Equal(t, float64(1), testutil.ToFloat64(limiter.requests.WithLabelValues("POST /", "limited")))
+	require.Equal(t, float64(2), testutil.ToFloat64(limiter.clients.WithLabelValues("POST /")))
+}
+
+func TestRateLimit_LimitedKeepsToken(t *testing.T) {
+	limits := map[string]RateLimit{"POST /": {Rate: 10, Burst: 1}}
+	limiter := NewRateLimitService(limits, time.Minute, prometheus.NewRegistry())
+
+	_, ok := limiter.Allow("POST /", "key:a")
+	require.True(t, ok)
+
+	// rejected calls must not push the next token further away
+	for i := 0; i < 5; i++ {
+		_, ok = limiter.Allow("POST /", "key:a")
+		require.False(t, ok)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	_, ok = limiter.Allow("POST /", "key:a")
+	require.True(t, ok)
+}
+
+func TestRateLimit_Sweep(t *testing.T) {
+	limits := map[string]RateLimit{"POST /": {Rate: 1, Burst: 2}}
+	limiter := NewRateLimitService(limits, time.Minute, prometheus.NewRegistry())
+
+	limiter.Allow("POST /", "ip:1.1.1.1")
+	limiter.Allow("POST /", "ip:2.2.2.2")
+
+	require.Equal(t, 0, limiter.Sweep(time.Now()))
+	require.Equal(t, 2, limiter.Sweep(time.Now().Add(2*time.Second)))
+	require.Equal(t, float64(0), testutil.ToFloat64(limiter.clients.WithLabelValues("POST /")))
+}
+
+func TestRateLimit_MaxClients(t *testing.T) {
+	limits := map[string]RateLimit{"POST /": {Rate: 1, Burst: 1}}
+	limiter := NewRateLimitService(limits, time.Minute, prometheus.NewRegistry())
+	limiter.MaxClients = 2
+
+	limiter.Allow("POST /", "ip:1.1.1.1")
+	limiter.Allow("POST /", "ip:2.2.2.2")
+	// 1.1.1.1 is used last, so 2.2.2.2 is dropped for the new client
+	_, ok := limiter.Allow("POST /", "ip:1.1.1.1")
+	require.False(t, ok)
+	limiter.Allow("POST /", "ip:3.3.3.3")
+
+	require.Len(t, limiter.buckets, 2)
+	require.Contains(t, limiter.buckets, bucketKey{scope: "POST /", client: "ip:1.1.1.1"})
+	require.Equal(t, float64(1), testutil.ToFloat64(limiter.evictions))
+	require.Equal(t, float64(2), testutil.ToFloat64(limiter.clients.WithLabelValues("POST /")))
+
+	// the kept client is still limited
+	_, ok = limiter.Allow("POST /", "ip:1.1.1.1")
+	require.False(t, ok)
+}
+
+func TestParseRateLimits(t *testing.T) {
+	tests := map[string]struct {
+		Value  string
+		Limits map[string]RateLimit
+		Error  error
+	}{
+		"Empty": {
+			Value:  "",
+			Limits: map[string]RateLimit{},
+		},
+		"Route And Method": {
+			Value: "POST /=0.5:20, /pb.ShortUrl/CreateUrl=2:5",
+			Limits: map[string]RateLimit{
+				"POST /":                 {Rate: 0.5, Burst: 20},
+				"/pb.ShortUrl/CreateUrl": {Rate: 2, Burst: 5},
+			},
+		},
+		"Missing Burst": {
+			Value: "POST /=1",
+			Error: domain.ErrorInvalidLimit,
+		},
+		"Missing Scope": {
+			Value: "=1:2",
+			Error: domain.ErrorInvalidLimit,
+		},
+		"Zero Rate": {
+			Value: "POST /=0:2",
+			Error: domain.ErrorInvalidLimit,
+		},
+		"Zero Burst": {
+			Value: "POST /=1:0",
+			Error: domain.ErrorInvalidLimit,
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			limits, err := ParseRateLimits(test.Value)
+
+			require.Equal(t, test.Error, err)
+			if test.Error == nil {
+				require.Equal(t, test.Limits, limits)
+			}
+		})
+	}
+}
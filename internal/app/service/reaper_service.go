@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+// ReaperService periodically removes expired links from the storage
+// and purges deleted links once their restore window is over
+type ReaperService struct {
+	DB            domain.IUrlStorage
+	Interval      time.Duration
+	RestoreWindow time.Duration
+}
+
+func NewReaperService(db domain.IUrlStorage, interval, restoreWindow time.Duration) *ReaperService {
+	return &ReaperService{
+		DB:            db,
+		Interval:      interval,
+		RestoreWindow: restoreWindow,
+	}
+}
+
+func (s *ReaperService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reap(ctx); err != nil {
+				log.Printf("Reaper error: %v\n", err)
+			}
+		}
+	}
+}
+
+// Reap returns the number of removed links
+func (s *ReaperService) Reap(ctx context.Context) (int64, error) {
+	now := time.Now()
+
+	expired, err := s.DB.DeleteExpired(ctx, now.Unix())
+	if err != nil {
+		return expired, err
+	}
+
+	purged, err := s.DB.PurgeDeleted(ctx, now.Add(-s.RestoreWindow).Unix())
+	return expired + purged, err
+}
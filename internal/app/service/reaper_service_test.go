@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReap(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	reaper := NewReaperService(db, time.Minute, time.Hour)
+
+	db.EXPECT().DeleteExpired(ctx, gomock.Any()).Return(int64(3), nil)
+	db.EXPECT().PurgeDeleted(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, before int64) (int64, error) {
+		require.InDelta(t, time.Now().Add(-time.Hour).Unix(), before, 1)
+		return int64(2), nil
+	})
+
+	deleted, err := reaper.Reap(ctx)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(5), deleted)
+}
+
+func TestReap_Error(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	reaper := NewReaperService(db, time.Minute, time.Hour)
+
+	db.EXPECT().DeleteExpired(ctx, gomock.Any()).Return(int64(0), ErrorDBShutdown)
+
+	_, err := reaper.Reap(ctx)
+
+	require.Equal(t, ErrorDBShutdown, err)
+}
+
+func TestReap_PurgeError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	reaper := NewReaperService(db, time.Minute, time.Hour)
+
+	db.EXPECT().DeleteExpired(ctx, gomock.Any()).Return(int64(3), nil)
+	db.EXPECT().PurgeDeleted(ctx, gomock.Any()).Return(int64(0), ErrorDBShutdown)
+
+	_, err := reaper.Reap(ctx)
+
+	require.Equal(t, ErrorDBShutdown, err)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+// Snowflake style id layout, 59 bits in total so that any id fits
+// into Length characters of the 63 character Alphabet (63^10 > 2^59),
+// so the short links are never shorter than Length
+const (
+	Epoch        = 1672531200000 // 2023-01-01 UTC in milliseconds
+	TimeBits     = 39            // milliseconds since Epoch, enough until 2040, later ids fail
+	maxTime      = 1<<TimeBits - 1
+	NodeBits     = 8
+	SequenceBits = 12
+	MaxNodeID    = 1<<NodeBits - 1
+	maxSequence  = 1<<SequenceBits - 1
+	idMask       = 1<<(TimeBits+NodeBits+SequenceBits) - 1
+	// odd multiplier is a bijection modulo 2^59,
+	// it keeps ids unique while consecutive codes stop looking alike
+	scramble = 0x5DEECE66D
+)
+
+// SnowflakeGenerateService builds short links from ids that are unique
+// across all instances as long as every instance has its own node id
+type SnowflakeGenerateService struct {
+	Mux      *sync.Mutex
+	NodeID   int64
+	Length   int // at least Length
+	last     int64
+	sequence int64
+	clock    func() int64 // unix time in milliseconds
+}
+
+func NewSnowflakeGenerateService(nodeID int64) (*SnowflakeGenerateService, error) {
+	if nodeID < 0 || nodeID > MaxNodeID {
+		return nil, domain.ErrorInvalidNodeID
+	}
+
+	return &SnowflakeGenerateService{
+		Mux:    new(sync.Mutex),
+		NodeID: nodeID,
+		Length: Length,
+		clock:  func() int64 { return time.Now().UnixMilli() },
+	}, nil
+}
+
+// GenerateShortLink fails with ErrorClockOverflow once the time since Epoch
+// no longer fits into TimeBits, instead of wrapping onto the ids of 2023
+func (s *SnowflakeGenerateService) GenerateShortLink() (string, int64, error) {
+	id, millis, err := s.nextID()
+	if err != nil {
+		return "", 0, err
+	}
+	return Encode((id*scramble)&idMask, s.Length), millis / 1000, nil
+}
+
+// nextID returns the id and the unix time in milliseconds it was made at
+func (s *SnowflakeGenerateService) nextID() (int64, int64, error) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	now := s.clock()
+	if now < s.last {
+		// clock went backwards, keep counting from the last timestamp
+		now = s.last
+	}
+
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			// sequence is exhausted for this millisecond
+			for now <= s.last {
+				time.Sleep(time.Millisecond / 10)
+				now = s.clock()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	if now-Epoch > maxTime {
+		return 0, 0, domain.ErrorClockOverflow
+	}
+	s.last = now
+
+	id := (now-Epoch)<<(NodeBits+SequenceBits) | s.NodeID<<SequenceBits | s.sequence
+	return id, now, nil
+}
+
+// Encode writes the id in base 63 using Alphabet, padded to length characters
+func Encode(id int64, length int) string {
+	base := int64(len(Alphabet))
+	short := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		short[i] = Alphabet[id%base]
+		id /= base
+	}
+	return string(short)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSnowflakeGenerateService(t *testing.T) {
+	var tests = []struct {
+		Title  string
+		NodeID int64
+		Error  error
+	}{
+		{"First node", 0, nil},
+		{"Last node", MaxNodeID, nil},
+		{"Negative node", -1, domain.ErrorInvalidNodeID},
+		{"Node out of range", MaxNodeID + 1, domain.ErrorInvalidNodeID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Title, func(t *testing.T) {
+			_, err := NewSnowflakeGenerateService(test.NodeID)
+			require.Equal(t, test.Error, err)
+		})
+	}
+}
+
+func TestSnowflakeGenerateShortLink_Format(t *testing.T) {
+	generator, err := NewSnowflakeGenerateService(7)
+	require.NoError(t, err)
+
+	short, now, err := generator.GenerateShortLink()
+
+	require.NoError(t, err)
+	require.Len(t, short, Length)
+	require.True(t, CheckAlias(short))
+	require.Greater(t, now, int64(Epoch/1000))
+}
+
+func TestSnowflakeGenerateShortLink_Unique(t *testing.T) {
+	first, _ := NewSnowflakeGenerateService(1)
+	second, _ := NewSnowflakeGenerateService(2)
+
+	var mux sync.Mutex
+	links := map[string]struct{}{}
+
+	var wg sync.WaitGroup
+	for _, generator := range []*SnowflakeGenerateService{first, second} {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(generator *SnowflakeGenerateService) {
+				defer wg.Done()
+				for j := 0; j < 10000; j++ {
+					short, _, _ := generator.GenerateShortLink()
+					mux.Lock()
+					links[short] = struct{}{}
+					mux.Unlock()
+				}
+			}(generator)
+		}
+	}
+	wg.Wait()
+
+	require.Len(t, links, 2*4*10000)
+}
+
+func TestEncode(t *testing.T) {
+	require.Equal(t, strings.Repeat("a", Length), Encode(0, Length))
+	require.Equal(t, strings.Repeat("a", Length-1)+"b", Encode(1, Length))
+	require.Equal(t, strings.Repeat("a", Length-2)+"ba", Encode(int64(len(Alphabet)), Length))
+	require.Equal(t, strings.Repeat("_", Length), Encode(int64(984930291881790849-1), Length))
+	require.Equal(t, "aaa"+Encode(12345, Length), Encode(12345, Length+3))
+}
+
+func TestSnowflakeGenerateShortLink_Length(t *testing.T) {
+	generator, err := NewSnowflakeGenerateService(7)
+	require.NoError(t, err)
+	generator.Length = 16
+
+	short, _, _ := generator.GenerateShortLink()
+
+	require.Len(t, short, 16)
+	require.True(t, CheckAlias(short))
+}
+
+func TestSnowflakeGenerateShortLink_ClockOverflow(t *testing.T) {
+	generator, err := NewSnowflakeGenerateService(7)
+	require.NoError(t, err)
+
+	// the last millisecond of the layout still fits
+	generator.clock = func() int64 { return Epoch + maxTime }
+	_, now, err := generator.GenerateShortLink()
+	require.NoError(t, err)
+	require.Equal(t, int64((Epoch+maxTime)/1000), now)
+
+	generator.clock = func() int64 { return Epoch + maxTime + 1 }
+	short, _, err := generator.GenerateShortLink()
+	require.Equal(t, domain.ErrorClockOverflow, err)
+	require.Empty(t, short)
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+const (
+	ConflictSkip      = "skip"      // keep the stored link
+	ConflictOverwrite = "overwrite" // replace the stored link
+	ConflictFail      = "fail"      // stop the import at the stored link
+)
+
+var csvHeader = []string{"short", "link", "added", "expires", "alias", "redirect", "deleted", "disabled", "owner"}
+
+// TransferService moves the links between the storage and JSONL or CSV files
+type TransferService struct {
+	DB        domain.IUrlStorage
+	BatchSize int
+}
+
+func NewTransferService(db domain.IUrlStorage, batchSize int) *TransferService {
+	return &TransferService{
+		DB:        db,
+		BatchSize: batchSize,
+	}
+}
+
+func CheckFormat(format string) bool {
+	return format == FormatJSONL || format == FormatCSV
+}
+
+func CheckConflict(policy string) bool {
+	return policy == ConflictSkip || policy == ConflictOverwrite || policy == ConflictFail
+}
+
+// Export writes every stored link and returns the number of written links
+func (s *TransferService) Export(ctx context.Context, w io.Writer, format string) (int64, error) {
+	var writer linkWriter
+	switch format {
+	case FormatJSONL:
+		buffer := bufio.NewWriter(w)
+		writer = &jsonlWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}
+	case FormatCSV:
+		writer = &csvWriter{writer: csv.NewWriter(w)}
+	default:
+		return 0, fmt.Errorf("unexpected format: %s", format)
+	}
+
+	var written int64
+	err := s.DB.ExportUrls(ctx, func(urlData domain.URLData) error {
+		if err := writer.Write(urlData); err != nil {
+			return err
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	return written, writer.Flush()
+}
+
+// Import reads the links and stores them in batches, links whose short link
+// is taken are handled by the conflict policy. A dry run reports what the
+// import would do without writing. With the fail policy the batches before
+// the conflicting link are already stored
+func (s *TransferService) Import(ctx context.Context, r io.Reader, format string, policy string, dryRun bool) (*domain.ImportReport, error) {
+	var reader linkReader
+	switch format {
+	case FormatJSONL:
+		reader = &jsonlReader{decoder: json.NewDecoder(r)}
+	case FormatCSV:
+		reader = &csvReader{reader: csv.NewReader(r)}
+	default:
+		return nil, fmt.Errorf("unexpected format: %s", format)
+	}
+	if !CheckConflict(policy) {
+		return nil, fmt.Errorf("unexpected conflict policy: %s", policy)
+	}
+
+	report := &domain.ImportReport{DryRun: dryRun}
+	run := &importRun{policy: policy, dryRun: dryRun, report: report, planned: make(map[string]bool)}
+
+	batch := make([]domain.URLData, 0, s.BatchSize)
+	shorts := make(map[string]bool, s.BatchSize)
+	for {
+		urlData, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("record %d: %w", report.Read+1, err)
+		}
+		if !checkRecord(urlData) {
+			return report, fmt.Errorf("record %d: %w", report.Read+1, domain.ErrorInvalidRecord)
+		}
+		report.Read++
+
+		// a short link repeated in the file conflicts with its first record
+		if len(batch) == s.BatchSize || shorts[urlData.URLShort] {
+			if err := s.importBatch(ctx, batch, run); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+			shorts = make(map[string]bool, s.BatchSize)
+		}
+		batch = append(batch, urlData)
+		shorts[urlData.URLShort] = true
+	}
+
+	if len(batch) > 0 {
+		if err := s.importBatch(ctx, batch, run); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// checkRecord applies the checks of a created link to an imported one,
+// its times are unix seconds where 0 means never
+func checkRecord(urlData domain.URLData) bool {
+	return CheckAlias(urlData.URLShort) &&
+		CheckLink(urlData.LongURL) &&
+		CheckRedirect(urlData.Redirect) &&
+		urlData.AddedAt >= 0 &&
+		urlData.ExpiresAt >= 0 &&
+		urlData.DeletedAt >= 0
+}
+
+type importRun struct {
+	policy  string
+	dryRun  bool
+	report  *domain.ImportReport
+	planned map[string]bool // short links a dry run would have created
+}
+
+func (s *TransferService) importBatch(ctx context.Context, batch []domain.URLData, run *importRun) error {
+	shorts := make([]string, len(batch))
+	for i, urlData := range batch {
+		shorts[i] = urlData.URLShort
+	}
+
+	stored, err := s.DB.GetUrls(ctx, shorts)
+	if err != nil {
+		return err
+	}
+
+	exists := make([]bool, len(batch))
+	for i, short := range shorts {
+		_, found := stored[short]
+		exists[i] = found || run.planned[short]
+		if exists[i] && run.policy == ConflictFail {
+			return fmt.Errorf("%w: %s", domain.ErrorImportConflict, short)
+		}
+	}
+
+	if run.dryRun {
+		for i, short := range shorts {
+			switch {
+			case !exists[i]:
+				run.report.Created++
+				run.planned[short] = true
+			case run.policy == ConflictOverwrite:
+				run.report.Overwritten++
+			default:
+				run.report.Skipped++
+			}
+		}
+		return nil
+	}
+
+	errs, err := s.DB.ImportUrls(ctx, batch, run.policy == ConflictOverwrite)
+	if err != nil {
+		return err
+	}
+
+	for i, short := range shorts {
+		switch {
+		case errs[i] == domain.ErrorShortExists && run.policy == ConflictFail:
+			// stored by someone else since the check
+			return fmt.Errorf("%w: %s", domain.ErrorImportConflict, short)
+		case errs[i] == domain.ErrorShortExists:
+			run.report.Skipped++
+		case errs[i] != nil:
+			return errs[i]
+		case exists[i]:
+			run.report.Overwritten++
+		default:
+			run.report.Created++
+		}
+	}
+
+	return nil
+}
+
+type linkWriter interface {
+	Write(domain.URLData) error
+	Flush() error
+}
+
+type jsonlWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(urlData domain.URLData) error {
+	return w.encoder.Encode(urlData)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.buffer.Flush()
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func (w *csvWriter) Write(urlData domain.URLData) error {
+	if !w.header {
+		if err := w.writer.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	return w.writer.Write([]string{
+		urlData.URLShort,
+		urlData.LongURL,
+		strconv.FormatInt(urlData.AddedAt, 10),
+		strconv.FormatInt(urlData.ExpiresAt, 10),
+		strconv.FormatBool(urlData.Alias),
+		strconv.Itoa(urlData.Redirect),
+		strconv.FormatInt(urlData.DeletedAt, 10),
+		strconv.FormatBool(urlData.Disabled),
+		urlData.Owner,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	if !w.header {
+		// an empty export still has its header
+		if err := w.writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// linkReader returns io.EOF after the last link
+type linkReader interface {
+	Read() (domain.URLData, error)
+}
+
+type jsonlReader struct {
+	decoder *json.Decoder
+}
+
+func (r *jsonlReader) Read() (domain.URLData, error) {
+	var urlData domain.URLData
+	err := r.decoder.Decode(&urlData)
+	return urlData, err
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header bool
+}
+
+func (r *csvReader) Read() (domain.URLData, error) {
+	if !r.header {
+		header, err := r.reader.Read()
+		if err != nil {
+			return domain.URLData{}, err
+		}
+		if len(header) != len(csvHeader) || header[0] != csvHeader[0] {
+			return domain.URLData{}, domain.ErrorInvalidRecord
+		}
+		r.header = true
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		return domain.URLData{}, err
+	}
+
+	// the reader makes sure every record has as many fields as the header
+	urlData := domain.URLData{URLShort: record[0]}
+	urlData.LongURL = record[1]
+	urlData.Owner = record[8]
+
+	var errs [6]error
+	urlData.AddedAt, errs[0] = strconv.ParseInt(record[2], 10, 64)
+	urlData.ExpiresAt, errs[1] = strconv.ParseInt(record[3], 10, 64)
+	urlData.Alias, errs[2] = strconv.ParseBool(record[4])
+	urlData.Redirect, errs[3] = strconv.Atoi(record[5])
+	urlData.DeletedAt, errs[4] = strconv.ParseInt(record[6], 10, 64)
+	urlData.Disabled, errs[5] = strconv.ParseBool(record[7])
+	for _, err := range errs {
+		if err != nil {
+			return domain.URLData{}, domain.ErrorInvalidRecord
+		}
+	}
+
+	return urlData, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/domain"
+	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var transferLinks = []domain.URLData{
+	{URLShort: "GoodLink12", URLLong: domain.URLLong{LongURL: "google.com", AddedAt: 1686557090, Owner: "0123456789abcdef"}},
+	{URLShort: "summer_sale", URLLong: domain.URLLong{LongURL: "ozon.ru/sale?a=1,2", AddedAt: 1686557091, ExpiresAt: 1686657091, Redirect: 301, DeletedAt: 1686557095, Disabled: true}, Alias: true},
+}
+
+func export(links []domain.URLData) func(context.Context, func(domain.URLData) error) error {
+	return func(ctx context.Context, fn func(domain.URLData) error) error {
+		for _, urlData := range links {
+			if err := fn(urlData); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestTransfer_RoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			db := mocks.NewMockIUrlStorage(ctrl)
+			transfer := NewTransferService(db, 10)
+
+			db.EXPECT().ExportUrls(ctx, gomock.Any()).DoAndReturn(export(transferLinks))
+
+			var file bytes.Buffer
+			written, err := transfer.Export(ctx, &file, format)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), written)
+
+			db.EXPECT().GetUrls(ctx, []string{"GoodLink12", "summer_sale"}).Return(map[string]domain.URLLong{}, nil)
+			db.EXPECT().ImportUrls(ctx, transferLinks, false).Return([]error{nil, nil}, nil)
+
+			report, err := transfer.Import(ctx, &file, format, ConflictSkip, false)
+
+			require.NoError(t, err)
+			require.Equal(t, &domain.ImportReport{Read: 2, Created: 2}, report)
+		})
+	}
+}
+
+func TestImport_Policies(t *testing.T) {
+	file := `{"short":"GoodLink12","link":"google.com"}
+{"short":"Stored1234","link":"yandex.ru"}
+{"short":"GoodLink12","link":"ozon.ru"}
+`
+	stored := map[string]domain.URLLong{"Stored1234": {LongURL: "avito.ru"}}
+
+	tests := map[string]struct {
+		Policy string
+		DryRun bool
+		Report *domain.ImportReport
+		Error  error
+	}{
+		"Skip": {
+			Policy: ConflictSkip,
+			Report: &domain.ImportReport{Read: 3, Created: 1, Skipped: 2},
+		},
+		"Overwrite": {
+			Policy: ConflictOverwrite,
+			Report: &domain.ImportReport{Read: 3, Created: 1, Overwritten: 2},
+		},
+		"Fail": {
+			Policy: ConflictFail,
+			Report: &domain.ImportReport{Read: 3},
+			Error:  domain.ErrorImportConflict,
+		},
+		"Dry Run": {
+			Policy: ConflictOverwrite,
+			DryRun: true,
+			Report: &domain.ImportReport{Read: 3, Created: 1, Overwritten: 2, DryRun: true},
+		},
+	}
+
+	for title, test := range tests {
+		t.Run(title, func(t *testing.T) {
+			ctx := context.Background()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			db := mocks.NewMockIUrlStorage(ctrl)
+			transfer := NewTransferService(db, 10)
+
+			// the repeated short link splits the file in two batches
+			db.EXPECT().GetUrls(ctx, []string{"GoodLink12", "Stored1234"}).Return(stored, nil)
+			if test.Policy == ConflictFail {
+				report, err := transfer.Import(ctx, strings.NewReader(file), FormatJSONL, test.Policy, test.DryRun)
+				require.True(t, errors.Is(err, test.Error))
+				require.Equal(t, test.Report, report)
+				return
+			}
+
+			overwrite := test.Policy == ConflictOverwrite
+			second := map[string]domain.URLLong{}
+			if !test.DryRun {
+				db.EXPECT().ImportUrls(ctx, gomock.Len(2), overwrite).DoAndReturn(func(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+					if overwrite {
+						return []error{nil, nil}, nil
+					}
+					return []error{nil, domain.ErrorShortExists}, nil
+				})
+				db.EXPECT().ImportUrls(ctx, gomock.Len(1), overwrite).DoAndReturn(func(ctx context.Context, batch []domain.URLData, overwrite bool) ([]error, error) {
+					if overwrite {
+						return []error{nil}, nil
+					}
+					return []error{domain.ErrorShortExists}, nil
+				})
+				second["GoodLink12"] = domain.URLLong{LongURL: "google.com"}
+			}
+			db.EXPECT().GetUrls(ctx, []string{"GoodLink12"}).Return(second, nil)
+
+			report, err := transfer.Import(ctx, strings.NewReader(file), FormatJSONL, test.Policy, test.DryRun)
+
+			require.NoError(t, err)
+			require.Equal(t, test.Report, report)
+		})
+	}
+}
+
+func TestImport_InvalidRecord(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	transfer := NewTransferService(db, 10)
+
+	for file, format := range map[string]string{
+		`{"short":"not a short","link":"google.com"}`:                                                                          FormatJSONL,
+		`{"short":"GoodLink12","link":"google.com","redirect":200}`:                                                            FormatJSONL,
+		`{"short":"GoodLink12","link":"google.com","expires":-1}`:                                                              FormatJSONL,
+		`{"short":"GoodLink12","link":"google.com","deleted":-1686557090}`:                                                     FormatJSONL,
+		"short,link,added,expires,alias,redirect,deleted,disabled,owner\nGoodLink12,google.com,yesterday,0,false,0,0,false,\n": FormatCSV,
+		"short,link,added,expires,alias,redirect,deleted,disabled,owner\nGoodLink12,google.com,0,0,false,303,0,false,\n":       FormatCSV,
+	} {
+		// nothing of an invalid record reaches the storage
+		_, err := transfer.Import(ctx, strings.NewReader(file), format, ConflictSkip, false)
+
+		require.True(t, errors.Is(err, domain.ErrorInvalidRecord), file)
+	}
+}
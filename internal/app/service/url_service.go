@@ -2,34 +2,58 @@ package service
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 )
 
-const timeout = 3 * time.Second
+// DefaultTimeout is the time a create spends on generated short links that collide
+const DefaultTimeout = 3 * time.Second
+
+const (
+	statsPeriod   = 7 * 24 * 60 * 60 // default stats range in seconds
+	statsInterval = 24 * 60 * 60     // default bucket size in seconds
+	maxBuckets    = 1000
+	MaxBatch      = 10000 // links created by a single CreateUrls
+)
 
 type UrlService struct {
 	DB       domain.IUrlStorage
 	Generate domain.IGenerateLinkService
+	Clicks   domain.IClickStorage
+	Timeout  time.Duration
 }
 
-func NewUrlService(db domain.IUrlStorage, service domain.IGenerateLinkService) *UrlService {
+func NewUrlService(db domain.IUrlStorage, service domain.IGenerateLinkService, clicks domain.IClickStorage) *UrlService {
 	return &UrlService{
 		DB:       db,
 		Generate: service,
+		Clicks:   clicks,
+		Timeout:  DefaultTimeout,
 	}
 }
 
-func (s *UrlService) CreateUrl(ctx context.Context, long string) (string, error) {
+func (s *UrlService) CreateUrl(ctx context.Context, request domain.URLRequest) (string, error) {
+
+	long := request.LongURL
+	expiresAt, err := validate(request, time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
 
-	if !CheckLink(long) {
-		return "", domain.ErrorInvalidLink
+	if request.Alias != "" {
+		return s.createAlias(ctx, request, expiresAt)
 	}
 
-	urldata := &domain.URLData{}
+	owner := domain.ActorFromContext(ctx)
+	if short, err := s.DB.GetShort(ctx, long, owner); err == nil {
+		return short, nil
+	} else if err != domain.ErrorLinkNotFound {
+		return "", err
+	}
 
-	timer := time.NewTimer(timeout)
+	timer := time.NewTimer(s.Timeout)
 	defer timer.Stop()
 
 	for {
@@ -37,37 +61,400 @@ func (s *UrlService) CreateUrl(ctx context.Context, long string) (string, error)
 		case <-timer.C:
 			return "", domain.ErrorGenerateTimeout
 		default:
-			short, now := s.Generate.GenerateShortLink()
+		}
 
-			if _, err := s.DB.GetUrl(ctx, short); err == domain.ErrorLinkNotFound {
-				urldata = domain.NewURLData(short, long, now)
-			} else if err != nil {
-				return "", err
-			}
+		short, now, err := s.Generate.GenerateShortLink()
+		if err != nil {
+			return "", err
 		}
+		urldata := domain.NewURLData(short, long, now)
+		urldata.ExpiresAt = expiresAt
+		urldata.Redirect = request.Redirect
+		urldata.Owner = owner
 
-		if urldata.URLShort != "" {
-			break
+		switch err := s.DB.AddUrl(ctx, *urldata); err {
+		case nil:
+			return short, nil
+		case domain.ErrorShortExists:
+			// generated link collided with a stored one, try the next
+			continue
+		case domain.ErrorLongExists:
+			// lost the race to a concurrent create of the same link
+			return s.DB.GetShort(ctx, long, owner)
+		default:
+			return "", err
 		}
 	}
+}
+
+// createAlias stores the link under the code chosen by the client,
+// aliases are extra codes and do not take part in the long link deduplication
+func (s *UrlService) createAlias(ctx context.Context, request domain.URLRequest, expiresAt int64) (string, error) {
+	alias := request.Alias
+	urldata := domain.NewURLData(alias, request.LongURL, time.Now().Unix())
+	urldata.ExpiresAt = expiresAt
+	urldata.Redirect = request.Redirect
+	urldata.Alias = true
+	urldata.Owner = domain.ActorFromContext(ctx)
 
-	if err := s.DB.AddUrl(ctx, *urldata); err != nil {
+	if err := s.DB.AddUrl(ctx, *urldata); err == domain.ErrorShortExists {
+		return "", domain.ErrorAliasTaken
+	} else if err != nil {
 		return "", err
 	}
 
-	return urldata.URLShort, nil
+	return alias, nil
 }
 
-func (s UrlService) GetUrl(ctx context.Context, shortUrl string) (string, error) {
+// CreateUrls creates the batch with a few storage calls instead of a call
+// per link, requests fail one by one with the errors CreateUrl returns
+func (s *UrlService) CreateUrls(ctx context.Context, requests []domain.URLRequest) ([]domain.URLResult, error) {
+	if len(requests) == 0 || len(requests) > MaxBatch {
+		return nil, domain.ErrorInvalidBatch
+	}
+
+	owner := domain.ActorFromContext(ctx)
+	now := time.Now().Unix()
 
-	if len(shortUrl) > 10 {
-		return "", domain.ErrorInvalidShort
+	results := make([]domain.URLResult, len(requests))
+	first := make(map[string]int) // long link -> index of its first generated request
+	same := make(map[int]int)     // index of a repeated generated request -> index of the first one
+	pending := make(map[int]*domain.URLData)
+	for i, request := range requests {
+		expiresAt, err := validate(request, now)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if request.Alias == "" {
+			// the same link twice in the batch gets the same short link
+			if j, ok := first[request.LongURL]; ok {
+				same[i] = j
+				continue
+			}
+			first[request.LongURL] = i
+		}
+
+		urldata := domain.NewURLData(request.Alias, request.LongURL, now)
+		urldata.ExpiresAt = expiresAt
+		urldata.Redirect = request.Redirect
+		urldata.Alias = request.Alias != ""
+		urldata.Owner = owner
+		pending[i] = urldata
+	}
+
+	timer := time.NewTimer(s.Timeout)
+	defer timer.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case <-timer.C:
+			for i := range pending {
+				results[i].Err = domain.ErrorGenerateTimeout
+			}
+			pending = nil
+			continue
+		default:
+		}
+
+		indexes := make([]int, 0, len(pending))
+		batch := make([]domain.URLData, 0, len(pending))
+		for i, urldata := range pending {
+			if !urldata.Alias {
+				var err error
+				urldata.URLShort, urldata.AddedAt, err = s.Generate.GenerateShortLink()
+				if err != nil {
+					return nil, err
+				}
+			}
+			indexes = append(indexes, i)
+			batch = append(batch, *urldata)
+		}
+
+		errs, err := s.DB.AddUrls(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, i := range indexes {
+			urldata := pending[i]
+			switch errs[k] {
+			case nil:
+				results[i].Short = urldata.URLShort
+			case domain.ErrorShortExists:
+				if !urldata.Alias {
+					// generated link collided, the next round gets a new one
+					continue
+				}
+				results[i].Err = domain.ErrorAliasTaken
+			case domain.ErrorLongExists:
+				results[i].Short, results[i].Err = s.DB.GetShort(ctx, urldata.LongURL, owner)
+			default:
+				results[i].Err = errs[k]
+			}
+			delete(pending, i)
+		}
+	}
+
+	for i, j := range same {
+		results[i] = results[j]
+	}
+
+	return results, nil
+}
+
+func (s UrlService) GetUrl(ctx context.Context, shortUrl string) (*domain.URLLong, error) {
+
+	if len(shortUrl) > AliasMaxLength {
+		return &domain.URLLong{}, domain.ErrorInvalidShort
 	}
 
 	data, err := s.DB.GetUrl(ctx, shortUrl)
 	if err != nil {
-		return "", err
+		return &domain.URLLong{}, err
+	}
+
+	now := time.Now().Unix()
+	if data.Expired(now) {
+		return &domain.URLLong{}, domain.ErrorLinkNotFound
+	}
+	if data.Gone() {
+		return &domain.URLLong{}, domain.ErrorLinkGone
+	}
+
+	click := domain.Click{
+		Short:  shortUrl,
+		At:     now,
+		Client: domain.ClientFromContext(ctx),
+	}
+	if err := s.Clicks.AddClick(ctx, click); err != nil {
+		// analytics must not break the redirect
+		log.Printf("Click record error: %v\n", err)
+	}
+
+	return data, nil
+}
+
+// ResolveUrls is GetUrl for many links at once, it is meant for link
+// previews, so the lookups are not counted as clicks
+func (s *UrlService) ResolveUrls(ctx context.Context, shorts []string) ([]domain.ResolveResult, error) {
+	if len(shorts) == 0 || len(shorts) > MaxBatch {
+		return nil, domain.ErrorInvalidBatch
 	}
 
-	return data.LongURL, nil
+	results := make([]domain.ResolveResult, len(shorts))
+	lookup := make([]string, 0, len(shorts))
+	for i, short := range shorts {
+		results[i].Short = short
+		if len(short) > AliasMaxLength {
+			results[i].Err = domain.ErrorInvalidShort
+			continue
+		}
+		lookup = append(lookup, short)
+	}
+
+	found, err := s.DB.GetUrls(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+
+		long, ok := found[results[i].Short]
+		switch {
+		case !ok || long.Expired(now):
+			results[i].Err = domain.ErrorLinkNotFound
+		case long.Gone():
+			results[i].Err = domain.ErrorLinkGone
+		default:
+			results[i].Long = &long
+		}
+	}
+
+	return results, nil
+}
+
+// GetStats counts the clicks of the link, zero values of the request
+// fall back to daily buckets over the last week
+func (s *UrlService) GetStats(ctx context.Context, request domain.StatsRequest) (*domain.Stats, error) {
+	if request.To == 0 {
+		request.To = time.Now().Unix()
+	}
+	if request.From == 0 {
+		request.From = request.To - statsPeriod
+	}
+	if request.Interval == 0 {
+		request.Interval = statsInterval
+	}
+	if request.Interval < 0 || request.From >= request.To || (request.To-request.From)/request.Interval >= maxBuckets {
+		return &domain.Stats{}, domain.ErrorInvalidStats
+	}
+
+	if err := s.authorize(ctx, request.Short); err != nil {
+		return &domain.Stats{}, err
+	}
+
+	stats, err := s.Clicks.GetStats(ctx, request)
+	if err != nil {
+		return &domain.Stats{}, err
+	}
+
+	counts := make(map[int64]int64, len(stats.Buckets))
+	for _, bucket := range stats.Buckets {
+		counts[bucket.Start] = bucket.Count
+	}
+
+	// fill the buckets without clicks
+	stats.Buckets = stats.Buckets[:0]
+	for start := request.From; start < request.To; start += request.Interval {
+		stats.Buckets = append(stats.Buckets, domain.Bucket{Start: start, Count: counts[start]})
+	}
+
+	return stats, nil
+}
+
+// DeleteUrl soft deletes the link, it can be restored until the reaper purges it
+func (s *UrlService) DeleteUrl(ctx context.Context, shortUrl string) error {
+	if len(shortUrl) > AliasMaxLength {
+		return domain.ErrorInvalidShort
+	}
+
+	if err := s.authorize(ctx, shortUrl); err != nil {
+		return err
+	}
+
+	return s.DB.DeleteUrl(ctx, shortUrl, time.Now().Unix())
+}
+
+func (s *UrlService) RestoreUrl(ctx context.Context, shortUrl string) error {
+	if len(shortUrl) > AliasMaxLength {
+		return domain.ErrorInvalidShort
+	}
+
+	if err := s.authorize(ctx, shortUrl); err != nil {
+		return err
+	}
+
+	return s.DB.RestoreUrl(ctx, shortUrl)
+}
+
+func (s *UrlService) DisableUrl(ctx context.Context, shortUrl string, disabled bool) error {
+	if len(shortUrl) > AliasMaxLength {
+		return domain.ErrorInvalidShort
+	}
+
+	if err := s.authorize(ctx, shortUrl); err != nil {
+		return err
+	}
+
+	return s.DB.DisableUrl(ctx, shortUrl, disabled)
+}
+
+// UpdateUrl retargets the link, the previous long link stays in its history
+func (s *UrlService) UpdateUrl(ctx context.Context, shortUrl string, longUrl string) error {
+	if len(shortUrl) > AliasMaxLength {
+		return domain.ErrorInvalidShort
+	}
+	if !CheckLink(longUrl) {
+		return domain.ErrorInvalidLink
+	}
+
+	if err := s.authorize(ctx, shortUrl); err != nil {
+		return err
+	}
+
+	update := domain.URLUpdate{
+		LongURL:   longUrl,
+		ChangedAt: time.Now().Unix(),
+		Actor:     domain.ActorFromContext(ctx),
+	}
+	return s.DB.UpdateUrl(ctx, shortUrl, update)
+}
+
+func (s *UrlService) GetHistory(ctx context.Context, shortUrl string) ([]domain.URLVersion, error) {
+	if len(shortUrl) > AliasMaxLength {
+		return nil, domain.ErrorInvalidShort
+	}
+
+	if err := s.authorize(ctx, shortUrl); err != nil {
+		return nil, err
+	}
+
+	return s.DB.GetHistory(ctx, shortUrl)
+}
+
+// RollbackUrl retargets the link back to the long link of the version,
+// the rollback itself is recorded as a new version
+func (s *UrlService) RollbackUrl(ctx context.Context, shortUrl string, version int64) error {
+	history, err := s.GetHistory(ctx, shortUrl)
+	if err != nil {
+		return err
+	}
+
+	for _, previous := range history {
+		if previous.Version == version {
+			return s.UpdateUrl(ctx, shortUrl, previous.LongURL)
+		}
+	}
+
+	return domain.ErrorVersionNotFound
+}
+
+// authorize lets only the owner or the admin manage the link, the owner is the actor
+// the link was created by, links made without authentication have no owner
+func (s *UrlService) authorize(ctx context.Context, shortUrl string) error {
+	long, err := s.DB.GetUrl(ctx, shortUrl)
+	if err != nil {
+		return err
+	}
+
+	if !domain.IsAdmin(ctx) && long.Owner != domain.ActorFromContext(ctx) {
+		return domain.ErrorForbidden
+	}
+
+	return nil
+}
+
+// validate checks the request and resolves its expiry time
+func validate(request domain.URLRequest, now int64) (int64, error) {
+	if !CheckLink(request.LongURL) {
+		return 0, domain.ErrorInvalidLink
+	}
+
+	expiresAt, err := expiry(request, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if !CheckRedirect(request.Redirect) {
+		return 0, domain.ErrorInvalidRedirect
+	}
+
+	if request.Alias != "" && !CheckAlias(request.Alias) {
+		return 0, domain.ErrorInvalidAlias
+	}
+
+	return expiresAt, nil
+}
+
+// expiry resolves the absolute expiry time of the request, zero means never
+func expiry(request domain.URLRequest, now int64) (int64, error) {
+	switch {
+	case request.ExpiresAt != 0:
+		if request.ExpiresAt <= now {
+			return 0, domain.ErrorInvalidExpiry
+		}
+		return request.ExpiresAt, nil
+	case request.TTL < 0:
+		return 0, domain.ErrorInvalidExpiry
+	case request.TTL > 0:
+		return now + request.TTL, nil
+	default:
+		return 0, nil
+	}
 }
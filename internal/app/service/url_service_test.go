@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Totus-Floreo/shortURL/internal/app/domain"
 	"github.com/Totus-Floreo/shortURL/internal/app/domain/mocks"
@@ -16,7 +19,7 @@ type TestCase struct {
 	Short       string
 	Long        string
 	AddedAt     int64
-	GetUrlError error
+	AddUrlError error
 	Error       error
 }
 
@@ -26,7 +29,7 @@ var CreateTests = []TestCase{
 		Short:       "GoodLink12",
 		Long:        "google.com",
 		AddedAt:     1686557090,
-		GetUrlError: domain.ErrorLinkNotFound,
+		AddUrlError: nil,
 		Error:       nil,
 	},
 	TestCase{
@@ -34,23 +37,23 @@ var CreateTests = []TestCase{
 		Short:       "",
 		Long:        "google.com",
 		AddedAt:     0,
-		GetUrlError: nil,
+		AddUrlError: domain.ErrorShortExists,
 		Error:       domain.ErrorGenerateTimeout,
 	},
 	TestCase{
-		Name:        "GetUrl Fatal",
-		Short:       "",
+		Name:        "Collision Retry",
+		Short:       "Collided12",
 		Long:        "google.com",
-		AddedAt:     0,
-		GetUrlError: ErrorDBShutdown,
-		Error:       ErrorDBShutdown,
+		AddedAt:     1686557090,
+		AddUrlError: domain.ErrorShortExists,
+		Error:       nil,
 	},
 	TestCase{
 		Name:        "Add Url Error",
 		Short:       "BadLuck123",
 		Long:        "google.com",
 		AddedAt:     1686557090,
-		GetUrlError: domain.ErrorLinkNotFound,
+		AddUrlError: ErrorDBShutdown,
 		Error:       ErrorDBShutdown,
 	},
 }
@@ -65,17 +68,17 @@ func TestCreateUrl_Success(t *testing.T) {
 
 	db := mocks.NewMockIUrlStorage(ctrl)
 	generator := mocks.NewMockIGenerateLinkService(ctrl)
-	service := NewUrlService(db, generator)
-
-	generator.EXPECT().GenerateShortLink().Return(CreateTests[0].Short, CreateTests[0].AddedAt)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
 
-	db.EXPECT().GetUrl(ctx, CreateTests[0].Short).Return(&domain.URLLong{}, CreateTests[0].GetUrlError)
+	db.EXPECT().GetShort(ctx, CreateTests[0].Long, "").Return("", domain.ErrorLinkNotFound)
+	generator.EXPECT().GenerateShortLink().Return(CreateTests[0].Short, CreateTests[0].AddedAt, nil)
 
 	urldata := domain.NewURLData(CreateTests[0].Short, CreateTests[0].Long, CreateTests[0].AddedAt)
 
-	db.EXPECT().AddUrl(ctx, *urldata).Return(nil)
+	db.EXPECT().AddUrl(ctx, *urldata).Return(CreateTests[0].AddUrlError)
 
-	short, err := service.CreateUrl(ctx, CreateTests[0].Long)
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[0].Long})
 
 	require.NoError(t, err)
 	require.Equal(t, CreateTests[0].Short, short)
@@ -89,20 +92,23 @@ func TestCreateUrl_Timeout(t *testing.T) {
 
 	db := mocks.NewMockIUrlStorage(ctrl)
 	generator := mocks.NewMockIGenerateLinkService(ctrl)
-	service := NewUrlService(db, generator)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+	service.Timeout = 50 * time.Millisecond
 
-	generator.EXPECT().GenerateShortLink().Return(CreateTests[1].Short, CreateTests[1].AddedAt).AnyTimes()
+	db.EXPECT().GetShort(ctx, CreateTests[1].Long, "").Return("", domain.ErrorLinkNotFound)
+	generator.EXPECT().GenerateShortLink().Return(CreateTests[1].Short, CreateTests[1].AddedAt, nil).AnyTimes()
 
-	db.EXPECT().GetUrl(ctx, CreateTests[1].Short).Return(&domain.URLLong{}, CreateTests[1].GetUrlError).AnyTimes()
+	db.EXPECT().AddUrl(ctx, gomock.Any()).Return(CreateTests[1].AddUrlError).AnyTimes()
 
-	short, err := service.CreateUrl(ctx, CreateTests[1].Long)
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[1].Long})
 
 	require.Error(t, err)
 	require.Equal(t, CreateTests[1].Error, err)
 	require.Equal(t, CreateTests[1].Short, short)
 }
 
-func TestCreateUrl_GetUrlError(t *testing.T) {
+func TestCreateUrl_CollisionRetry(t *testing.T) {
 	ctx := context.Background()
 
 	ctrl := gomock.NewController(t)
@@ -110,17 +116,27 @@ func TestCreateUrl_GetUrlError(t *testing.T) {
 
 	db := mocks.NewMockIUrlStorage(ctrl)
 	generator := mocks.NewMockIGenerateLinkService(ctrl)
-	service := NewUrlService(db, generator)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
 
-	generator.EXPECT().GenerateShortLink().Return(CreateTests[2].Short, CreateTests[2].AddedAt)
+	db.EXPECT().GetShort(ctx, CreateTests[2].Long, "").Return("", domain.ErrorLinkNotFound)
+	gomock.InOrder(
+		generator.EXPECT().GenerateShortLink().Return(CreateTests[2].Short, CreateTests[2].AddedAt, nil),
+		generator.EXPECT().GenerateShortLink().Return(CreateTests[0].Short, CreateTests[0].AddedAt, nil),
+	)
 
-	db.EXPECT().GetUrl(ctx, CreateTests[2].Short).Return(&domain.URLLong{}, CreateTests[2].GetUrlError)
+	collided := domain.NewURLData(CreateTests[2].Short, CreateTests[2].Long, CreateTests[2].AddedAt)
+	urldata := domain.NewURLData(CreateTests[0].Short, CreateTests[0].Long, CreateTests[0].AddedAt)
 
-	short, err := service.CreateUrl(ctx, CreateTests[2].Long)
+	gomock.InOrder(
+		db.EXPECT().AddUrl(ctx, *collided).Return(CreateTests[2].AddUrlError),
+		db.EXPECT().AddUrl(ctx, *urldata).Return(nil),
+	)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[2].Long})
 
-	require.Error(t, err)
 	require.Equal(t, CreateTests[2].Error, err)
-	require.Equal(t, CreateTests[2].Short, short)
+	require.Equal(t, CreateTests[0].Short, short)
 }
 
 func TestCreateUrl_AddUrlError(t *testing.T) {
@@ -131,22 +147,85 @@ func TestCreateUrl_AddUrlError(t *testing.T) {
 
 	db := mocks.NewMockIUrlStorage(ctrl)
 	generator := mocks.NewMockIGenerateLinkService(ctrl)
-	service := NewUrlService(db, generator)
-
-	generator.EXPECT().GenerateShortLink().Return(CreateTests[3].Short, CreateTests[3].AddedAt)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
 
-	db.EXPECT().GetUrl(ctx, CreateTests[3].Short).Return(&domain.URLLong{}, CreateTests[3].GetUrlError)
+	db.EXPECT().GetShort(ctx, CreateTests[3].Long, "").Return("", domain.ErrorLinkNotFound)
+	generator.EXPECT().GenerateShortLink().Return(CreateTests[3].Short, CreateTests[3].AddedAt, nil)
 
 	urldata := domain.NewURLData(CreateTests[3].Short, CreateTests[3].Long, CreateTests[3].AddedAt)
 
-	db.EXPECT().AddUrl(ctx, *urldata).Return(ErrorDBShutdown)
+	db.EXPECT().AddUrl(ctx, *urldata).Return(CreateTests[3].AddUrlError)
 
-	_, err := service.CreateUrl(ctx, CreateTests[3].Long)
+	_, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[3].Long})
 
 	require.Error(t, err)
 	require.Equal(t, CreateTests[3].Error, err)
 }
 
+func TestCreateUrl_Exists(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetShort(ctx, CreateTests[0].Long, "").Return(CreateTests[0].Short, nil)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[0].Long})
+
+	require.NoError(t, err)
+	require.Equal(t, CreateTests[0].Short, short)
+}
+
+func TestCreateUrl_GetShortError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetShort(ctx, CreateTests[0].Long, "").Return("", ErrorDBShutdown)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[0].Long})
+
+	require.Equal(t, ErrorDBShutdown, err)
+	require.Equal(t, "", short)
+}
+
+func TestCreateUrl_LongExistsRace(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	gomock.InOrder(
+		db.EXPECT().GetShort(ctx, CreateTests[0].Long, "").Return("", domain.ErrorLinkNotFound),
+		db.EXPECT().GetShort(ctx, CreateTests[0].Long, "").Return("RaceWin123", nil),
+	)
+	generator.EXPECT().GenerateShortLink().Return(CreateTests[0].Short, CreateTests[0].AddedAt, nil)
+	urldata := domain.NewURLData(CreateTests[0].Short, CreateTests[0].Long, CreateTests[0].AddedAt)
+	db.EXPECT().AddUrl(ctx, *urldata).Return(domain.ErrorLongExists)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: CreateTests[0].Long})
+
+	require.NoError(t, err)
+	require.Equal(t, "RaceWin123", short)
+}
+
 func TestCreateUrl_InvalidLink(t *testing.T) {
 	test := TestCase{
 		Name:    "Invalid long link",
@@ -162,14 +241,306 @@ func TestCreateUrl_InvalidLink(t *testing.T) {
 
 	db := mocks.NewMockIUrlStorage(ctrl)
 	generator := NewGenerateLinkService()
-	service := NewUrlService(db, generator)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
 
-	short, err := service.CreateUrl(ctx, test.Long)
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: test.Long})
 
 	require.Equal(t, test.Error, err)
 	require.Equal(t, test.Short, short)
 }
 
+func TestCreateUrl_InvalidExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com", ExpiresAt: 1})
+
+	require.Equal(t, domain.ErrorInvalidExpiry, err)
+	require.Equal(t, "", short)
+}
+
+func TestCreateUrl_Alias(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().AddUrl(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, urldata domain.URLData) error {
+		require.Equal(t, "summer_sale", urldata.URLShort)
+		require.Equal(t, "google.com", urldata.LongURL)
+		require.True(t, urldata.Alias)
+		return nil
+	})
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com", Alias: "summer_sale"})
+
+	require.NoError(t, err)
+	require.Equal(t, "summer_sale", short)
+}
+
+func TestCreateUrl_AliasTaken(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().AddUrl(ctx, gomock.Any()).Return(domain.ErrorShortExists)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com", Alias: "summer_sale"})
+
+	require.Equal(t, domain.ErrorAliasTaken, err)
+	require.Equal(t, "", short)
+}
+
+func TestCreateUrl_InvalidAlias(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com", Alias: "summer-sale"})
+
+	require.Equal(t, domain.ErrorInvalidAlias, err)
+	require.Equal(t, "", short)
+}
+
+func TestCreateUrl_InvalidRedirect(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com", Redirect: 200})
+
+	require.Equal(t, domain.ErrorInvalidRedirect, err)
+	require.Equal(t, "", short)
+}
+
+func TestCreateUrls(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	var generated int
+	generator.EXPECT().GenerateShortLink().DoAndReturn(func() (string, int64, error) {
+		generated++
+		return fmt.Sprintf("Generated%d", generated), int64(1686557090), nil
+	}).AnyTimes()
+
+	first := db.EXPECT().AddUrls(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, batch []domain.URLData) ([]error, error) {
+		// the repeated and the invalid links do not reach the storage
+		require.Len(t, batch, 4)
+
+		errs := make([]error, len(batch))
+		for i, urldata := range batch {
+			switch urldata.LongURL {
+			case "google.com":
+				errs[i] = domain.ErrorLongExists
+			case "yandex.ru", "ozon.ru":
+				// a generated link and an alias collide
+				errs[i] = domain.ErrorShortExists
+			}
+		}
+		return errs, nil
+	})
+	db.EXPECT().AddUrls(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, batch []domain.URLData) ([]error, error) {
+		// only the collided generated link is retried
+		require.Len(t, batch, 1)
+		require.Equal(t, "yandex.ru", batch[0].LongURL)
+		return []error{nil}, nil
+	}).After(first)
+	db.EXPECT().GetShort(ctx, "google.com", "").Return("Existing12", nil)
+
+	requests := []domain.URLRequest{
+		{LongURL: "google.com"},
+		{LongURL: "yandex.ru"},
+		{LongURL: "yandex.ru"},
+		{LongURL: "not a link"},
+		{LongURL: "ozon.ru", Alias: "summer_sale"},
+		{LongURL: "avito.ru", Alias: "winter_sale"},
+	}
+
+	results, err := service.CreateUrls(ctx, requests)
+
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLResult{
+		{Short: "Existing12"},
+		{Short: "Generated3"},
+		{Short: "Generated3"},
+		{Err: domain.ErrorInvalidLink},
+		{Err: domain.ErrorAliasTaken},
+		{Short: "winter_sale"},
+	}, results)
+}
+
+func TestCreateUrls_InvalidBatch(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	_, err := service.CreateUrls(ctx, nil)
+	require.Equal(t, domain.ErrorInvalidBatch, err)
+
+	_, err = service.CreateUrls(ctx, make([]domain.URLRequest, MaxBatch+1))
+	require.Equal(t, domain.ErrorInvalidBatch, err)
+}
+
+func TestCreateUrls_StorageError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	generator.EXPECT().GenerateShortLink().Return("GoodLink12", int64(1686557090), nil)
+	db.EXPECT().AddUrls(ctx, gomock.Any()).Return(nil, ErrorDBShutdown)
+
+	_, err := service.CreateUrls(ctx, []domain.URLRequest{{LongURL: "google.com"}})
+
+	require.Equal(t, ErrorDBShutdown, err)
+}
+
+func TestResolveUrls(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	tooLong := strings.Repeat("a", AliasMaxLength+1)
+	db.EXPECT().GetUrls(ctx, []string{"GoodLink12", "Missing123", "Expired123", "Deleted123"}).Return(map[string]domain.URLLong{
+		"GoodLink12": {LongURL: "google.com"},
+		"Expired123": {LongURL: "google.com", ExpiresAt: 1},
+		"Deleted123": {LongURL: "google.com", DeletedAt: 1686557090},
+	}, nil)
+
+	// lookups are not clicks, the click storage is never called
+	results, err := service.ResolveUrls(ctx, []string{"GoodLink12", "Missing123", "Expired123", "Deleted123", tooLong})
+
+	require.NoError(t, err)
+	require.Equal(t, []domain.ResolveResult{
+		{Short: "GoodLink12", Long: &domain.URLLong{LongURL: "google.com"}},
+		{Short: "Missing123", Err: domain.ErrorLinkNotFound},
+		{Short: "Expired123", Err: domain.ErrorLinkNotFound},
+		{Short: "Deleted123", Err: domain.ErrorLinkGone},
+		{Short: tooLong, Err: domain.ErrorInvalidShort},
+	}, results)
+}
+
+func TestResolveUrls_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	_, err := service.ResolveUrls(ctx, nil)
+	require.Equal(t, domain.ErrorInvalidBatch, err)
+
+	db.EXPECT().GetUrls(ctx, []string{"GoodLink12"}).Return(nil, ErrorDBShutdown)
+
+	_, err = service.ResolveUrls(ctx, []string{"GoodLink12"})
+	require.Equal(t, ErrorDBShutdown, err)
+}
+
+func TestExpiry(t *testing.T) {
+	var now int64 = 1686557090
+
+	var tests = []struct {
+		Name      string
+		Request   domain.URLRequest
+		ExpiresAt int64
+		Error     error
+	}{
+		{"Never", domain.URLRequest{}, 0, nil},
+		{"TTL", domain.URLRequest{TTL: 60}, now + 60, nil},
+		{"Absolute", domain.URLRequest{ExpiresAt: now + 10}, now + 10, nil},
+		{"Absolute over TTL", domain.URLRequest{TTL: 60, ExpiresAt: now + 10}, now + 10, nil},
+		{"Negative TTL", domain.URLRequest{TTL: -1}, 0, domain.ErrorInvalidExpiry},
+		{"Past expiry", domain.URLRequest{ExpiresAt: now}, 0, domain.ErrorInvalidExpiry},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			expiresAt, err := expiry(test.Request, now)
+
+			require.Equal(t, test.Error, err)
+			require.Equal(t, test.ExpiresAt, expiresAt)
+		})
+	}
+}
+
+func TestGetUrl_Expired(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "google.com", AddedAt: 1686557090, ExpiresAt: 1686557091}, nil)
+
+	long, err := service.GetUrl(ctx, "GoodLink12")
+
+	require.Equal(t, domain.ErrorLinkNotFound, err)
+	require.Equal(t, "", long.LongURL)
+}
+
 func TestGetUrl(t *testing.T) {
 	var tests = []TestCase{
 		TestCase{
@@ -188,7 +559,7 @@ func TestGetUrl(t *testing.T) {
 		},
 		TestCase{
 			Name:    "Len Error",
-			Short:   "0123456789more",
+			Short:   "0123456789012345678901234567890123456789",
 			Long:    "",
 			AddedAt: 0,
 			Error:   domain.ErrorInvalidShort,
@@ -201,7 +572,8 @@ func TestGetUrl(t *testing.T) {
 
 	db := mocks.NewMockIUrlStorage(ctrl)
 	generator := NewGenerateLinkService()
-	service := NewUrlService(db, generator)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
@@ -213,11 +585,315 @@ func TestGetUrl(t *testing.T) {
 			if test.Name != "Len Error" {
 				db.EXPECT().GetUrl(ctx, test.Short).Return(&urlLong, test.Error)
 			}
+			if test.Error == nil {
+				clicks.EXPECT().AddClick(ctx, gomock.Any()).Return(nil)
+			}
 
 			long, err := service.GetUrl(ctx, test.Short)
 
 			require.Equal(t, test.Error, err)
-			require.Equal(t, test.Long, long)
+			require.Equal(t, test.Long, long.LongURL)
+		})
+	}
+}
+
+func TestGetUrl_ClickError(t *testing.T) {
+	client := domain.Client{Referrer: "ya.ru", UserAgent: "curl/8.0", IP: "127.0.0.1"}
+	ctx := domain.WithClient(context.Background(), client)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "google.com", AddedAt: 1686557090}, nil)
+	clicks.EXPECT().AddClick(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, click domain.Click) error {
+		require.Equal(t, "GoodLink12", click.Short)
+		require.Equal(t, client, click.Client)
+		return ErrorDBShutdown
+	})
+
+	long, err := service.GetUrl(ctx, "GoodLink12")
+
+	require.NoError(t, err)
+	require.Equal(t, "google.com", long.LongURL)
+}
+
+func TestGetStats(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	request := domain.StatsRequest{Short: "GoodLink12", From: 1000, To: 1300, Interval: 100}
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "google.com"}, nil)
+	clicks.EXPECT().GetStats(ctx, request).Return(&domain.Stats{
+		Short:   "GoodLink12",
+		Total:   7,
+		Buckets: []domain.Bucket{{Start: 1100, Count: 3}},
+	}, nil)
+
+	stats, err := service.GetStats(ctx, request)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(7), stats.Total)
+	require.Equal(t, []domain.Bucket{{Start: 1000}, {Start: 1100, Count: 3}, {Start: 1200}}, stats.Buckets)
+}
+
+func TestGetStats_Defaults(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "google.com"}, nil)
+	clicks.EXPECT().GetStats(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, request domain.StatsRequest) (*domain.Stats, error) {
+		require.Equal(t, int64(statsInterval), request.Interval)
+		require.Equal(t, int64(statsPeriod), request.To-request.From)
+		return &domain.Stats{Short: request.Short}, nil
+	})
+
+	stats, err := service.GetStats(ctx, domain.StatsRequest{Short: "GoodLink12"})
+
+	require.NoError(t, err)
+	require.Len(t, stats.Buckets, statsPeriod/statsInterval)
+}
+
+func TestGetStats_Errors(t *testing.T) {
+	var tests = []struct {
+		Name    string
+		Request domain.StatsRequest
+		Error   error
+	}{
+		{
+			Name:    "Negative Interval",
+			Request: domain.StatsRequest{Short: "GoodLink12", From: 1000, To: 2000, Interval: -1},
+			Error:   domain.ErrorInvalidStats,
+		},
+		{
+			Name:    "Empty Period",
+			Request: domain.StatsRequest{Short: "GoodLink12", From: 2000, To: 1000},
+			Error:   domain.ErrorInvalidStats,
+		},
+		{
+			Name:    "Too Many Buckets",
+			Request: domain.StatsRequest{Short: "GoodLink12", From: 1, To: 100000, Interval: 1},
+			Error:   domain.ErrorInvalidStats,
+		},
+		{
+			Name:    "Not Found",
+			Request: domain.StatsRequest{Short: "BadLink123", From: 1000, To: 2000, Interval: 100},
+			Error:   domain.ErrorLinkNotFound,
+		},
+	}
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.Error == domain.ErrorLinkNotFound {
+				db.EXPECT().GetUrl(ctx, test.Request.Short).Return(&domain.URLLong{}, test.Error)
+			}
+
+			_, err := service.GetStats(ctx, test.Request)
+
+			require.Equal(t, test.Error, err)
 		})
 	}
 }
+
+func TestGetUrl_Gone(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetUrl(ctx, "Deleted123").Return(&domain.URLLong{LongURL: "google.com", DeletedAt: 1686557090}, nil)
+	db.EXPECT().GetUrl(ctx, "Disabled12").Return(&domain.URLLong{LongURL: "google.com", Disabled: true}, nil)
+
+	_, err := service.GetUrl(ctx, "Deleted123")
+	require.Equal(t, domain.ErrorLinkGone, err)
+
+	_, err = service.GetUrl(ctx, "Disabled12")
+	require.Equal(t, domain.ErrorLinkGone, err)
+}
+
+func TestDeleteUrl(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "google.com"}, nil).Times(3)
+	db.EXPECT().DeleteUrl(ctx, "GoodLink12", gomock.Any()).Return(nil)
+	db.EXPECT().RestoreUrl(ctx, "GoodLink12").Return(domain.ErrorLinkNotFound)
+	db.EXPECT().DisableUrl(ctx, "GoodLink12", true).Return(nil)
+
+	require.NoError(t, service.DeleteUrl(ctx, "GoodLink12"))
+	require.Equal(t, domain.ErrorLinkNotFound, service.RestoreUrl(ctx, "GoodLink12"))
+	require.NoError(t, service.DisableUrl(ctx, "GoodLink12", true))
+
+	long := "0123456789012345678901234567890123456789"
+	require.Equal(t, domain.ErrorInvalidShort, service.DeleteUrl(ctx, long))
+	require.Equal(t, domain.ErrorInvalidShort, service.RestoreUrl(ctx, long))
+	require.Equal(t, domain.ErrorInvalidShort, service.DisableUrl(ctx, long, false))
+}
+
+func TestUpdateUrl(t *testing.T) {
+	ctx := domain.WithActor(context.Background(), "127.0.0.1")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "google.com", Owner: "127.0.0.1"}, nil)
+	db.EXPECT().UpdateUrl(ctx, "GoodLink12", gomock.Any()).DoAndReturn(func(ctx context.Context, short string, update domain.URLUpdate) error {
+		require.Equal(t, "yandex.ru", update.LongURL)
+		require.Equal(t, "127.0.0.1", update.Actor)
+		require.NotZero(t, update.ChangedAt)
+		return nil
+	})
+
+	require.NoError(t, service.UpdateUrl(ctx, "GoodLink12", "yandex.ru"))
+	require.Equal(t, domain.ErrorInvalidLink, service.UpdateUrl(ctx, "GoodLink12", "not a link"))
+	require.Equal(t, domain.ErrorInvalidShort, service.UpdateUrl(ctx, "0123456789012345678901234567890123456789", "yandex.ru"))
+}
+
+func TestRollbackUrl(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	history := []domain.URLVersion{
+		{Version: 1, LongURL: "google.com", ChangedAt: 1686557090},
+		{Version: 2, LongURL: "yandex.ru", ChangedAt: 1686557091},
+	}
+	db.EXPECT().GetUrl(ctx, "GoodLink12").Return(&domain.URLLong{LongURL: "yandex.ru"}, nil).Times(3)
+	db.EXPECT().GetHistory(ctx, "GoodLink12").Return(history, nil).Times(2)
+	db.EXPECT().UpdateUrl(ctx, "GoodLink12", gomock.Any()).DoAndReturn(func(ctx context.Context, short string, update domain.URLUpdate) error {
+		require.Equal(t, "google.com", update.LongURL)
+		return nil
+	})
+
+	require.NoError(t, service.RollbackUrl(ctx, "GoodLink12", 1))
+	require.Equal(t, domain.ErrorVersionNotFound, service.RollbackUrl(ctx, "GoodLink12", 3))
+
+	db.EXPECT().GetUrl(ctx, "BadLink123").Return(&domain.URLLong{}, domain.ErrorLinkNotFound)
+
+	require.Equal(t, domain.ErrorLinkNotFound, service.RollbackUrl(ctx, "BadLink123", 1))
+}
+
+func TestAuthorize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	owned := &domain.URLLong{LongURL: "google.com", Owner: "0123456789abcdef"}
+	db.EXPECT().GetUrl(gomock.Any(), "GoodLink12").Return(owned, nil).AnyTimes()
+
+	owner := domain.WithActor(context.Background(), "0123456789abcdef")
+	stranger := domain.WithActor(context.Background(), "fedcba9876543210")
+
+	require.Equal(t, domain.ErrorForbidden, service.DeleteUrl(stranger, "GoodLink12"))
+	require.Equal(t, domain.ErrorForbidden, service.DeleteUrl(context.Background(), "GoodLink12"))
+	require.Equal(t, domain.ErrorForbidden, service.UpdateUrl(stranger, "GoodLink12", "yandex.ru"))
+	_, err := service.GetStats(stranger, domain.StatsRequest{Short: "GoodLink12"})
+	require.Equal(t, domain.ErrorForbidden, err)
+
+	db.EXPECT().DisableUrl(owner, "GoodLink12", true).Return(nil)
+
+	require.NoError(t, service.DisableUrl(owner, "GoodLink12", true))
+
+	// the admin manages the links of every owner
+	admin := domain.WithAdmin(context.Background())
+	db.EXPECT().DeleteUrl(admin, "GoodLink12", gomock.Any()).Return(nil)
+
+	require.NoError(t, service.DeleteUrl(admin, "GoodLink12"))
+}
+
+func TestCreateUrl_Owner(t *testing.T) {
+	ctx := domain.WithActor(context.Background(), "0123456789abcdef")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetShort(ctx, "google.com", "0123456789abcdef").Return("", domain.ErrorLinkNotFound)
+	generator.EXPECT().GenerateShortLink().Return("GoodLink12", int64(1686557090), nil)
+	db.EXPECT().AddUrl(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, urldata domain.URLData) error {
+		require.Equal(t, "0123456789abcdef", urldata.Owner)
+		return nil
+	})
+
+	short, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com"})
+
+	require.NoError(t, err)
+	require.Equal(t, "GoodLink12", short)
+}
+
+func TestCreateUrl_GenerateError(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockIUrlStorage(ctrl)
+	generator := mocks.NewMockIGenerateLinkService(ctrl)
+	clicks := mocks.NewMockIClickStorage(ctrl)
+	service := NewUrlService(db, generator, clicks)
+
+	db.EXPECT().GetShort(ctx, "google.com", "").Return("", domain.ErrorLinkNotFound)
+	generator.EXPECT().GenerateShortLink().Return("", int64(0), domain.ErrorClockOverflow)
+
+	_, err := service.CreateUrl(ctx, domain.URLRequest{LongURL: "google.com"})
+
+	require.Equal(t, domain.ErrorClockOverflow, err)
+}
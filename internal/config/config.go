@@ -0,0 +1,393 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Totus-Floreo/shortURL/internal/app/service"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variable of every setting,
+// the variable of -cacheTTL is SHORTURL_CACHE_TTL
+const EnvPrefix = "SHORTURL_"
+
+// legacyEnv are the variables the server was configured with before the config file,
+// they are read when the prefixed variable is not set
+var legacyEnv = map[string]string{
+	"httpAddr":   "httpport",
+	"grpcAddr":   "gRPCport",
+	"pgURL":      "pg_url",
+	"adminToken": "admin_token",
+}
+
+// Config is every setting of the server. Load starts from Default and overrides
+// it with the config file, then the environment and then the flags
+type Config struct {
+	HTTP             HTTPConfig    `yaml:"http"`
+	GRPC             GRPCConfig    `yaml:"grpc"`
+	Auth             AuthConfig    `yaml:"auth"`
+	RateLimits       string        `yaml:"rate_limits"`        // token buckets per client as scope=rate:burst pairs
+	RateLimitClients int           `yaml:"rate_limit_clients"` // token buckets kept at once, the least recently used are dropped past it
+	ShutdownDelay    time.Duration `yaml:"shutdown_delay"`     // time the readiness probes fail for before the servers drain
+	ShutdownTimeout  time.Duration `yaml:"shutdown_timeout"`   // time to drain the servers and flush the clicks after a SIGTERM
+	DB               DBConfig      `yaml:"db"`
+	Cache            CacheConfig   `yaml:"cache"`
+	Links            LinksConfig   `yaml:"links"`
+	Clicks           ClicksConfig  `yaml:"clicks"`
+}
+
+type HTTPConfig struct {
+	Addr           string   `yaml:"addr"`
+	TrustedProxies []string `yaml:"trusted_proxies"` // IPs and CIDRs whose X-Forwarded-For is believed, empty trusts none
+}
+
+type GRPCConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+type AuthConfig struct {
+	Enabled    bool   `yaml:"enabled"`     // require an api key to create and manage links, needs the admin token to issue keys
+	AdminToken string `yaml:"admin_token"` // empty turns the api key management and, with auth off, the link management off
+}
+
+type DBConfig struct {
+	Type     string         `yaml:"type"`    // pgx, sqlite or inmemory
+	Migrate  bool           `yaml:"migrate"` // apply the pending migrations of the pgx database or create the sqlite tables at startup
+	Postgres PostgresConfig `yaml:"postgres"`
+	SQLite   SQLiteConfig   `yaml:"sqlite"`
+	InMemory InMemoryConfig `yaml:"inmemory"`
+}
+
+// PostgresConfig of the pgx database, zero pool settings keep the pgx defaults
+type PostgresConfig struct {
+	URL              string        `yaml:"url"` // the postgres:// scheme may be left out, empty connects by the PG* variables
+	MaxConns         int           `yaml:"max_conns"`
+	MinConns         int           `yaml:"min_conns"`
+	ConnLifetime     time.Duration `yaml:"conn_lifetime"`
+	ConnIdle         time.Duration `yaml:"conn_idle"`
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+}
+
+type SQLiteConfig struct {
+	Path string `yaml:"path"` // the database file, created if it does not exist
+}
+
+type InMemoryConfig struct {
+	DataDir         string        `yaml:"data_dir"` // empty keeps the database in memory only
+	DataSync        bool          `yaml:"data_sync"`
+	Shards          int           `yaml:"shards"` // zero keeps the links under a single lock
+	CompactInterval time.Duration `yaml:"compact_interval"`
+}
+
+type CacheConfig struct {
+	Size        int           `yaml:"size"` // zero turns the cache off
+	TTL         time.Duration `yaml:"ttl"`
+	NegativeTTL time.Duration `yaml:"negative_ttl"`
+	LoadTimeout time.Duration `yaml:"load_timeout"` // a load shared by concurrent misses is not canceled by them
+}
+
+type LinksConfig struct {
+	Generator       string        `yaml:"generator"` // snowflake or random
+	NodeID          int64         `yaml:"node_id"`
+	Length          int           `yaml:"length"`
+	GenerateTimeout time.Duration `yaml:"generate_timeout"`
+	Redirect        int           `yaml:"redirect"`
+	ReapInterval    time.Duration `yaml:"reap_interval"`
+	RestoreWindow   time.Duration `yaml:"restore_window"`
+}
+
+type ClicksConfig struct {
+	Queue   int           `yaml:"queue"`
+	Batch   int           `yaml:"batch"`
+	Workers int           `yaml:"workers"`
+	Flush   time.Duration `yaml:"flush"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP:             HTTPConfig{Addr: ":8080"},
+		GRPC:             GRPCConfig{Addr: ":50051"},
+		RateLimits:       "POST /=1:20,/pb.ShortUrl/CreateUrl=1:20,POST /api/links/batch=0.1:2,/pb.ShortUrl/CreateUrls=0.1:2,POST /api/resolve=10:100,/pb.ShortUrl/ResolveStream=1:10",
+		RateLimitClients: service.DefaultMaxClients,
+		ShutdownDelay:    5 * time.Second,
+		ShutdownTimeout:  10 * time.Second,
+		DB: DBConfig{
+			Type:     "pgx",
+			Migrate:  true,
+			SQLite:   SQLiteConfig{Path: "shorturl.db"},
+			InMemory: InMemoryConfig{CompactInterval: 10 * time.Minute},
+		},
+		Cache: CacheConfig{TTL: time.Minute, NegativeTTL: 5 * time.Second, LoadTimeout: 5 * time.Second},
+		Links: LinksConfig{
+			Generator:       "snowflake",
+			Length:          service.Length,
+			GenerateTimeout: 3 * time.Second,
+			Redirect:        http.StatusFound,
+			ReapInterval:    time.Minute,
+			RestoreWindow:   30 * 24 * time.Hour,
+		},
+		Clicks: ClicksConfig{Queue: 10000, Batch: 500, Workers: 2, Flush: time.Second},
+	}
+}
+
+// Load binds the settings to the flags next to the ones the caller defined,
+// parses the args and returns the validated config. The file comes from -config
+// or SHORTURL_CONFIG, .yaml, .yml and .toml files are read
+func Load(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	path := flags.String("config", "", "YAML or TOML config file, the environment and the flags override it")
+	c.bind(flags)
+
+	// the first parse only finds the file, the flags are parsed again over it
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if *path == "" {
+		*path, _ = lookupEnv(EnvPrefix + "CONFIG")
+	}
+	if *path != "" {
+		if err := c.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.readEnv(flags, lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) bind(flags *flag.FlagSet) {
+	flags.StringVar(&c.HTTP.Addr, "httpAddr", c.HTTP.Addr, "Address of the http server")
+	flags.Var((*listValue)(&c.HTTP.TrustedProxies), "trustedProxies", "Comma separated IPs and CIDRs of the proxies whose X-Forwarded-For gives the client IP, empty trusts none")
+	flags.StringVar(&c.GRPC.Addr, "grpcAddr", c.GRPC.Addr, "Address of the gRPC server")
+	flags.BoolVar(&c.Auth.Enabled, "auth", c.Auth.Enabled, "Require an api key to create and manage links")
+	flags.StringVar(&c.Auth.AdminToken, "adminToken", c.Auth.AdminToken, "Token of the api key management and of the link management without auth, empty turns them off")
+	flags.DurationVar(&c.ShutdownDelay, "shutdownDelay", c.ShutdownDelay, "Time the readiness probes fail for on shutdown before the servers stop taking connections")
+	flags.DurationVar(&c.ShutdownTimeout, "shutdownTimeout", c.ShutdownTimeout, "Time the servers drain their requests and the clicks are flushed in on shutdown")
+	flags.StringVar(&c.RateLimits, "rateLimits", c.RateLimits, "Token buckets per client as scope=rate:burst pairs, the scope is a route or a full rpc method")
+	flags.IntVar(&c.RateLimitClients, "rateLimitClients", c.RateLimitClients, "Max number of token buckets kept at once, the least recently used are dropped past it")
+
+	flags.StringVar(&c.DB.Type, "dbType", c.DB.Type, "Type of database (pgx, sqlite or inmemory)")
+	flags.BoolVar(&c.DB.Migrate, "migrate", c.DB.Migrate, "Apply the pending schema migrations of the pgx database or create the sqlite tables at startup")
+	flags.StringVar(&c.DB.Postgres.URL, "pgURL", c.DB.Postgres.URL, "Url of the pgx database")
+	flags.IntVar(&c.DB.Postgres.MaxConns, "pgMaxConns", c.DB.Postgres.MaxConns, "Max number of pgx database connections, zero keeps the pgx default")
+	flags.IntVar(&c.DB.Postgres.MinConns, "pgMinConns", c.DB.Postgres.MinConns, "Number of pgx database connections kept open when idle")
+	flags.DurationVar(&c.DB.Postgres.ConnLifetime, "pgConnLifetime", c.DB.Postgres.ConnLifetime, "Time a pgx database connection is reused for, zero keeps the pgx default")
+	flags.DurationVar(&c.DB.Postgres.ConnIdle, "pgConnIdle", c.DB.Postgres.ConnIdle, "Time an idle pgx database connection is kept open, zero keeps the pgx default")
+	flags.DurationVar(&c.DB.Postgres.StatementTimeout, "pgStatementTimeout", c.DB.Postgres.StatementTimeout, "Time the pgx database runs a statement before it cancels it, zero does not limit it")
+	flags.StringVar(&c.DB.SQLite.Path, "sqlitePath", c.DB.SQLite.Path, "File of the sqlite database, created if it does not exist")
+	flags.StringVar(&c.DB.InMemory.DataDir, "dataDir", c.DB.InMemory.DataDir, "Directory the inmemory database is persisted in, empty keeps it in memory only")
+	flags.BoolVar(&c.DB.InMemory.DataSync, "dataSync", c.DB.InMemory.DataSync, "Sync every change of the inmemory database to the disk")
+	flags.IntVar(&c.DB.InMemory.Shards, "shards", c.DB.InMemory.Shards, "Number of independently locked shards of the inmemory links, zero keeps them under a single lock")
+	flags.DurationVar(&c.DB.InMemory.CompactInterval, "compactInterval", c.DB.InMemory.CompactInterval, "Interval between compactions of the inmemory database files")
+
+	flags.IntVar(&c.Cache.Size, "cacheSize", c.Cache.Size, "Number of links kept in the read cache, zero turns the cache off")
+	flags.DurationVar(&c.Cache.TTL, "cacheTTL", c.Cache.TTL, "Time a link is kept in the read cache, changes made by other replicas are seen after it")
+	flags.DurationVar(&c.Cache.NegativeTTL, "cacheNegativeTTL", c.Cache.NegativeTTL, "Time a missing short link is kept in the read cache, zero does not keep them")
+	flags.DurationVar(&c.Cache.LoadTimeout, "cacheLoadTimeout", c.Cache.LoadTimeout, "Time a read cache miss waits for the db, the wait is shared by concurrent misses of a link")
+
+	flags.StringVar(&c.Links.Generator, "generator", c.Links.Generator, "Type of short link generator (snowflake or random)")
+	flags.Int64Var(&c.Links.NodeID, "nodeID", c.Links.NodeID, "Unique id of this instance for the snowflake generator (0-255)")
+	flags.IntVar(&c.Links.Length, "length", c.Links.Length, "Length of the generated short links, at least 10 for the snowflake generator")
+	flags.DurationVar(&c.Links.GenerateTimeout, "generateTimeout", c.Links.GenerateTimeout, "Time a create spends on short links that collide before it fails")
+	flags.IntVar(&c.Links.Redirect, "redirect", c.Links.Redirect, "Default redirect status of GET /:link for clients that accept text/html (301, 302, 307, 308 or 0 to answer with json)")
+	flags.DurationVar(&c.Links.ReapInterval, "reapInterval", c.Links.ReapInterval, "Interval between expired links cleanups")
+	flags.DurationVar(&c.Links.RestoreWindow, "restoreWindow", c.Links.RestoreWindow, "Time a deleted link can be restored before it is purged")
+
+	flags.IntVar(&c.Clicks.Queue, "clickQueue", c.Clicks.Queue, "Size of the click queue, clicks over it are dropped")
+	flags.IntVar(&c.Clicks.Batch, "clickBatch", c.Clicks.Batch, "Max number of clicks written at once")
+	flags.IntVar(&c.Clicks.Workers, "clickWorkers", c.Clicks.Workers, "Number of click writers")
+	flags.DurationVar(&c.Clicks.Flush, "clickFlush", c.Clicks.Flush, "Max time a click waits for its batch")
+}
+
+// readFile decodes the file over the config, unknown keys are errors.
+// TOML is read into a tree first and decoded by the same rules as YAML,
+// so durations are strings like "1m30s" in both
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		var tree map[string]interface{}
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(tree); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unexpected config format, use .yaml, .yml or .toml", path)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// readEnv sets the flags from their environment variables
+func (c *Config) readEnv(flags *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" {
+			return
+		}
+
+		name := EnvName(f.Name)
+		value, ok := lookupEnv(name)
+		if !ok && legacyEnv[f.Name] != "" {
+			name = legacyEnv[f.Name]
+			value, ok = lookupEnv(name)
+		}
+		if !ok {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("%s: %w", name, setErr)
+		}
+	})
+
+	return err
+}
+
+// EnvName returns the environment variable of the flag, cacheTTL is SHORTURL_CACHE_TTL
+func EnvName(flagName string) string {
+	var name strings.Builder
+	name.WriteString(EnvPrefix)
+	for i := 0; i < len(flagName); i++ {
+		char := flagName[i]
+		upper := char >= 'A' && char <= 'Z'
+		if upper && i > 0 {
+			previous := flagName[i-1]
+			nextLower := i+1 < len(flagName) && flagName[i+1] >= 'a' && flagName[i+1] <= 'z'
+			if previous < 'A' || previous > 'Z' || nextLower {
+				name.WriteByte('_')
+			}
+		}
+		name.WriteString(strings.ToUpper(string(char)))
+	}
+	return name.String()
+}
+
+// ConnString returns the url of the pgx database with its scheme
+func (c PostgresConfig) ConnString() string {
+	if strings.Contains(c.URL, "://") {
+		return c.URL
+	}
+	return "postgres://" + c.URL
+}
+
+// Validate reports every invalid setting at once by its key in the config file
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(c.HTTP.Addr)
+	check(err == nil, "http.addr %q is not a host:port address", c.HTTP.Addr)
+	_, _, err = net.SplitHostPort(c.GRPC.Addr)
+	check(err == nil, "grpc.addr %q is not a host:port address", c.GRPC.Addr)
+	for _, proxy := range c.HTTP.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "http.trusted_proxies %q is not an IP or a CIDR", proxy)
+	}
+	if _, err := service.ParseRateLimits(c.RateLimits); err != nil {
+		problems = append(problems, fmt.Sprintf("rate_limits: %v", err))
+	}
+	check(c.RateLimitClients > 0, "rate_limit_clients %d must be positive", c.RateLimitClients)
+
+	// without the admin token no key can be issued, so every create would be refused
+	check(!c.Auth.Enabled || c.Auth.AdminToken != "", "auth.admin_token is required when auth.enabled is on")
+	check(c.ShutdownDelay >= 0, "shutdown_delay %v can not be negative", c.ShutdownDelay)
+	check(c.ShutdownTimeout > 0, "shutdown_timeout %v must be positive", c.ShutdownTimeout)
+
+	check(c.DB.Type == "pgx" || c.DB.Type == "sqlite" || c.DB.Type == "inmemory", "db.type %q is not pgx, sqlite or inmemory", c.DB.Type)
+	postgres := c.DB.Postgres
+	check(postgres.MaxConns >= 0 && postgres.MinConns >= 0, "db.postgres.max_conns and min_conns can not be negative")
+	check(postgres.MaxConns == 0 || postgres.MinConns <= postgres.MaxConns, "db.postgres.min_conns %d is over max_conns %d", postgres.MinConns, postgres.MaxConns)
+	check(postgres.ConnLifetime >= 0 && postgres.ConnIdle >= 0 && postgres.StatementTimeout >= 0, "db.postgres durations can not be negative")
+	check(c.DB.Type != "sqlite" || c.DB.SQLite.Path != "", "db.sqlite.path is required when db.type is sqlite")
+	check(c.DB.InMemory.Shards >= 0, "db.inmemory.shards %d can not be negative", c.DB.InMemory.Shards)
+	check(c.DB.InMemory.CompactInterval > 0, "db.inmemory.compact_interval %v must be positive", c.DB.InMemory.CompactInterval)
+
+	check(c.Cache.Size >= 0, "cache.size %d can not be negative", c.Cache.Size)
+	check(c.Cache.Size == 0 || c.Cache.TTL > 0, "cache.ttl %v must be positive", c.Cache.TTL)
+	check(c.Cache.NegativeTTL >= 0, "cache.negative_ttl %v can not be negative", c.Cache.NegativeTTL)
+	check(c.Cache.Size == 0 || c.Cache.LoadTimeout > 0, "cache.load_timeout %v must be positive", c.Cache.LoadTimeout)
+
+	links := c.Links
+	switch links.Generator {
+	case "snowflake":
+		check(links.NodeID >= 0 && links.NodeID <= service.MaxNodeID, "links.node_id %d is not in 0-%d", links.NodeID, service.MaxNodeID)
+		// every snowflake id fits into service.Length characters
+		check(links.Length >= service.Length && links.Length <= service.AliasMaxLength, "links.length %d is not in %d-%d for the snowflake generator", links.Length, service.Length, service.AliasMaxLength)
+	case "random":
+		check(links.Length >= 1 && links.Length <= service.AliasMaxLength, "links.length %d is not in 1-%d", links.Length, service.AliasMaxLength)
+	default:
+		problems = append(problems, fmt.Sprintf("links.generator %q is not snowflake or random", links.Generator))
+	}
+	check(links.GenerateTimeout > 0, "links.generate_timeout %v must be positive", links.GenerateTimeout)
+	check(service.CheckRedirect(links.Redirect), "links.redirect %d is not 0, 301, 302, 307 or 308", links.Redirect)
+	check(links.ReapInterval > 0, "links.reap_interval %v must be positive", links.ReapInterval)
+	check(links.RestoreWindow >= 0, "links.restore_window %v can not be negative", links.RestoreWindow)
+
+	clicks := c.Clicks
+	check(clicks.Queue >= 0, "clicks.queue %d can not be negative", clicks.Queue)
+	check(clicks.Batch >= 1, "clicks.batch %d must be at least 1", clicks.Batch)
+	check(clicks.Workers >= 1, "clicks.workers %d must be at least 1", clicks.Workers)
+	check(clicks.Flush > 0, "clicks.flush %v must be positive", clicks.Flush)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// listValue is a flag of comma separated values
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, args []string, env map[string]string) (*Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return Load(flags, args, func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+}
+
+func writeFile(t *testing.T, name string, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	config, err := load(t, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, Default(), config)
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "shorturl.yaml", `
+http:
+  addr: ":9090"
+db:
+  type: inmemory
+  inmemory:
+    data_dir: /var/lib/shorturl
+    shards: 16
+cache:
+  size: 1000
+  ttl: 30s
+links:
+  generator: random
+  length: 8
+`)
+
+	config, err := load(t, []string{"-config", path}, nil)
+	require.NoError(t, err)
+	require.Equal(t, ":9090", config.HTTP.Addr)
+	require.Equal(t, ":50051", config.GRPC.Addr)
+	require.Equal(t, "inmemory", config.DB.Type)
+	require.Equal(t, "/var/lib/shorturl", config.DB.InMemory.DataDir)
+	require.Equal(t, 16, config.DB.InMemory.Shards)
+	require.Equal(t, 10*time.Minute, config.DB.InMemory.CompactInterval)
+	require.Equal(t, 1000, config.Cache.Size)
+	require.Equal(t, 30*time.Second, config.Cache.TTL)
+	require.Equal(t, "random", config.Links.Generator)
+	require.Equal(t, 8, config.Links.Length)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "shorturl.toml", `
+rate_limits = "POST /=2:10"
+
+[db]
+type = "pgx"
+
+[db.postgres]
+url = "user:pass@db:5432/shorturl"
+max_conns = 20
+statement_timeout = "5s"
+
+[clicks]
+workers = 4
+`)
+
+	config, err := load(t, nil, map[string]string{"SHORTURL_CONFIG": path})
+	require.NoError(t, err)
+	require.Equal(t, "POST /=2:10", config.RateLimits)
+	require.Equal(t, "user:pass@db:5432/shorturl", config.DB.Postgres.URL)
+	require.Equal(t, 20, config.DB.Postgres.MaxConns)
+	require.Equal(t, 5*time.Second, config.DB.Postgres.StatementTimeout)
+	require.Equal(t, 4, config.Clicks.Workers)
+	require.Equal(t, 500, config.Clicks.Batch)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "shorturl.yaml", `
+cache:
+  size: 100
+  ttl: 10s
+links:
+  redirect: 301
+`)
+	env := map[string]string{
+		"SHORTURL_CACHE_SIZE": "200",
+		"SHORTURL_CACHE_TTL":  "20s",
+	}
+
+	config, err := load(t, []string{"-config", path, "-cacheTTL", "40s"}, env)
+	require.NoError(t, err)
+	require.Equal(t, 301, config.Links.Redirect)
+	require.Equal(t, 200, config.Cache.Size)
+	require.Equal(t, 40*time.Second, config.Cache.TTL)
+}
+
+func TestLoad_LegacyEnv(t *testing.T) {
+	env := map[string]string{
+		"httpport":           ":8000",
+		"gRPCport":           ":8001",
+		"pg_url":             "user:pass@db:5432/shorturl",
+		"admin_token":        "secret",
+		"SHORTURL_GRPC_ADDR": ":9001",
+	}
+
+	config, err := load(t, nil, env)
+	require.NoError(t, err)
+	require.Equal(t, ":8000", config.HTTP.Addr)
+	require.Equal(t, ":9001", config.GRPC.Addr)
+	require.Equal(t, "user:pass@db:5432/shorturl", config.DB.Postgres.URL)
+	require.Equal(t, "postgres://user:pass@db:5432/shorturl", config.DB.Postgres.ConnString())
+	require.Equal(t, "secret", config.Auth.AdminToken)
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := writeFile(t, "shorturl.yaml", `
+cache:
+  sise: 100
+`)
+
+	_, err := load(t, []string{"-config", path}, nil)
+	require.ErrorContains(t, err, "field sise not found")
+}
+
+func TestLoad_UnknownFormat(t *testing.T) {
+	path := writeFile(t, "shorturl.json", `{}`)
+
+	_, err := load(t, []string{"-config", path}, nil)
+	require.ErrorContains(t, err, "unexpected config format")
+}
+
+func TestLoad_BadEnv(t *testing.T) {
+	_, err := load(t, nil, map[string]string{"SHORTURL_CLICK_FLUSH": "soon"})
+	require.ErrorContains(t, err, "SHORTURL_CLICK_FLUSH")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := load(t, []string{"-dbType", "mysql", "-shards", "-1", "-clickWorkers", "0", "-shutdownDelay", "-1s", "-shutdownTimeout", "0s"}, nil)
+	require.EqualError(t, err, `invalid config: shutdown_delay -1s can not be negative; shutdown_timeout 0s must be positive; `+
+		`db.type "mysql" is not pgx, sqlite or inmemory; `+
+		`db.inmemory.shards -1 can not be negative; clicks.workers 0 must be at least 1`)
+}
+
+func TestValidate_Length(t *testing.T) {
+	config := Default()
+	config.Links.Length = 5
+	require.ErrorContains(t, config.Validate(), "links.length 5 is not in 10-32 for the snowflake generator")
+
+	config.Links.Generator = "random"
+	require.NoError(t, config.Validate())
+
+	config.Links.Length = 0
+	require.ErrorContains(t, config.Validate(), "links.length 0 is not in 1-32")
+}
+
+func TestValidate_Auth(t *testing.T) {
+	config := Default()
+	require.False(t, config.Auth.Enabled)
+
+	config.Auth.Enabled = true
+	require.EqualError(t, config.Validate(), "invalid config: auth.admin_token is required when auth.enabled is on")
+
+	config.Auth.AdminToken = "secret"
+	require.NoError(t, config.Validate())
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	config, err := load(t, nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, config.HTTP.TrustedProxies)
+
+	config, err = load(t, []string{"-trustedProxies", "10.0.0.0/8, 192.168.1.1"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, config.HTTP.TrustedProxies)
+
+	_, err = load(t, nil, map[string]string{"SHORTURL_TRUSTED_PROXIES": "10.0.0.0/8,proxy"})
+	require.EqualError(t, err, `invalid config: http.trusted_proxies "proxy" is not an IP or a CIDR`)
+}
+
+func TestValidate_Pool(t *testing.T) {
+	config := Default()
+	config.DB.Postgres.MaxConns = 4
+	config.DB.Postgres.MinConns = 8
+	require.EqualError(t, config.Validate(), "invalid config: db.postgres.min_conns 8 is over max_conns 4")
+}
+
+func TestEnvName(t *testing.T) {
+	cases := map[string]string{
+		"auth":               "SHORTURL_AUTH",
+		"cacheTTL":           "SHORTURL_CACHE_TTL",
+		"cacheNegativeTTL":   "SHORTURL_CACHE_NEGATIVE_TTL",
+		"pgURL":              "SHORTURL_PG_URL",
+		"pgStatementTimeout": "SHORTURL_PG_STATEMENT_TIMEOUT",
+		"nodeID":             "SHORTURL_NODE_ID",
+		"httpAddr":           "SHORTURL_HTTP_ADDR",
+	}
+	for flagName, envName := range cases {
+		require.Equal(t, envName, EnvName(flagName), flagName)
+	}
+}
@@ -6,4 +6,4 @@ gRPCport=:3022
 
 export pg_url httpport gRPCport
 
-go run ./cmd/shortURL/main.go -dbType pgx
\ No newline at end of file
+go run ./cmd/shortURL -dbType pgx
\ No newline at end of file